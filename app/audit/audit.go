@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/samborkent/uuidv7"
+	"gorm.io/gorm"
+)
+
+// Event is a security relevant event to record. UserID, IPAddress and UserAgent are optional.
+type Event struct {
+	Type      string
+	UserID    string
+	IPAddress string
+	UserAgent string
+	Metadata  map[string]any
+}
+
+// Recorder keeps an audit trail of events.
+type Recorder interface {
+	Record(ctx context.Context, event Event) error
+}
+
+// Database records events in the audit_events table.
+type Database struct {
+	db *gorm.DB
+}
+
+var _ Recorder = (*Database)(nil)
+
+func NewDatabase(db *gorm.DB) *Database {
+	return &Database{db: db}
+}
+
+func (self *Database) Record(ctx context.Context, event Event) error {
+	metadata := []byte("{}")
+	if len(event.Metadata) > 0 {
+		var err error
+		if metadata, err = json.Marshal(event.Metadata); err != nil {
+			return err
+		}
+	}
+
+	return self.db.WithContext(ctx).Create(&models.AuditEvent{
+		ID:        uuidv7.New().String(),
+		Type:      event.Type,
+		UserID:    optional(event.UserID),
+		IPAddress: optional(event.IPAddress),
+		UserAgent: optional(event.UserAgent),
+		Metadata:  string(metadata),
+		CreatedAt: time.Now().UTC(),
+	}).Error
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
@@ -0,0 +1,29 @@
+package mailer
+
+import (
+	"context"
+
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+)
+
+// Console writes emails to the application log instead of sending them, for local development.
+type Console struct {
+	from string
+}
+
+var _ Mailer = (*Console)(nil)
+
+func NewConsole(from string) *Console {
+	return &Console{from: from}
+}
+
+func (self *Console) Send(ctx context.Context, message Message) error {
+	Log(SeverityInfo, "Mailer: email not sent, logged instead", map[string]any{
+		"from":    self.from,
+		"to":      message.To,
+		"subject": message.Subject,
+		"text":    message.Text,
+	})
+
+	return nil
+}
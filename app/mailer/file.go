@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+// File writes each email to an .eml file in a directory instead of sending it, so emails can be
+// opened in a mail client during development and inspected in tests.
+type File struct {
+	dir  string
+	from string
+}
+
+var _ Mailer = (*File)(nil)
+
+func NewFile(dir string, from string) *File {
+	return &File{dir: dir, from: from}
+}
+
+func (self *File) Send(ctx context.Context, message Message) error {
+	body, err := compose(self.from, message)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(self.dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuidv7.New().String())
+
+	return os.WriteFile(filepath.Join(self.dir, name), body, 0o644)
+}
@@ -0,0 +1,106 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+// Message is a plain text email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Mailer sends emails.
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// NewFromEnv builds the mailer selected by MAIL_DRIVER, which is "log" by default. Emails are sent
+// from MAIL_FROM.
+func NewFromEnv() Mailer {
+	driver := utils.GetEnv("MAIL_DRIVER", "log")
+	from := utils.GetEnv("MAIL_FROM", "no-reply@localhost")
+
+	switch driver {
+	case "log":
+		return NewConsole(from)
+	case "file":
+		return NewFile(utils.GetEnv("MAIL_FILE_DIR", "./mails"), from)
+	case "smtp":
+		return NewSMTP(SMTPConfig{
+			Host:     utils.RequireEnv("SMTP_HOST"),
+			Port:     utils.GetEnv("SMTP_PORT", "587"),
+			Username: utils.GetEnv("SMTP_USERNAME", ""),
+			Password: utils.GetEnv("SMTP_PASSWORD", ""),
+			From:     from,
+		})
+	default:
+		log.Fatalf("Unknown mail driver %q, expected log, file or smtp", driver)
+		return nil
+	}
+}
+
+// compose renders the message as an RFC 5322 email from the given address.
+func compose(from string, message Message) ([]byte, error) {
+	if _, err := mail.ParseAddress(message.To); err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+	if strings.ContainsAny(message.Subject, "\r\n") {
+		return nil, errors.New("subject cannot contain line breaks")
+	}
+
+	var buffer bytes.Buffer
+
+	headers := [][2]string{
+		{"From", from},
+		{"To", message.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", message.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageId(from)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+		{"Content-Transfer-Encoding", "quoted-printable"},
+	}
+	for _, header := range headers {
+		fmt.Fprintf(&buffer, "%s: %s\r\n", header[0], header[1])
+	}
+	buffer.WriteString("\r\n")
+
+	writer := quotedprintable.NewWriter(&buffer)
+	if _, err := writer.Write([]byte(strings.ReplaceAll(message.Text, "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func messageId(from string) string {
+	domain := "localhost"
+	if address, err := mail.ParseAddress(from); err == nil {
+		if _, host, found := strings.Cut(address.Address, "@"); found {
+			domain = host
+		}
+	}
+
+	random := make([]byte, 16)
+	_, _ = rand.Read(random)
+
+	return "<" + hex.EncodeToString(random) + "@" + domain + ">"
+}
@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+type SMTPConfig struct {
+	Host string
+	Port string
+	// Username and Password are used for PLAIN authentication when set.
+	Username string
+	Password string
+	From     string
+}
+
+// SMTP sends emails through an SMTP server, upgrading the connection with STARTTLS when the server
+// supports it.
+type SMTP struct {
+	config SMTPConfig
+}
+
+var _ Mailer = (*SMTP)(nil)
+
+func NewSMTP(config SMTPConfig) *SMTP {
+	return &SMTP{config: config}
+}
+
+func (self *SMTP) Send(ctx context.Context, message Message) error {
+	body, err := compose(self.config.From, message)
+	if err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(self.config.From)
+	if err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if self.config.Username != "" {
+		auth = smtp.PlainAuth("", self.config.Username, self.config.Password, self.config.Host)
+	}
+
+	// net/smtp doesn't take a context, so run it aside and stop waiting when the context is done.
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(net.JoinHostPort(self.config.Host, self.config.Port), auth, from.Address, []string{to.Address}, body)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
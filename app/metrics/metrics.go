@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Counter is a monotonically increasing count, optionally split by labels. Counters are exposed in
+// the Prometheus text format by Write.
+type Counter struct {
+	name       string
+	help       string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]uint64
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []*Counter
+)
+
+// NewCounter creates and registers a counter. Call it once per name, at package initialization.
+func NewCounter(name string, help string, labelNames ...string) *Counter {
+	counter := &Counter{name: name, help: help, labelNames: labelNames, values: map[string]uint64{}}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, counter)
+
+	return counter
+}
+
+// Inc adds one to the count for the given label values, which follow the order of the label names.
+func (self *Counter) Inc(labelValues ...string) {
+	if len(labelValues) != len(self.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", self.name, len(self.labelNames), len(labelValues)))
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.values[self.labels(labelValues)]++
+}
+
+func (self *Counter) labels(labelValues []string) string {
+	if len(labelValues) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(labelValues))
+	for i, value := range labelValues {
+		value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+		pairs[i] = fmt.Sprintf(`%s="%s"`, self.labelNames[i], value)
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Write writes every registered counter in the Prometheus text exposition format.
+func Write(w io.Writer) error {
+	registryMu.Lock()
+	counters := append([]*Counter(nil), registry...)
+	registryMu.Unlock()
+
+	for _, counter := range counters {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", counter.name, counter.help, counter.name); err != nil {
+			return err
+		}
+
+		counter.mu.Lock()
+		labels := make([]string, 0, len(counter.values))
+		for label := range counter.values {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+
+		lines := make([]string, len(labels))
+		for i, label := range labels {
+			lines[i] = fmt.Sprintf("%s%s %d\n", counter.name, label, counter.values[label])
+		}
+		counter.mu.Unlock()
+
+		// An unlabelled counter that was never incremented is still exposed as 0.
+		if len(lines) == 0 && len(counter.labelNames) == 0 {
+			lines = append(lines, counter.name+" 0\n")
+		}
+
+		for _, line := range lines {
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
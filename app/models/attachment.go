@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+type Attachment struct {
+	ID     string `sql:"id"             gorm:"type:uuid;primaryKey"`
+	UserID string `sql:"user_id"        gorm:"type:uuid;not null"`
+	// PostID is null until the attachment is linked to a post.
+	PostID      *string `sql:"post_id"        gorm:"type:uuid"`
+	StorageKey  string  `sql:"storage_key"    gorm:"type:text;not null;unique"`
+	FileName    string  `sql:"file_name"      gorm:"size:255;not null"`
+	ContentType string  `sql:"content_type"   gorm:"size:127;not null"`
+	Size        int64   `sql:"size"           gorm:"not null"`
+	// Width and Height are only set for images.
+	Width     *int      `sql:"width"`
+	Height    *int      `sql:"height"`
+	CreatedAt time.Time `sql:"created_at"     gorm:"not null;default:now()"`
+}
+
+// ToDto converts the attachment, url being the address the file can be downloaded from.
+func (self *Attachment) ToDto(url string) *AttachmentDto {
+	return &AttachmentDto{
+		ID:          self.ID,
+		UserID:      self.UserID,
+		PostID:      self.PostID,
+		URL:         url,
+		FileName:    self.FileName,
+		ContentType: self.ContentType,
+		Size:        self.Size,
+		Width:       self.Width,
+		Height:      self.Height,
+		CreatedAt:   self.CreatedAt,
+	}
+}
+
+// NewAttachment creates the record of an upload. The storage key is left for the caller to derive from the ID.
+func NewAttachment(userId string, postId *string, fileName string, contentType string, size int64) *Attachment {
+	return &Attachment{
+		ID:          uuidv7.New().String(),
+		UserID:      userId,
+		PostID:      postId,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+type UpdateAttachmentDto struct {
+	// PostID links the attachment to a post, or unlinks it when null.
+	PostID *string `json:"post_id"     validate:"omitempty,uuid"`
+}
+
+type AttachmentDto struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	PostID      *string   `json:"post_id"`
+	URL         string    `json:"url"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       *int      `json:"width"`
+	Height      *int      `json:"height"`
+	CreatedAt   time.Time `json:"created_at"`
+}
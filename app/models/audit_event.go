@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Types of audit events.
+const (
+	AuditAccountLocked   = "account_locked"
+	AuditAccountUnlocked = "account_unlocked"
+)
+
+// AuditEvent records a security relevant event, kept for investigating incidents.
+type AuditEvent struct {
+	ID   string `sql:"id"             gorm:"type:uuid;primaryKey"`
+	Type string `sql:"type"           gorm:"size:64;not null"`
+	// UserID is the user the event is about, if any.
+	UserID    *string `sql:"user_id"        gorm:"type:uuid"`
+	IPAddress *string `sql:"ip_address"     gorm:"size:45"`
+	UserAgent *string `sql:"user_agent"     gorm:"type:text"`
+	// Metadata is a JSON object with the details of the event.
+	Metadata  string    `sql:"metadata"       gorm:"type:jsonb;not null;default:'{}'"`
+	CreatedAt time.Time `sql:"created_at"     gorm:"not null;default:now()"`
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+// Bookmark saves a post for a user, either unsorted or in one of the user's lists.
+type Bookmark struct {
+	ID     string `sql:"id"             gorm:"type:uuid;primaryKey"`
+	UserID string `sql:"user_id"        gorm:"type:uuid;not null"`
+	PostID string `sql:"post_id"        gorm:"type:uuid;not null"`
+	// ListID is null for bookmarks that aren't in a list.
+	ListID    *string   `sql:"list_id"        gorm:"type:uuid"`
+	CreatedAt time.Time `sql:"created_at"     gorm:"not null;default:now()"`
+}
+
+func (self *Bookmark) ToDto() *BookmarkDto {
+	return &BookmarkDto{
+		ID:        self.ID,
+		PostID:    self.PostID,
+		ListID:    self.ListID,
+		CreatedAt: self.CreatedAt,
+	}
+}
+
+type CreateBookmarkDto struct {
+	PostID string  `json:"post_id"     validate:"required,uuid"`
+	ListID *string `json:"list_id"     validate:"omitempty,uuid"`
+}
+
+func (self *CreateBookmarkDto) FromDto(userId string) *Bookmark {
+	id := uuidv7.New().String()
+
+	return &Bookmark{
+		ID:        id,
+		UserID:    userId,
+		PostID:    self.PostID,
+		ListID:    self.ListID,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+type BookmarkDto struct {
+	ID     string  `json:"id"`
+	PostID string  `json:"post_id"`
+	ListID *string `json:"list_id"`
+	// Post is null when the post is no longer visible to the user, e.g. after being unpublished.
+	Post      *PostDto  `json:"post"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BookmarkList is a named reading list of a user.
+type BookmarkList struct {
+	ID        string     `sql:"id"             gorm:"type:uuid;primaryKey"`
+	UserID    string     `sql:"user_id"        gorm:"type:uuid;not null"`
+	Name      string     `sql:"name"           gorm:"size:64;not null"`
+	CreatedAt time.Time  `sql:"created_at"     gorm:"not null;default:now()"`
+	UpdatedAt *time.Time `sql:"updated_at"`
+}
+
+func (self *BookmarkList) ToDto() *BookmarkListDto {
+	return &BookmarkListDto{
+		ID:        self.ID,
+		Name:      self.Name,
+		CreatedAt: self.CreatedAt,
+		UpdatedAt: self.UpdatedAt,
+	}
+}
+
+type CreateBookmarkListDto struct {
+	Name string `json:"name"     validate:"required,min=1,max=64"`
+}
+
+func (self *CreateBookmarkListDto) FromDto(userId string) *BookmarkList {
+	id := uuidv7.New().String()
+
+	return &BookmarkList{
+		ID:        id,
+		UserID:    userId,
+		Name:      strings.TrimSpace(self.Name),
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+type UpdateBookmarkListDto struct {
+	Name string `json:"name"     validate:"required,min=1,max=64"`
+}
+
+type BookmarkListDto struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// BookmarksCount is only filled when listing the lists.
+	BookmarksCount int64      `json:"bookmarks_count"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+type Comment struct {
+	ID     string `sql:"id"             gorm:"type:uuid;primaryKey"`
+	PostID string `sql:"post_id"        gorm:"type:uuid;not null"`
+	UserID string `sql:"user_id"        gorm:"type:uuid;not null"`
+	// ParentID is null for comments made directly on the post.
+	ParentID     *string    `sql:"parent_id"      gorm:"type:uuid"`
+	Depth        int        `sql:"depth"          gorm:"not null;default:0"`
+	Content      string     `sql:"content"        gorm:"type:text;not null"`
+	RepliesCount int64      `sql:"replies_count"  gorm:"not null;default:0"`
+	CreatedAt    time.Time  `sql:"created_at"     gorm:"not null;default:now()"`
+	UpdatedAt    *time.Time `sql:"updated_at"`
+	// DeletedAt is set instead of removing the row so replies keep their place in the thread.
+	DeletedAt *time.Time `sql:"deleted_at"`
+}
+
+func (self *Comment) ToDto() *CommentDto {
+	dto := &CommentDto{
+		ID:           self.ID,
+		PostID:       self.PostID,
+		UserID:       &self.UserID,
+		ParentID:     self.ParentID,
+		Depth:        self.Depth,
+		Content:      self.Content,
+		RepliesCount: self.RepliesCount,
+		Deleted:      self.DeletedAt != nil,
+		CreatedAt:    self.CreatedAt,
+		UpdatedAt:    self.UpdatedAt,
+	}
+
+	if dto.Deleted {
+		dto.UserID = nil
+		dto.Content = ""
+	}
+
+	return dto
+}
+
+type CreateCommentDto struct {
+	Content  string  `json:"content"     validate:"required,min=1,max=10000"`
+	ParentID *string `json:"parent_id"   validate:"omitempty,uuid"`
+}
+
+func (self *CreateCommentDto) FromDto(postId string, userId string, depth int) *Comment {
+	id := uuidv7.New().String()
+
+	return &Comment{
+		ID:       id,
+		PostID:   postId,
+		UserID:   userId,
+		ParentID: self.ParentID,
+		Depth:    depth,
+		Content:  self.Content,
+	}
+}
+
+type UpdateCommentDto struct {
+	Content string `json:"content"     validate:"required,min=1,max=10000"`
+}
+
+type CommentDto struct {
+	ID     string `json:"id"`
+	PostID string `json:"post_id"`
+	// UserID is null once the comment is deleted.
+	UserID       *string    `json:"user_id"`
+	ParentID     *string    `json:"parent_id"`
+	Depth        int        `json:"depth"`
+	Content      string     `json:"content"`
+	RepliesCount int64      `json:"replies_count"`
+	Deleted      bool       `json:"deleted"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
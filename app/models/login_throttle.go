@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// LoginThrottle counts the recent failed logins of an account or an IP address, named by its Key,
+// and how long further attempts are refused for.
+type LoginThrottle struct {
+	Key           string    `sql:"key"            gorm:"size:320;primaryKey"`
+	Failures      int       `sql:"failures"       gorm:"not null;default:0"`
+	LastFailureAt time.Time `sql:"last_failure_at" gorm:"not null"`
+	// BlockedUntil is set while attempts are refused, either to back off or because of a lockout.
+	BlockedUntil *time.Time `sql:"blocked_until"`
+	// LockedAt is set when an account got locked out, as opposed to just backing off.
+	LockedAt *time.Time `sql:"locked_at"`
+	// UnlockTokenHash is the SHA-256 hash of the token of the unlock link emailed on lockout.
+	UnlockTokenHash *string `sql:"unlock_token_hash" gorm:"size:64"`
+}
+
+// IsBlocked reports whether attempts are refused at the given time.
+func (self *LoginThrottle) IsBlocked(now time.Time) bool {
+	return self.BlockedUntil != nil && now.Before(*self.BlockedUntil)
+}
+
+// UnlockAccountDto carries the token of an unlock link.
+type UnlockAccountDto struct {
+	Token string `json:"token"          validate:"required"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+)
+
+// UserMFA is the TOTP second factor of a user. It is pending until the user confirms it with a code,
+// which proves their authenticator app was set up.
+type UserMFA struct {
+	UserID string `sql:"user_id"        gorm:"type:uuid;primaryKey"`
+	Secret string `sql:"secret"         gorm:"size:64;not null"`
+	// EnabledAt is set once the user confirmed the secret. Until then it isn't asked for on login.
+	EnabledAt *time.Time `sql:"enabled_at"`
+	// LastUsedStep is the TOTP time step of the last accepted code, so a code can't be replayed.
+	LastUsedStep int64     `sql:"last_used_step" gorm:"not null;default:0"`
+	CreatedAt    time.Time `sql:"created_at"     gorm:"not null;default:now()"`
+}
+
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+func (self *UserMFA) IsEnabled() bool {
+	return self.EnabledAt != nil
+}
+
+// MFARecoveryCode lets a user log in once without their authenticator app. Only its SHA-256 hash is
+// stored.
+type MFARecoveryCode struct {
+	ID       string     `sql:"id"             gorm:"type:uuid;primaryKey"`
+	UserID   string     `sql:"user_id"        gorm:"type:uuid;not null"`
+	CodeHash string     `sql:"code_hash"      gorm:"size:64;not null"`
+	UsedAt   *time.Time `sql:"used_at"`
+}
+
+type MFAStatusDto struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabled_at"`
+	RecoveryCodesLeft int64      `json:"recovery_codes_left"`
+}
+
+// MFAEnrollmentDto holds what the user needs to add the account to an authenticator app. OtpauthURI
+// is the payload of the QR code to show.
+type MFAEnrollmentDto struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+// MFACodeDto carries a code from the authenticator app or, where accepted, a recovery code.
+type MFACodeDto struct {
+	Code string `json:"code"           validate:"required,max=32"`
+}
+
+// MFARecoveryCodesDto lists freshly generated recovery codes. They are only shown this once.
+type MFARecoveryCodesDto struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// MFAChallengeDto is the answer to a login with the right password when the account has two-factor
+// authentication. The login is completed by sending MFAToken with a code.
+type MFAChallengeDto struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	// ExpiresIn is the lifetime of the MFA token in seconds.
+	ExpiresIn int `json:"expires_in"`
+}
+
+type MFALoginDto struct {
+	MFAToken string `json:"mfa_token"      validate:"required"`
+	Code     string `json:"code"           validate:"required,max=32"`
+}
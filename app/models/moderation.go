@@ -0,0 +1,158 @@
+package models
+
+import (
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+// ReportReason is why a user reported a post.
+type ReportReason string
+
+const (
+	ReportReasonSpam           ReportReason = "spam"
+	ReportReasonHarassment     ReportReason = "harassment"
+	ReportReasonHate           ReportReason = "hate"
+	ReportReasonViolence       ReportReason = "violence"
+	ReportReasonSexual         ReportReason = "sexual"
+	ReportReasonMisinformation ReportReason = "misinformation"
+	ReportReasonOther          ReportReason = "other"
+)
+
+// ModerationActionKind is what a moderator did about a post.
+type ModerationActionKind string
+
+const (
+	ModerationActionHide    ModerationActionKind = "hide"
+	ModerationActionRestore ModerationActionKind = "restore"
+	ModerationActionDelete  ModerationActionKind = "delete"
+	// ModerationActionDismiss closes the reports of a post without changing it.
+	ModerationActionDismiss ModerationActionKind = "dismiss"
+)
+
+// PostReport is a user's complaint about a post. Open reports make up the moderation queue until a
+// moderator acts on the post.
+type PostReport struct {
+	ID         string       `sql:"id"             gorm:"type:uuid;primaryKey"`
+	PostID     string       `sql:"post_id"        gorm:"type:uuid;not null"`
+	ReporterID string       `sql:"reporter_id"    gorm:"type:uuid;not null"`
+	Reason     ReportReason `sql:"reason"         gorm:"size:32;not null"`
+	Details    *string      `sql:"details"        gorm:"type:text"`
+	CreatedAt  time.Time    `sql:"created_at"     gorm:"not null;default:now()"`
+	// ResolvedAt and ActionID are set once a moderator acted on the post, closing the report.
+	ResolvedAt *time.Time `sql:"resolved_at"`
+	ActionID   *string    `sql:"action_id"      gorm:"type:uuid"`
+}
+
+func (self *PostReport) ToDto() *PostReportDto {
+	return &PostReportDto{
+		ID:         self.ID,
+		PostID:     self.PostID,
+		ReporterID: self.ReporterID,
+		Reason:     self.Reason,
+		Details:    self.Details,
+		CreatedAt:  self.CreatedAt,
+		ResolvedAt: self.ResolvedAt,
+		ActionID:   self.ActionID,
+	}
+}
+
+type CreatePostReportDto struct {
+	Reason  ReportReason `json:"reason"     validate:"required,oneof=spam harassment hate violence sexual misinformation other"`
+	Details *string      `json:"details"    validate:"omitempty,max=1000"`
+}
+
+func (self *CreatePostReportDto) FromDto(postId string, reporterId string) *PostReport {
+	id := uuidv7.New().String()
+
+	return &PostReport{
+		ID:         id,
+		PostID:     postId,
+		ReporterID: reporterId,
+		Reason:     self.Reason,
+		Details:    self.Details,
+		CreatedAt:  time.Now().UTC(),
+	}
+}
+
+type PostReportDto struct {
+	ID         string       `json:"id"`
+	PostID     string       `json:"post_id"`
+	ReporterID string       `json:"reporter_id"`
+	Reason     ReportReason `json:"reason"`
+	Details    *string      `json:"details"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ResolvedAt *time.Time   `json:"resolved_at"`
+	ActionID   *string      `json:"action_id"`
+}
+
+// ModerationAction records what a moderator did about a post. Actions are kept after the post is
+// deleted, so PostID isn't a foreign key and PostTitle keeps the title the post had at the time.
+type ModerationAction struct {
+	ID          string               `sql:"id"             gorm:"type:uuid;primaryKey"`
+	PostID      string               `sql:"post_id"        gorm:"type:uuid;not null"`
+	PostTitle   string               `sql:"post_title"     gorm:"size:255;not null"`
+	ModeratorID *string              `sql:"moderator_id"   gorm:"type:uuid"`
+	Action      ModerationActionKind `sql:"action"         gorm:"size:16;not null"`
+	Note        *string              `sql:"note"           gorm:"type:text"`
+	// ReportsResolved is the number of open reports the action closed.
+	ReportsResolved int64     `sql:"reports_resolved" gorm:"not null;default:0"`
+	CreatedAt       time.Time `sql:"created_at"     gorm:"not null;default:now()"`
+}
+
+func (self *ModerationAction) ToDto() *ModerationActionDto {
+	return &ModerationActionDto{
+		ID:              self.ID,
+		PostID:          self.PostID,
+		PostTitle:       self.PostTitle,
+		ModeratorID:     self.ModeratorID,
+		Action:          self.Action,
+		Note:            self.Note,
+		ReportsResolved: self.ReportsResolved,
+		CreatedAt:       self.CreatedAt,
+	}
+}
+
+type CreateModerationActionDto struct {
+	Action ModerationActionKind `json:"action"     validate:"required,oneof=hide restore delete dismiss"`
+	Note   *string              `json:"note"       validate:"omitempty,max=1000"`
+}
+
+func (self *CreateModerationActionDto) FromDto(post *Post, moderatorId string) *ModerationAction {
+	id := uuidv7.New().String()
+
+	return &ModerationAction{
+		ID:          id,
+		PostID:      post.ID,
+		PostTitle:   post.Title,
+		ModeratorID: &moderatorId,
+		Action:      self.Action,
+		Note:        self.Note,
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+type ModerationActionDto struct {
+	ID              string               `json:"id"`
+	PostID          string               `json:"post_id"`
+	PostTitle       string               `json:"post_title"`
+	ModeratorID     *string              `json:"moderator_id"`
+	Action          ModerationActionKind `json:"action"`
+	Note            *string              `json:"note"`
+	ReportsResolved int64                `json:"reports_resolved"`
+	CreatedAt       time.Time            `json:"created_at"`
+}
+
+// ModerationQueueItemDto aggregates the open reports of a post in the moderation queue.
+type ModerationQueueItemDto struct {
+	PostID       string `json:"post_id"`
+	Title        string `json:"title"`
+	Slug         string `json:"slug"`
+	UserID       string `json:"user_id"`
+	Hidden       bool   `json:"hidden"`
+	ReportsCount int64  `json:"reports_count"`
+	// Reasons holds the number of open reports for each reason.
+	Reasons         map[ReportReason]int64 `json:"reasons"           gorm:"-"`
+	FirstReportedAt time.Time              `json:"first_reported_at"`
+	LastReportedAt  time.Time              `json:"last_reported_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+// PasswordResetToken lets a user who forgot their password choose a new one. Only the SHA-256 hash of
+// the token is stored, so the tokens can't be read back from the database.
+type PasswordResetToken struct {
+	ID        string     `sql:"id"             gorm:"type:uuid;primaryKey"`
+	UserID    string     `sql:"user_id"        gorm:"type:uuid;not null"`
+	TokenHash string     `sql:"token_hash"     gorm:"size:64;not null;unique"`
+	CreatedAt time.Time  `sql:"created_at"     gorm:"not null;default:now()"`
+	ExpiresAt time.Time  `sql:"expires_at"     gorm:"not null"`
+	UsedAt    *time.Time `sql:"used_at"`
+}
+
+func NewPasswordResetToken(userId string, tokenHash string, ttl time.Duration) *PasswordResetToken {
+	now := time.Now().UTC()
+
+	return &PasswordResetToken{
+		ID:        uuidv7.New().String(),
+		UserID:    userId,
+		TokenHash: tokenHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+}
+
+type ForgotPasswordDto struct {
+	Email string `json:"email"          validate:"required,email"`
+}
+
+type ResetPasswordDto struct {
+	Token    string `json:"token"          validate:"required"`
+	Password string `json:"password"       validate:"required"`
+}
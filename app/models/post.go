@@ -6,54 +6,192 @@ import (
 	"github.com/samborkent/uuidv7"
 )
 
+// PostStatus is the editorial state of a post.
+type PostStatus string
+
+const (
+	PostStatusDraft     PostStatus = "draft"
+	PostStatusInReview  PostStatus = "in_review"
+	PostStatusScheduled PostStatus = "scheduled"
+	PostStatusPublished PostStatus = "published"
+	PostStatusArchived  PostStatus = "archived"
+)
+
+// PostVisibility is who may read a published post.
+type PostVisibility string
+
+const (
+	// PostVisibilityPublic posts are listed everywhere.
+	PostVisibilityPublic PostVisibility = "public"
+	// PostVisibilityUnlisted posts can be read by anyone with a link but are left out of listings.
+	PostVisibilityUnlisted PostVisibility = "unlisted"
+	// PostVisibilityFollowers posts are only shown to the followers of their author.
+	PostVisibilityFollowers PostVisibility = "followers"
+	// PostVisibilityPrivate posts are only shown to their author.
+	PostVisibilityPrivate PostVisibility = "private"
+)
+
 type Post struct {
-	ID        string     `sql:"id"             gorm:"type:uuid;primaryKey"`
-	Title     string     `sql:"title"          gorm:"size:255;not null"`
-	Content   string     `sql:"content"        gorm:"type:text"`
-	Published bool       `sql:"published"      gorm:"not null;default:false"`
-	CreatedAt time.Time  `sql:"created_at"     gorm:"not null;default:now()"`
-	UpdatedAt *time.Time `sql:"updated_at"`
-	UserID    string     `sql:"user_id"        gorm:"type:uuid;not null"`
+	ID    string `sql:"id"             gorm:"type:uuid;primaryKey"`
+	Title string `sql:"title"          gorm:"size:255;not null"`
+	Slug  string `sql:"slug"           gorm:"size:255;uniqueIndex;not null"`
+	// Content is the raw Markdown written by the author.
+	Content string `sql:"content"        gorm:"type:text"`
+	// ContentHTML, Excerpt and ReadingTimeMinutes are derived from Content whenever it's written.
+	// ContentHTML is null for posts that haven't been rendered yet.
+	ContentHTML        *string `sql:"content_html"   gorm:"type:text"`
+	Excerpt            string  `sql:"excerpt"        gorm:"type:text;not null;default:''"`
+	ReadingTimeMinutes int     `sql:"reading_time_minutes" gorm:"not null;default:0"`
+	// Published mirrors Status == PostStatusPublished. It is kept for filters written against it.
+	Published    bool           `sql:"published"      gorm:"not null;default:false"`
+	Status       PostStatus     `sql:"status"         gorm:"size:16;not null;default:draft"`
+	Visibility   PostVisibility `sql:"visibility"     gorm:"size:16;not null;default:public"`
+	PublishedAt  *time.Time     `sql:"published_at"`
+	ScheduledFor *time.Time     `sql:"scheduled_for"`
+	// Hidden posts were taken down by a moderator. Only moderators and admins can see them.
+	Hidden bool `sql:"hidden"         gorm:"not null;default:false"`
+	// CommentsCount is maintained by the comments module and never written on post updates.
+	CommentsCount  int64      `sql:"comments_count"  gorm:"not null;default:0"`
+	CommentsLocked bool       `sql:"comments_locked" gorm:"not null;default:false"`
+	CreatedAt      time.Time  `sql:"created_at"     gorm:"not null;default:now()"`
+	UpdatedAt      *time.Time `sql:"updated_at"`
+	UserID         string     `sql:"user_id"        gorm:"type:uuid;not null"`
 
 	User User
+	Tags []Tag `gorm:"many2many:post_tags"`
 }
 
 func (self *Post) ToDto() *PostDto {
-	return &PostDto{
-		ID:        self.ID,
-		UserID:    self.UserID,
-		Title:     self.Title,
-		Content:   self.Content,
-		Published: self.Published,
-		CreatedAt: self.CreatedAt,
-		UpdatedAt: self.UpdatedAt,
+	tags := make([]string, len(self.Tags))
+	for i, tag := range self.Tags {
+		tags[i] = tag.Name
 	}
+
+	dto := &PostDto{
+		ID:             self.ID,
+		UserID:         self.UserID,
+		Title:          self.Title,
+		Slug:           self.Slug,
+		Content:        self.Content,
+		ContentHTML:    self.ContentHTML,
+		Excerpt:        self.Excerpt,
+		ReadingTime:    self.ReadingTimeMinutes,
+		Published:      self.Published,
+		Status:         self.Status,
+		Visibility:     self.Visibility,
+		PublishedAt:    self.PublishedAt,
+		ScheduledFor:   self.ScheduledFor,
+		CommentsCount:  self.CommentsCount,
+		CommentsLocked: self.CommentsLocked,
+		Hidden:         self.Hidden,
+		Tags:           tags,
+		Reactions:      map[string]int64{},
+		MyReactions:    []string{},
+		CreatedAt:      self.CreatedAt,
+		UpdatedAt:      self.UpdatedAt,
+	}
+
+	// The author is only known when the User association was loaded.
+	if self.User.ID != "" {
+		dto.Author = self.User.ToAuthorSummary()
+	}
+
+	return dto
+}
+
+// IsPublic reports whether anyone may read the post: it is published and wasn't hidden by a moderator.
+func (self *Post) IsPublic() bool {
+	return self.Status == PostStatusPublished && !self.Hidden
 }
 
 type CreatePostDto struct {
-	Title     string `json:"title"     validate:"required,min=1,max=255"`
-	Content   string `json:"content"`
-	Published bool   `json:"published"`
+	Title   string `json:"title"     validate:"required,min=1,max=255"`
+	Content string `json:"content"`
+	// Published publishes the post right away instead of creating a draft.
+	Published bool `json:"published"`
+	// Tags are names of existing tags.
+	Tags []string `json:"tags"      validate:"omitempty,max=10,dive,min=1,max=32"`
+	// Visibility defaults to PostVisibilityPublic.
+	Visibility PostVisibility `json:"visibility"     validate:"omitempty,oneof=public unlisted followers private"`
 }
 
 func (self *CreatePostDto) FromDto(userId string) *Post {
 	id := uuidv7.New().String()
 
+	status := PostStatusDraft
+	var publishedAt *time.Time
+	if self.Published {
+		now := time.Now().UTC()
+		status = PostStatusPublished
+		publishedAt = &now
+	}
+
+	visibility := self.Visibility
+	if visibility == "" {
+		visibility = PostVisibilityPublic
+	}
+
 	return &Post{
-		ID:        id,
-		UserID:    userId,
-		Title:     self.Title,
-		Content:   self.Content,
-		Published: self.Published,
+		ID:          id,
+		UserID:      userId,
+		Title:       self.Title,
+		Content:     self.Content,
+		Published:   self.Published,
+		Status:      status,
+		Visibility:  visibility,
+		PublishedAt: publishedAt,
 	}
 }
 
+// UpdatePostDto holds the fields of a post an author can edit. Nil fields are left untouched.
+type UpdatePostDto struct {
+	Title   *string `json:"title"     validate:"omitempty,min=1,max=255"`
+	Content *string `json:"content"`
+	// Tags replaces the tags of the post when set.
+	Tags       *[]string       `json:"tags"      validate:"omitempty,max=10,dive,min=1,max=32"`
+	Visibility *PostVisibility `json:"visibility"     validate:"omitempty,oneof=public unlisted followers private"`
+}
+
+type TransitionPostDto struct {
+	Status PostStatus `json:"status"           validate:"required,oneof=draft in_review scheduled published archived"`
+	// ScheduledFor is required when moving to PostStatusScheduled.
+	ScheduledFor *time.Time `json:"scheduled_for"`
+}
+
 type PostDto struct {
-	ID        string     `json:"id"`
-	Title     string     `json:"title"     validate:"required,min=1,max=255"`
-	Content   string     `json:"content"`
-	Published bool       `json:"published"`
-	UserID    string     `json:"user_id"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt *time.Time `json:"updated_at"`
+	ID          string  `json:"id"`
+	Title       string  `json:"title"     validate:"required,min=1,max=255"`
+	Slug        string  `json:"slug"`
+	Content     string  `json:"content"`
+	ContentHTML *string `json:"content_html"`
+	Excerpt     string  `json:"excerpt"`
+	// ReadingTime is the estimated reading time in minutes.
+	ReadingTime    int            `json:"reading_time"`
+	Published      bool           `json:"published"`
+	Status         PostStatus     `json:"status"`
+	Visibility     PostVisibility `json:"visibility"`
+	PublishedAt    *time.Time     `json:"published_at"`
+	ScheduledFor   *time.Time     `json:"scheduled_for"`
+	CommentsCount  int64          `json:"comments_count"`
+	CommentsLocked bool           `json:"comments_locked"`
+	Hidden         bool           `json:"hidden"`
+	Tags           []string       `json:"tags"`
+	// Reactions holds the number of reactions of each kind the post received.
+	Reactions map[string]int64 `json:"reactions"`
+	// MyReactions lists the kinds the current user reacted with. It is empty for anonymous users.
+	MyReactions []string `json:"my_reactions"`
+	// Bookmarked tells whether the current user bookmarked the post. It is false for anonymous users.
+	Bookmarked bool              `json:"bookmarked"`
+	UserID     string            `json:"user_id"`
+	Author     *AuthorSummaryDto `json:"author"`
+	Series     *PostSeriesDto    `json:"series"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  *time.Time        `json:"updated_at"`
+}
+
+// PostSlug is a slug a post used to have. Requests for it are redirected to the post's current slug.
+type PostSlug struct {
+	Slug      string    `sql:"slug"           gorm:"size:255;primaryKey"`
+	PostID    string    `sql:"post_id"        gorm:"type:uuid;not null"`
+	CreatedAt time.Time `sql:"created_at"     gorm:"not null;default:now()"`
 }
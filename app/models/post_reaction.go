@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// ReactionKinds are the reactions a user can leave on a post, once each.
+var ReactionKinds = []string{"like", "love", "laugh", "wow", "sad", "angry"}
+
+type PostReaction struct {
+	PostID    string    `sql:"post_id"        gorm:"type:uuid;primaryKey"`
+	UserID    string    `sql:"user_id"        gorm:"type:uuid;primaryKey"`
+	Kind      string    `sql:"kind"           gorm:"size:16;primaryKey"`
+	CreatedAt time.Time `sql:"created_at"     gorm:"not null;default:now()"`
+}
+
+type PostReactionsDto struct {
+	// Counts holds the number of reactions of each kind the post received.
+	Counts map[string]int64 `json:"counts"`
+	// Mine lists the kinds the current user reacted with.
+	Mine []string `json:"mine"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+// PostRevision is an immutable snapshot of a post's title and content, taken every time they change.
+type PostRevision struct {
+	ID     string `sql:"id"             gorm:"type:uuid;primaryKey"`
+	PostID string `sql:"post_id"        gorm:"type:uuid;not null"`
+	// Number counts the revisions of a post, starting at 1.
+	Number    int       `sql:"number"         gorm:"not null"`
+	Title     string    `sql:"title"          gorm:"size:255;not null"`
+	Content   string    `sql:"content"        gorm:"type:text"`
+	EditorID  string    `sql:"editor_id"      gorm:"type:uuid;not null"`
+	CreatedAt time.Time `sql:"created_at"     gorm:"not null;default:now()"`
+}
+
+// NewPostRevision snapshots the current title and content of a post. Its number is assigned when it's stored.
+func NewPostRevision(post *Post, editorId string) *PostRevision {
+	return &PostRevision{
+		ID:       uuidv7.New().String(),
+		PostID:   post.ID,
+		Title:    post.Title,
+		Content:  post.Content,
+		EditorID: editorId,
+	}
+}
+
+func (self *PostRevision) ToDto() *PostRevisionDto {
+	return &PostRevisionDto{
+		ID:        self.ID,
+		PostID:    self.PostID,
+		Number:    self.Number,
+		Title:     self.Title,
+		Content:   self.Content,
+		EditorID:  self.EditorID,
+		CreatedAt: self.CreatedAt,
+	}
+}
+
+type PostRevisionDto struct {
+	ID        string    `json:"id"`
+	PostID    string    `json:"post_id"`
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	EditorID  string    `json:"editor_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PostRevisionDiffDto struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	// Diff is a unified diff of the title and content, empty when the revisions are the same.
+	Diff string `json:"diff"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// PostViewDaily aggregates the views of a post over a UTC day.
+type PostViewDaily struct {
+	PostID         string    `sql:"post_id"          gorm:"type:uuid;primaryKey"`
+	Day            time.Time `sql:"day"              gorm:"type:date;primaryKey"`
+	Views          int64     `sql:"views"            gorm:"not null;default:0"`
+	UniqueVisitors int64     `sql:"unique_visitors"  gorm:"not null;default:0"`
+}
+
+func (PostViewDaily) TableName() string {
+	return "post_views_daily"
+}
+
+// PostViewVisitor records that a visitor viewed a post on a day, so they are counted once per day.
+// VisitorHash is salted with a key that changes daily and can't be traced back to the visitor.
+type PostViewVisitor struct {
+	PostID      string    `sql:"post_id"          gorm:"type:uuid;primaryKey"`
+	Day         time.Time `sql:"day"              gorm:"type:date;primaryKey"`
+	VisitorHash string    `sql:"visitor_hash"     gorm:"size:64;primaryKey"`
+}
+
+// PostViewReferrer counts the views of a post coming from a referring host over a UTC day.
+type PostViewReferrer struct {
+	PostID   string    `sql:"post_id"          gorm:"type:uuid;primaryKey"`
+	Day      time.Time `sql:"day"              gorm:"type:date;primaryKey"`
+	Referrer string    `sql:"referrer"         gorm:"size:255;primaryKey"`
+	Views    int64     `sql:"views"            gorm:"not null;default:0"`
+}
+
+type PostStatsDto struct {
+	PostID string    `json:"post_id"`
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Views  int64     `json:"views"`
+	// UniqueVisitors sums the daily unique visitors, since visitors aren't tracked across days.
+	UniqueVisitors int64                  `json:"unique_visitors"`
+	Daily          []*PostStatsDayDto     `json:"daily"`
+	Referrers      []*PostReferrerStatDto `json:"referrers"`
+}
+
+type PostStatsDayDto struct {
+	Day            string `json:"day"`
+	Views          int64  `json:"views"`
+	UniqueVisitors int64  `json:"unique_visitors"`
+}
+
+type PostReferrerStatDto struct {
+	// Referrer is the referring host, or empty for direct visits.
+	Referrer string `json:"referrer"`
+	Views    int64  `json:"views"`
+}
@@ -2,6 +2,12 @@ package models
 
 import "github.com/samborkent/uuidv7"
 
+// Names of the roles the application checks for. Users without a role are normal users.
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+)
+
 type Role struct {
 	ID   string `sql:"id" gorm:"type:uuid;primaryKey"`
 	Name string `sql:"name" gorm:"size:32;uniqueIndex;not null"`
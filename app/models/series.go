@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+// Series is an ordered collection of posts by the same author, e.g. a multi-part tutorial.
+type Series struct {
+	ID          string  `sql:"id"             gorm:"type:uuid;primaryKey"`
+	UserID      string  `sql:"user_id"        gorm:"type:uuid;not null"`
+	Title       string  `sql:"title"          gorm:"size:255;not null"`
+	Description *string `sql:"description"    gorm:"type:text"`
+	// Version is incremented whenever the parts change. Reorders must name the version they were
+	// based on, so a stale order is rejected instead of silently overwriting a newer one.
+	Version   int        `sql:"version"        gorm:"not null;default:1"`
+	CreatedAt time.Time  `sql:"created_at"     gorm:"not null;default:now()"`
+	UpdatedAt *time.Time `sql:"updated_at"`
+}
+
+func (Series) TableName() string {
+	return "series"
+}
+
+func (self *Series) ToDto() *SeriesDto {
+	return &SeriesDto{
+		ID:          self.ID,
+		UserID:      self.UserID,
+		Title:       self.Title,
+		Description: self.Description,
+		Version:     self.Version,
+		Parts:       []SeriesPartDto{},
+		CreatedAt:   self.CreatedAt,
+		UpdatedAt:   self.UpdatedAt,
+	}
+}
+
+// SeriesPost places a post in a series. A post belongs to at most one series.
+type SeriesPost struct {
+	SeriesID string `sql:"series_id"      gorm:"type:uuid;primaryKey"`
+	PostID   string `sql:"post_id"        gorm:"type:uuid;primaryKey"`
+	// Position is the 1-based place of the post in the series.
+	Position int `sql:"position"       gorm:"not null"`
+}
+
+type CreateSeriesDto struct {
+	Title       string  `json:"title"          validate:"required,min=1,max=255"`
+	Description *string `json:"description"    validate:"omitempty,max=2000"`
+	// PostIDs are the author's posts making up the series, in order.
+	PostIDs []string `json:"post_ids"       validate:"omitempty,max=100,unique,dive,uuid"`
+}
+
+func (self *CreateSeriesDto) FromDto(userId string) *Series {
+	id := uuidv7.New().String()
+
+	return &Series{
+		ID:          id,
+		UserID:      userId,
+		Title:       self.Title,
+		Description: self.Description,
+		Version:     1,
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+// UpdateSeriesDto holds the fields of a series an author can edit. Nil fields are left untouched.
+type UpdateSeriesDto struct {
+	Title       *string `json:"title"          validate:"omitempty,min=1,max=255"`
+	Description *string `json:"description"    validate:"omitempty,max=2000"`
+}
+
+// SetSeriesPostsDto replaces the parts of a series. Posts left out are removed from it.
+type SetSeriesPostsDto struct {
+	PostIDs []string `json:"post_ids"       validate:"max=100,unique,dive,uuid"`
+	// Version is the version of the series the new order was based on.
+	Version int `json:"version"        validate:"required,min=1"`
+}
+
+type SeriesDto struct {
+	ID          string  `json:"id"`
+	UserID      string  `json:"user_id"`
+	Title       string  `json:"title"`
+	Description *string `json:"description"`
+	Version     int     `json:"version"`
+	// Parts lists the posts of the series the current user may read, in order.
+	Parts     []SeriesPartDto `json:"parts"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt *time.Time      `json:"updated_at"`
+}
+
+type SeriesPartDto struct {
+	Position int    `json:"position"`
+	PostID   string `json:"post_id"`
+	Title    string `json:"title"`
+	Slug     string `json:"slug"`
+}
+
+// PostSeriesDto places a post in its series, with links to the parts around it.
+type PostSeriesDto struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	// Position and Total count the parts the current user may read.
+	Position int            `json:"position"`
+	Total    int            `json:"total"`
+	Previous *SeriesPartDto `json:"previous"`
+	Next     *SeriesPartDto `json:"next"`
+}
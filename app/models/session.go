@@ -0,0 +1,115 @@
+package models
+
+import (
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+type SessionRevocationReason string
+
+const (
+	SessionRevokedLogout SessionRevocationReason = "logout"
+	// SessionRevokedReuse marks a session whose rotated refresh token was presented again, which means
+	// the token leaked.
+	SessionRevokedReuse SessionRevocationReason = "reuse"
+	// SessionRevokedByUser marks a session the user ended from another device.
+	SessionRevokedByUser SessionRevocationReason = "revoked"
+	// SessionRevokedByAdmin marks a session an admin ended.
+	SessionRevokedByAdmin SessionRevocationReason = "admin"
+	// SessionRevokedPasswordReset marks the sessions ended when the user reset their password.
+	SessionRevokedPasswordReset SessionRevocationReason = "password_reset"
+)
+
+// Session is a login on a device. It is the family of all the refresh tokens issued from that login:
+// each refresh rotates the token, and only the latest one is accepted.
+type Session struct {
+	ID     string `sql:"id"             gorm:"type:uuid;primaryKey"`
+	UserID string `sql:"user_id"        gorm:"type:uuid;not null"`
+	// TokenID is the ID (jti) of the refresh token currently issued for the session.
+	TokenID string `sql:"token_id"       gorm:"type:uuid;not null"`
+	// PreviousTokenID is the token TokenID replaced at RotatedAt. It is still accepted for a short
+	// while so concurrent requests that refresh with it don't look like a stolen token.
+	PreviousTokenID *string                  `sql:"previous_token_id" gorm:"type:uuid"`
+	RotatedAt       *time.Time               `sql:"rotated_at"`
+	UserAgent       *string                  `sql:"user_agent"     gorm:"type:text"`
+	IPAddress       *string                  `sql:"ip_address"     gorm:"size:45"`
+	CreatedAt       time.Time                `sql:"created_at"     gorm:"not null;default:now()"`
+	LastSeenAt      time.Time                `sql:"last_seen_at"   gorm:"not null;default:now()"`
+	ExpiresAt       time.Time                `sql:"expires_at"     gorm:"not null"`
+	RevokedAt       *time.Time               `sql:"revoked_at"`
+	RevokedReason   *SessionRevocationReason `sql:"revoked_reason" gorm:"size:16"`
+	// MFA is whether the login passed two-factor authentication.
+	MFA bool `sql:"mfa"            gorm:"not null;default:false"`
+}
+
+func (self *Session) ToDto(currentSessionId string) *SessionDto {
+	return &SessionDto{
+		ID:         self.ID,
+		UserAgent:  self.UserAgent,
+		IPAddress:  self.IPAddress,
+		Current:    self.ID == currentSessionId,
+		CreatedAt:  self.CreatedAt,
+		LastSeenAt: self.LastSeenAt,
+		ExpiresAt:  self.ExpiresAt,
+	}
+}
+
+type SessionDto struct {
+	ID string `json:"id"`
+	// Device is a readable summary of the user agent, e.g. "Firefox on Windows".
+	Device    string  `json:"device"`
+	UserAgent *string `json:"user_agent"`
+	IPAddress *string `json:"ip_address"`
+	// Current is true for the session of the request.
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// SessionClient describes the client a session is opened from.
+type SessionClient struct {
+	UserAgent string
+	IPAddress string
+}
+
+// NewSession opens a session for the user that lasts for ttl unless it is refreshed.
+func NewSession(userId string, client SessionClient, ttl time.Duration) *Session {
+	now := time.Now().UTC()
+
+	return &Session{
+		ID:         uuidv7.New().String(),
+		UserID:     userId,
+		TokenID:    uuidv7.New().String(),
+		UserAgent:  optionalString(client.UserAgent),
+		IPAddress:  optionalString(client.IPAddress),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+}
+
+// Rotate replaces the refresh token of the session and extends it by ttl.
+func (self *Session) Rotate(ttl time.Duration) {
+	now := time.Now().UTC()
+	previous := self.TokenID
+
+	self.PreviousTokenID = &previous
+	self.TokenID = uuidv7.New().String()
+	self.RotatedAt = &now
+	self.LastSeenAt = now
+	self.ExpiresAt = now.Add(ttl)
+}
+
+func (self *Session) IsActive() bool {
+	return self.RevokedAt == nil && time.Now().Before(self.ExpiresAt)
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// SigningKey is a key pair tokens are signed with. It signs new tokens from ActivatesAt until RetiresAt.
+// Its public key is published, and the tokens it signed are accepted, from its creation until ExpiresAt.
+type SigningKey struct {
+	// ID is the key ID, sent in the kid header of the tokens the key signs.
+	ID        string `sql:"id"           gorm:"type:uuid;primaryKey"`
+	Algorithm string `sql:"algorithm"    gorm:"size:16;not null"`
+	// PrivateKey is the private key in PKCS #8 DER form.
+	PrivateKey  []byte    `sql:"private_key"  gorm:"not null"`
+	CreatedAt   time.Time `sql:"created_at"   gorm:"not null;default:now()"`
+	ActivatesAt time.Time `sql:"activates_at" gorm:"not null"`
+	RetiresAt   time.Time `sql:"retires_at"   gorm:"not null"`
+	ExpiresAt   time.Time `sql:"expires_at"   gorm:"not null"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+type Tag struct {
+	ID        string    `sql:"id"             gorm:"type:uuid;primaryKey"`
+	Name      string    `sql:"name"           gorm:"size:32;uniqueIndex;not null"`
+	CreatedAt time.Time `sql:"created_at"     gorm:"not null;default:now()"`
+}
+
+func (self *Tag) ToDto() *TagDto {
+	return &TagDto{
+		ID:        self.ID,
+		Name:      self.Name,
+		CreatedAt: self.CreatedAt,
+	}
+}
+
+// NormalizeTagName lowercases and trims a tag name so "Go " and "go" are the same tag.
+func NormalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+type CreateTagDto struct {
+	Name string `json:"name" validate:"required,min=1,max=32"`
+}
+
+func (self *CreateTagDto) FromDto() *Tag {
+	id := uuidv7.New().String()
+
+	return &Tag{
+		ID:   id,
+		Name: NormalizeTagName(self.Name),
+	}
+}
+
+type UpdateTagDto struct {
+	Name string `json:"name" validate:"required,min=1,max=32"`
+}
+
+type TagDto struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// UsageCount is the number of published posts with the tag. It is only filled when listing tags.
+	UsageCount int64     `json:"usage_count"`
+	CreatedAt  time.Time `json:"created_at"`
+}
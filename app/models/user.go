@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/samborkent/uuidv7"
@@ -14,10 +15,13 @@ type User struct {
 	LastName  string     `sql:"last_name"      gorm:"size:32;not null"`
 	Email     string     `sql:"email"          gorm:"type:text;uniqueIndex;not null"`
 	Password  string     `sql:"password"       gorm:"type:text;not null"`
-	Phone     string     `sql:"phone"          gorm:"type:text;not null;unique"`
+	Phone     *string    `sql:"phone"          gorm:"type:text;unique"`
+	AvatarURL *string    `sql:"avatar_url"     gorm:"type:text"`
 	IsActive  bool       `sql:"is_active"      gorm:"not null"`
 	CreatedAt time.Time  `sql:"created_at"     gorm:"not null;default:now()"`
 	UpdatedAt *time.Time `sql:"updated_at"`
+	// EmailVerifiedAt is when the user confirmed they own their email address, nil until then.
+	EmailVerifiedAt *time.Time `sql:"email_verified_at"`
 
 	Role  Role
 	Posts []Post
@@ -25,14 +29,40 @@ type User struct {
 
 func (self *User) ToDto() *UserDto {
 	return &UserDto{
+		ID:              self.ID,
+		RoleID:          self.RoleID,
+		FirstName:       self.FirstName,
+		LastName:        self.LastName,
+		Email:           self.Email,
+		Phone:           self.PhoneNumber(),
+		AvatarURL:       self.AvatarURL,
+		CreatedAt:       self.CreatedAt,
+		UpdatedAt:       self.UpdatedAt,
+		EmailVerifiedAt: self.EmailVerifiedAt,
+	}
+}
+
+// IsEmailVerified reports whether the user confirmed their email address.
+func (self *User) IsEmailVerified() bool {
+	return self.EmailVerifiedAt != nil
+}
+
+// PhoneNumber returns the phone number of the user, or "" for users who signed up through an identity
+// provider and never gave one.
+func (self *User) PhoneNumber() string {
+	if self.Phone == nil {
+		return ""
+	}
+
+	return *self.Phone
+}
+
+// ToAuthorSummary returns the public part of the user, safe to embed in content shown to anyone.
+func (self *User) ToAuthorSummary() *AuthorSummaryDto {
+	return &AuthorSummaryDto{
 		ID:        self.ID,
-		RoleID:    self.RoleID,
-		FirstName: self.FirstName,
-		LastName:  self.LastName,
-		Email:     self.Email,
-		Phone:     self.Phone,
-		CreatedAt: self.CreatedAt,
-		UpdatedAt: self.UpdatedAt,
+		Name:      strings.TrimSpace(self.FirstName + " " + self.LastName),
+		AvatarURL: self.AvatarURL,
 	}
 }
 
@@ -44,8 +74,11 @@ type UserDto struct {
 	LastName  string     `json:"last_name"    validate:"required"`
 	Email     string     `json:"email"        validate:"required,email"`
 	Phone     string     `json:"phone"        validate:"required,e164"`
+	AvatarURL *string    `json:"avatar_url"   validate:"omitempty,url,max=2048"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at"`
+	// EmailVerifiedAt is set once the user confirmed their email address. It is ignored on input.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
 }
 
 func (self *UserDto) FromDto(autoFill bool, password string) *User {
@@ -60,17 +93,57 @@ func (self *UserDto) FromDto(autoFill bool, password string) *User {
 		FirstName: self.FirstName,
 		LastName:  self.LastName,
 		Email:     self.Email,
-		Phone:     self.Phone,
+		Phone:     optionalString(self.Phone),
+		AvatarURL: self.AvatarURL,
 		Password:  password,
 		CreatedAt: self.CreatedAt,
 		UpdatedAt: self.UpdatedAt,
 	}
 }
 
+// AuthorSummaryDto identifies the author of public content. It never carries contact details.
+type AuthorSummaryDto struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	AvatarURL *string `json:"avatar_url"`
+}
+
+// AuthTokensDto carries the tokens to clients that can't use cookies. User is only set on login
+// and registration.
+type AuthTokensDto struct {
+	User         *UserDto `json:"user,omitempty"`
+	AccessToken  string   `json:"access_token"`
+	RefreshToken string   `json:"refresh_token"`
+	TokenType    string   `json:"token_type"`
+	// ExpiresIn is the lifetime of the access token in seconds.
+	ExpiresIn int `json:"expires_in"`
+}
+
 type JwtUser struct {
 	UserID   string `json:"userId"`
 	Email    string `json:"email"`
 	RoleName string `json:"roleName"`
+	// EmailVerified is whether the email address was verified when the token was issued.
+	EmailVerified bool `json:"emailVerified"`
+	// SessionID is the session the access token was issued for.
+	SessionID string `json:"sessionId"`
+	// MFA is whether the session passed two-factor authentication.
+	MFA bool `json:"mfa"`
+}
+
+// HasRole reports whether the user holds any of the given roles. Admins only hold their role in
+// sessions that passed two-factor authentication.
+func (self *JwtUser) HasRole(roleNames ...string) bool {
+	for _, roleName := range roleNames {
+		if self.RoleName != "" && self.RoleName == roleName {
+			if roleName == RoleAdmin && !self.MFA {
+				continue
+			}
+			return true
+		}
+	}
+
+	return false
 }
 
 type UserContact struct {
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// UserFollow records that a user follows another one.
+type UserFollow struct {
+	FollowerID string    `sql:"follower_id"    gorm:"type:uuid;primaryKey"`
+	FolloweeID string    `sql:"followee_id"    gorm:"type:uuid;primaryKey"`
+	CreatedAt  time.Time `sql:"created_at"     gorm:"not null;default:now()"`
+}
+
+type FollowDto struct {
+	UserID string `json:"user_id"`
+	// Following tells whether the current user follows the user.
+	Following      bool  `json:"following"`
+	FollowersCount int64 `json:"followers_count"`
+	FollowingCount int64 `json:"following_count"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/samborkent/uuidv7"
+)
+
+// UserIdentity links a user to their account at an identity provider, such as Google or the OpenID
+// Connect provider of their company.
+type UserIdentity struct {
+	ID     string `sql:"id"             gorm:"type:uuid;primaryKey"`
+	UserID string `sql:"user_id"        gorm:"type:uuid;not null"`
+	// Provider is the name the provider is configured under.
+	Provider string `sql:"provider"       gorm:"size:32;not null"`
+	// Subject is the ID of the account at the provider.
+	Subject string `sql:"subject"        gorm:"size:255;not null"`
+	// Email is the address the provider gave when the identity was last used.
+	Email       string    `sql:"email"          gorm:"type:text;not null"`
+	CreatedAt   time.Time `sql:"created_at"     gorm:"not null;default:now()"`
+	LastLoginAt time.Time `sql:"last_login_at"  gorm:"not null;default:now()"`
+}
+
+func NewUserIdentity(userId string, provider string, subject string, email string) *UserIdentity {
+	now := time.Now().UTC()
+
+	return &UserIdentity{
+		ID:          uuidv7.New().String(),
+		UserID:      userId,
+		Provider:    provider,
+		Subject:     subject,
+		Email:       email,
+		CreatedAt:   now,
+		LastLoginAt: now,
+	}
+}
+
+func (self *UserIdentity) ToDto() *UserIdentityDto {
+	return &UserIdentityDto{
+		ID:          self.ID,
+		Provider:    self.Provider,
+		Email:       self.Email,
+		CreatedAt:   self.CreatedAt,
+		LastLoginAt: self.LastLoginAt,
+	}
+}
+
+type UserIdentityDto struct {
+	ID          string    `json:"id"`
+	Provider    string    `json:"provider"`
+	Email       string    `json:"email"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+// OIDCProviderDto describes a provider users can sign in with.
+type OIDCProviderDto struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	// AuthorizePath is the endpoint of this API to send the browser to in order to sign in.
+	AuthorizePath string `json:"authorize_path"`
+}
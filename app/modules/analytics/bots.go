@@ -0,0 +1,29 @@
+package analytics
+
+import "strings"
+
+// botMarkers are substrings of the user agents of crawlers, link previewers, monitors and HTTP libraries.
+var botMarkers = []string{
+	"bot", "crawl", "spider", "slurp", "archiver", "scraper",
+	"facebookexternalhit", "embedly", "preview", "whatsapp", "telegram",
+	"monitor", "uptime", "pingdom", "lighthouse", "headless",
+	"curl", "wget", "httpie", "python-requests", "python-urllib", "go-http-client",
+	"java/", "okhttp", "libwww", "node-fetch", "axios",
+}
+
+// isBot reports whether the user agent belongs to an automated client. Requests without a user agent
+// are treated as automated too.
+func isBot(userAgent string) bool {
+	userAgent = strings.ToLower(strings.TrimSpace(userAgent))
+	if userAgent == "" {
+		return true
+	}
+
+	for _, marker := range botMarkers {
+		if strings.Contains(userAgent, marker) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,57 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(Service *Service) *Handler {
+	return &Handler{service: Service}
+}
+
+// GetPostStats accepts an optional range of days as from and to (YYYY-MM-DD). It defaults to the
+// last 30 days.
+func (self *Handler) GetPostStats(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	to := parseDay(formatDay(time.Now()))
+	if value := ctx.Query("to", ""); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return utils.Err(ctx, 400, "Invalid to parameter", nil)
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -29)
+	if value := ctx.Query("from", ""); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return utils.Err(ctx, 400, "Invalid from parameter", nil)
+		}
+		from = parsed
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	stats, statusCode, err := self.service.GetPostStats(ctx.Context(), id, from, to, user)
+	if err != nil {
+		Log(SeverityWarn, "GetPostStats: failed to fetch post stats", map[string]any{"postId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", stats)
+}
@@ -0,0 +1,67 @@
+package analytics
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+// TrackViews records a view of the post the handler marked with utils.SetViewedPost, once the
+// response succeeded. Bots and prefetches aren't counted.
+func TrackViews(recorder *Recorder) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if err := ctx.Next(); err != nil {
+			return err
+		}
+
+		postId := utils.GetViewedPost(ctx)
+		if postId == "" || ctx.Response().StatusCode() != 200 {
+			return nil
+		}
+
+		userAgent := ctx.Get(fiber.HeaderUserAgent)
+		if isBot(userAgent) || isPrefetch(ctx) {
+			return nil
+		}
+
+		// Fiber reuses the request's memory once the handler returns, so only copies of the request's
+		// strings may be handed over to the recorder. The concatenation below makes one.
+		recorder.Record(View{
+			PostID:   postId,
+			Visitor:  ctx.IP() + "\n" + userAgent,
+			Referrer: referrerHost(ctx.Get(fiber.HeaderReferer)),
+			At:       time.Now().UTC(),
+		})
+
+		return nil
+	}
+}
+
+func isPrefetch(ctx *fiber.Ctx) bool {
+	purpose := strings.ToLower(ctx.Get("Sec-Purpose") + ctx.Get("Purpose") + ctx.Get("X-Moz"))
+
+	return strings.Contains(purpose, "prefetch") || strings.Contains(purpose, "prerender")
+}
+
+// referrerHost reduces the Referer header to its host, so referrers are grouped by site and no
+// page paths or query strings are stored.
+func referrerHost(referer string) string {
+	if referer == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(referer)
+	if err != nil || parsed.Hostname() == "" {
+		return ""
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	if len(host) > 255 {
+		host = host[:255]
+	}
+
+	return strings.Clone(host)
+}
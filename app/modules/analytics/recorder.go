@@ -0,0 +1,218 @@
+package analytics
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+)
+
+// maxBatchViews flushes a batch early when it grows this large, which also keeps the number of
+// query parameters of a flush well within the database's limits.
+const maxBatchViews = 5000
+
+// View is a single counted view of a post.
+type View struct {
+	PostID string
+	// Visitor identifies the visitor, e.g. their IP address and user agent. It is hashed before use.
+	Visitor string
+	// Referrer is the host of the referring page, empty for direct visits.
+	Referrer string
+	At       time.Time
+}
+
+type dayKey struct {
+	postId string
+	day    string
+}
+
+type visitorKey struct {
+	postId      string
+	day         string
+	visitorHash string
+}
+
+type referrerKey struct {
+	postId   string
+	day      string
+	referrer string
+}
+
+// batch holds the views aggregated since the last flush.
+type batch struct {
+	views     map[dayKey]int64
+	visitors  map[visitorKey]struct{}
+	referrers map[referrerKey]int64
+	size      int
+}
+
+func newBatch() *batch {
+	return &batch{
+		views:     map[dayKey]int64{},
+		visitors:  map[visitorKey]struct{}{},
+		referrers: map[referrerKey]int64{},
+	}
+}
+
+func (self *batch) add(view View, visitorHash string) {
+	day := formatDay(view.At)
+
+	self.views[dayKey{postId: view.PostID, day: day}]++
+	self.visitors[visitorKey{postId: view.PostID, day: day, visitorHash: visitorHash}] = struct{}{}
+	self.referrers[referrerKey{postId: view.PostID, day: day, referrer: view.Referrer}]++
+	self.size++
+}
+
+// Recorder counts views in the background. Record never blocks: views are queued on a buffered channel,
+// de-duplicated and aggregated in memory, then written to the database in batches.
+type Recorder struct {
+	repository    *Repository
+	views         chan View
+	flushInterval time.Duration
+	dedupeWindow  time.Duration
+	secret        []byte
+	// lastSeen holds when each visitor last had a view of a post counted. Only the Run goroutine uses it.
+	lastSeen map[string]time.Time
+	dropped  atomic.Int64
+}
+
+// NewRecorder creates a recorder. Views of a post by the same visitor within dedupeWindow count once.
+// secret salts the visitor hashes; a random one is used when it's empty.
+func NewRecorder(repository *Repository, bufferSize int, flushInterval time.Duration, dedupeWindow time.Duration, secret string) *Recorder {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		_, _ = rand.Read(key)
+	}
+
+	return &Recorder{
+		repository:    repository,
+		views:         make(chan View, bufferSize),
+		flushInterval: flushInterval,
+		dedupeWindow:  dedupeWindow,
+		secret:        key,
+		lastSeen:      map[string]time.Time{},
+	}
+}
+
+// Record queues a view. It returns false when the buffer is full and the view was dropped.
+func (self *Recorder) Record(view View) bool {
+	select {
+	case self.views <- view:
+		return true
+	default:
+		self.dropped.Add(1)
+		return false
+	}
+}
+
+// Run processes the queued views until ctx is done, then flushes what is left.
+func (self *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(self.flushInterval)
+	defer ticker.Stop()
+
+	current := newBatch()
+	lastPrune := time.Now().UTC()
+
+	for {
+		select {
+		case view := <-self.views:
+			visitorHash := self.visitorHash(view)
+			if !self.firstInWindow(view, visitorHash) {
+				continue
+			}
+
+			current.add(view, visitorHash)
+			if current.size >= maxBatchViews {
+				self.flush(current)
+				current = newBatch()
+			}
+		case now := <-ticker.C:
+			self.flush(current)
+			current = newBatch()
+
+			if dropped := self.dropped.Swap(0); dropped > 0 {
+				Log(SeverityWarn, "Recorder: view buffer full, views dropped", map[string]any{"dropped": dropped})
+			}
+
+			self.pruneLastSeen(now)
+			if formatDay(now.UTC()) != formatDay(lastPrune) {
+				self.deleteOldVisitors(now.UTC())
+				lastPrune = now.UTC()
+			}
+		case <-ctx.Done():
+			self.flush(current)
+			return
+		}
+	}
+}
+
+// firstInWindow reports whether the view is the first of the visitor for the post within the
+// de-duplication window, and remembers it.
+func (self *Recorder) firstInWindow(view View, visitorHash string) bool {
+	key := view.PostID + ":" + visitorHash
+
+	if last, ok := self.lastSeen[key]; ok && view.At.Sub(last) < self.dedupeWindow {
+		return false
+	}
+
+	self.lastSeen[key] = view.At
+
+	return true
+}
+
+func (self *Recorder) pruneLastSeen(now time.Time) {
+	for key, last := range self.lastSeen {
+		if now.Sub(last) >= self.dedupeWindow {
+			delete(self.lastSeen, key)
+		}
+	}
+}
+
+// visitorHash identifies the visitor without storing who they are. The day is part of the hash so a
+// visitor can't be followed from one day to the next.
+func (self *Recorder) visitorHash(view View) string {
+	mac := hmac.New(sha256.New, self.secret)
+	mac.Write([]byte(formatDay(view.At) + "\n" + view.Visitor))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (self *Recorder) flush(current *batch) {
+	if current.size == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Analytics are best effort: a failed batch is logged and dropped rather than retried.
+	if err := self.repository.SaveBatch(ctx, current); err != nil {
+		Log(SeverityError, "Recorder: failed to save views", map[string]any{"views": current.size, "error": err.Error()})
+	}
+}
+
+// deleteOldVisitors forgets the visitors of the previous days, which are no longer needed.
+func (self *Recorder) deleteOldVisitors(now time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := self.repository.DeleteVisitorsBefore(ctx, parseDay(formatDay(now))); err != nil {
+		Log(SeverityError, "Recorder: failed to delete old visitors", map[string]any{"error": err.Error()})
+	}
+}
+
+func formatDay(at time.Time) string {
+	return at.UTC().Format(time.DateOnly)
+}
+
+func parseDay(day string) time.Time {
+	parsed, _ := time.Parse(time.DateOnly, day)
+
+	return parsed
+}
@@ -0,0 +1,204 @@
+package analytics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// SaveBatch adds the aggregated views of a batch to the daily counters. Visitors already seen on the
+// same day, e.g. by an earlier batch, don't count again as unique visitors. The views of posts deleted
+// since they were recorded are dropped.
+func (self *Repository) SaveBatch(ctx context.Context, batch *batch) error {
+	return self.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		posts, err := lockPosts(tx, batch)
+		if err != nil {
+			return err
+		}
+
+		visitors := make(map[visitorKey]struct{}, len(batch.visitors))
+		for key := range batch.visitors {
+			if posts[key.postId] {
+				visitors[key] = struct{}{}
+			}
+		}
+
+		newVisitors, err := insertVisitors(tx, visitors)
+		if err != nil {
+			return err
+		}
+
+		days := make([]models.PostViewDaily, 0, len(batch.views))
+		for key, views := range batch.views {
+			if !posts[key.postId] {
+				continue
+			}
+			days = append(days, models.PostViewDaily{
+				PostID:         key.postId,
+				Day:            parseDay(key.day),
+				Views:          views,
+				UniqueVisitors: newVisitors[key],
+			})
+		}
+
+		if len(days) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "post_id"}, {Name: "day"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"views":           gorm.Expr("post_views_daily.views + excluded.views"),
+					"unique_visitors": gorm.Expr("post_views_daily.unique_visitors + excluded.unique_visitors"),
+				}),
+			}).Create(&days).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		referrers := make([]models.PostViewReferrer, 0, len(batch.referrers))
+		for key, views := range batch.referrers {
+			if !posts[key.postId] {
+				continue
+			}
+			referrers = append(referrers, models.PostViewReferrer{
+				PostID:   key.postId,
+				Day:      parseDay(key.day),
+				Referrer: key.referrer,
+				Views:    views,
+			})
+		}
+
+		if len(referrers) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "post_id"}, {Name: "day"}, {Name: "referrer"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"views": gorm.Expr("post_view_referrers.views + excluded.views"),
+				}),
+			}).Create(&referrers).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// lockPosts returns which posts of the batch still exist, and keeps them from being deleted until the
+// end of the transaction so their counters can be inserted.
+func lockPosts(tx *gorm.DB, batch *batch) (map[string]bool, error) {
+	ids := make([]string, 0, len(batch.views))
+	for key := range batch.views {
+		ids = append(ids, key.postId)
+	}
+	for key := range batch.visitors {
+		ids = append(ids, key.postId)
+	}
+	for key := range batch.referrers {
+		ids = append(ids, key.postId)
+	}
+
+	posts := map[string]bool{}
+	if len(ids) == 0 {
+		return posts, nil
+	}
+
+	var existing []string
+	if err := tx.Raw("SELECT id FROM posts WHERE id IN ? FOR KEY SHARE", ids).Scan(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	for _, id := range existing {
+		posts[id] = true
+	}
+
+	return posts, nil
+}
+
+// insertVisitors records the visitors and returns how many of them are new for each post and day.
+func insertVisitors(tx *gorm.DB, visitors map[visitorKey]struct{}) (map[dayKey]int64, error) {
+	newVisitors := map[dayKey]int64{}
+	if len(visitors) == 0 {
+		return newVisitors, nil
+	}
+
+	placeholders := make([]string, 0, len(visitors))
+	values := make([]any, 0, len(visitors)*3)
+	for key := range visitors {
+		placeholders = append(placeholders, "(?, ?, ?)")
+		values = append(values, key.postId, key.day, key.visitorHash)
+	}
+
+	var inserted []models.PostViewVisitor
+	err := tx.Raw(
+		"INSERT INTO post_view_visitors (post_id, day, visitor_hash) VALUES "+strings.Join(placeholders, ", ")+
+			" ON CONFLICT DO NOTHING RETURNING post_id, day",
+		values...,
+	).Scan(&inserted).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, visitor := range inserted {
+		newVisitors[dayKey{postId: visitor.PostID, day: formatDay(visitor.Day)}]++
+	}
+
+	return newVisitors, nil
+}
+
+// DeleteVisitorsBefore forgets the visitors of the days before day. They are only needed to count
+// unique visitors of the current day.
+func (self *Repository) DeleteVisitorsBefore(ctx context.Context, day time.Time) error {
+	return self.db.WithContext(ctx).Where("day < ?", day).Delete(&models.PostViewVisitor{}).Error
+}
+
+// FindDaily returns the daily counters of a post between from and to, both included.
+func (self *Repository) FindDaily(ctx context.Context, postId string, from time.Time, to time.Time) ([]models.PostViewDaily, error) {
+	var entities []models.PostViewDaily
+
+	err := self.db.WithContext(ctx).
+		Where("post_id = ? AND day BETWEEN ? AND ?", postId, from, to).
+		Order("day ASC").
+		Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// FindTopReferrers returns the referrers that sent the most views to a post between from and to.
+func (self *Repository) FindTopReferrers(
+	ctx context.Context,
+	postId string,
+	from time.Time,
+	to time.Time,
+	limit int,
+) ([]models.PostReferrerStatDto, error) {
+	var referrers []models.PostReferrerStatDto
+
+	err := self.db.WithContext(ctx).
+		Model(&models.PostViewReferrer{}).
+		Select("referrer, SUM(views) AS views").
+		Where("post_id = ? AND day BETWEEN ? AND ?", postId, from, to).
+		Group("referrer").
+		Order("views DESC, referrer ASC").
+		Limit(limit).
+		Scan(&referrers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return referrers, nil
+}
@@ -0,0 +1,14 @@
+package analytics
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/okira-e/go-as-your-backend/app/modules/users"
+)
+
+func SetupRoutes(api fiber.Router, handler *Handler, usersService *users.Service) {
+	api.Get(
+		"/posts/:id/stats",
+		users.AuthMiddleware(usersService),
+		handler.GetPostStats,
+	)
+}
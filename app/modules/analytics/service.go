@@ -0,0 +1,94 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/modules/posts"
+)
+
+// maxStatsDays bounds the range of a stats request.
+const maxStatsDays = 366
+
+// topReferrersLimit is the number of referrers returned with the stats of a post.
+const topReferrersLimit = 20
+
+type Service struct {
+	repository      *Repository
+	postsRepository *posts.Repository
+}
+
+func NewService(repository *Repository, postsRepository *posts.Repository) *Service {
+	return &Service{repository: repository, postsRepository: postsRepository}
+}
+
+// GetPostStats returns the views of a post between the from and to days, both included. Only the
+// post's author and admins may see them.
+func (self *Service) GetPostStats(
+	ctx context.Context,
+	postId string,
+	from time.Time,
+	to time.Time,
+	user models.JwtUser,
+) (*models.PostStatsDto, int, error) {
+	post, err := self.postsRepository.FindByID(ctx, postId)
+	if err != nil {
+		return &models.PostStatsDto{}, 404, fmt.Errorf("Post with ID %s not found.", postId)
+	}
+
+	if post.UserID != user.UserID && !user.HasRole(models.RoleAdmin) {
+		return &models.PostStatsDto{}, 403, fmt.Errorf("Only the author can see the stats of this post.")
+	}
+
+	if to.Before(from) {
+		return &models.PostStatsDto{}, 400, fmt.Errorf("The start of the range must not be after its end.")
+	}
+
+	if to.Sub(from) >= maxStatsDays*24*time.Hour {
+		return &models.PostStatsDto{}, 400, fmt.Errorf("The range can't be longer than %d days.", maxStatsDays)
+	}
+
+	days, err := self.repository.FindDaily(ctx, postId, from, to)
+	if err != nil {
+		return &models.PostStatsDto{}, 500, err
+	}
+
+	referrers, err := self.repository.FindTopReferrers(ctx, postId, from, to, topReferrersLimit)
+	if err != nil {
+		return &models.PostStatsDto{}, 500, err
+	}
+
+	stats := &models.PostStatsDto{
+		PostID:    postId,
+		From:      from,
+		To:        to,
+		Daily:     []*models.PostStatsDayDto{},
+		Referrers: make([]*models.PostReferrerStatDto, len(referrers)),
+	}
+
+	for i := range referrers {
+		stats.Referrers[i] = &referrers[i]
+	}
+
+	countsByDay := make(map[string]models.PostViewDaily, len(days))
+	for _, day := range days {
+		countsByDay[formatDay(day.Day)] = day
+	}
+
+	// Days without views are included with zero counts so the series has no gaps.
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		counts := countsByDay[formatDay(day)]
+
+		stats.Views += counts.Views
+		stats.UniqueVisitors += counts.UniqueVisitors
+		stats.Daily = append(stats.Daily, &models.PostStatsDayDto{
+			Day:            formatDay(day),
+			Views:          counts.Views,
+			UniqueVisitors: counts.UniqueVisitors,
+		})
+	}
+
+	return stats, 200, nil
+}
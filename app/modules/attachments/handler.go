@@ -0,0 +1,147 @@
+package attachments
+
+import (
+	"github.com/gofiber/fiber/v2"
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(Service *Service) *Handler {
+	return &Handler{service: Service}
+}
+
+// Upload expects a multipart form with the file in the "file" field and an optional "post_id".
+func (self *Handler) Upload(ctx *fiber.Ctx) error {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return utils.Err(ctx, 400, "The file field is required", nil)
+	}
+
+	var postId *string
+	if value := ctx.FormValue("post_id"); value != "" {
+		if err := utils.ValidateVar(value, "uuid"); err != nil {
+			return utils.Err(ctx, 400, "Invalid post_id field", nil)
+		}
+		postId = &value
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	attachment, statusCode, err := self.service.Upload(ctx.Context(), fileHeader, postId, user)
+	if err != nil {
+		Log(SeverityWarn, "Upload: failed to upload attachment", map[string]any{"userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	Log(SeverityInfo, "Upload: attachment uploaded", map[string]any{"attachmentId": attachment.ID, "userId": user.UserID, "size": attachment.Size})
+
+	return utils.Ok(ctx, statusCode, "", attachment)
+}
+
+func (self *Handler) FindOne(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Attachment ID not provided", nil)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	attachment, statusCode, err := self.service.FindByID(ctx.Context(), id, user)
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", attachment)
+}
+
+func (self *Handler) FindByPost(ctx *fiber.Ctx) error {
+	postId := ctx.Params("postId")
+	if postId == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	attachments, statusCode, err := self.service.FindByPost(ctx.Context(), postId, utils.GetOptionalUserFromContext(ctx))
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", attachments)
+}
+
+// Serve sends a file of the local storage to whoever may see it, under the public URL of the storage.
+func (self *Handler) Serve(ctx *fiber.Ctx) error {
+	entity, body, statusCode, err := self.service.Open(ctx.Context(), ctx.Params("*"), utils.GetOptionalUserFromContext(ctx))
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	ctx.Set(fiber.HeaderContentType, entity.ContentType)
+	ctx.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	// Who may see the file changes with the post, e.g. when it is unpublished, so shared caches can't keep it.
+	ctx.Set(fiber.HeaderCacheControl, "private, max-age=300")
+
+	return ctx.SendStream(body, int(entity.Size))
+}
+
+func (self *Handler) Update(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Attachment ID not provided", nil)
+	}
+
+	entityDto := models.UpdateAttachmentDto{}
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	attachment, statusCode, err := self.service.Update(ctx.Context(), id, &entityDto, user)
+	if err != nil {
+		Log(SeverityWarn, "Update: failed to update attachment", map[string]any{"attachmentId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", attachment)
+}
+
+func (self *Handler) Delete(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Attachment ID not provided", nil)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	statusCode, err := self.service.Delete(ctx.Context(), id, user)
+	if err != nil {
+		Log(SeverityWarn, "Delete: failed to delete attachment", map[string]any{"attachmentId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	Log(SeverityInfo, "Delete: attachment deleted", map[string]any{"attachmentId": id, "userId": user.UserID})
+
+	return utils.Ok(ctx, statusCode, "Attachment deleted", nil)
+}
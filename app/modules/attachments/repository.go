@@ -0,0 +1,148 @@
+package attachments
+
+import (
+	"context"
+	"errors"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"gorm.io/gorm"
+)
+
+var _ spec.Repository[models.Attachment] = (*Repository)(nil)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (self *Repository) Create(ctx context.Context, entity *models.Attachment) (*models.Attachment, error) {
+	if entity == nil {
+		return nil, errors.New("entity cannot be nil")
+	}
+
+	if err := self.db.WithContext(ctx).Create(entity).Error; err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+func (self *Repository) FindByID(ctx context.Context, id string) (*models.Attachment, error) {
+	var entity models.Attachment
+
+	err := self.db.WithContext(ctx).First(&entity, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+func (self *Repository) FindByStorageKey(ctx context.Context, key string) (*models.Attachment, error) {
+	var entity models.Attachment
+
+	err := self.db.WithContext(ctx).First(&entity, "storage_key = ?", key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+func (self *Repository) FindAll(ctx context.Context, queryOptions *spec.QueryOptions, filter *spec.Filter) ([]models.Attachment, error) {
+	var entities []models.Attachment
+
+	tx := self.db.WithContext(ctx)
+	tx = spec.ApplyPagination(tx, queryOptions)
+	tx, err := spec.ApplyFilters(tx, filter, models.Attachment{})
+	if err != nil {
+		return entities, err
+	}
+
+	err = tx.Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// FindByPost returns the attachments linked to a post, oldest first.
+func (self *Repository) FindByPost(ctx context.Context, postId string) ([]models.Attachment, error) {
+	var entities []models.Attachment
+
+	err := self.db.WithContext(ctx).Where("post_id = ?", postId).Order("id ASC").Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+func (self *Repository) Count(ctx context.Context, filter *spec.Filter) (int64, error) {
+	var count int64
+
+	tx := self.db.WithContext(ctx)
+	tx, err := spec.ApplyFilters(tx, filter, models.Attachment{})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Model(&models.Attachment{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// Update saves every column so the attachment can be unlinked from its post.
+func (self *Repository) Update(ctx context.Context, entity *models.Attachment) error {
+	if entity == nil {
+		return errors.New("entity cannot be nil")
+	}
+
+	result := self.db.WithContext(ctx).Model(entity).Select("*").Updates(entity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
+
+func (self *Repository) Delete(ctx context.Context, id string) error {
+	result := self.db.WithContext(ctx).Delete(&models.Attachment{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
+
+func (self *Repository) Exists(ctx context.Context, id string) (bool, error) {
+	var count int64
+
+	err := self.db.WithContext(ctx).Model(&models.Attachment{}).Where("id = ?", id).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,39 @@
+package attachments
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/okira-e/go-as-your-backend/app/modules/users"
+)
+
+func SetupRoutes(api fiber.Router, handler *Handler, usersService *users.Service) {
+	api.Get("/posts/:postId/attachments", users.OptionalAuthMiddleware(usersService), handler.FindByPost)
+
+	api = api.Group("/attachments")
+
+	api.Post(
+		"/",
+		users.AuthMiddleware(usersService),
+		users.RequireVerifiedEmail(usersService),
+		handler.Upload,
+	)
+	api.Get(
+		"/:id",
+		users.AuthMiddleware(usersService),
+		handler.FindOne,
+	)
+	api.Patch(
+		"/:id",
+		users.AuthMiddleware(usersService),
+		handler.Update,
+	)
+	api.Delete(
+		"/:id",
+		users.AuthMiddleware(usersService),
+		handler.Delete,
+	)
+}
+
+// SetupFileRoutes serves the files of the local storage under its public URL.
+func SetupFileRoutes(app fiber.Router, publicURL string, handler *Handler, usersService *users.Service) {
+	app.Get(publicURL+"/*", users.OptionalAuthMiddleware(usersService), handler.Serve)
+}
@@ -0,0 +1,293 @@
+package attachments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gabriel-vasile/mimetype"
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/modules/posts"
+	"github.com/okira-e/go-as-your-backend/app/storage"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+// defaultAllowedTypes are accepted when UPLOAD_ALLOWED_TYPES isn't set. Types that browsers render as
+// documents, such as HTML or SVG, are deliberately left out.
+const defaultAllowedTypes = "image/jpeg,image/png,image/gif,image/webp,application/pdf"
+
+// MaxUploadSize returns the largest accepted file in bytes, configured through MAX_UPLOAD_SIZE.
+func MaxUploadSize() int {
+	return utils.GetEnvInt("MAX_UPLOAD_SIZE", 10<<20)
+}
+
+type Service struct {
+	repository      *Repository
+	postsRepository *posts.Repository
+	storage         storage.Storage
+	maxSize         int64
+	allowedTypes    []string
+}
+
+func NewService(repository *Repository, postsRepository *posts.Repository, storage storage.Storage) *Service {
+	allowedTypes := []string{}
+	for _, contentType := range strings.Split(utils.GetEnv("UPLOAD_ALLOWED_TYPES", defaultAllowedTypes), ",") {
+		if contentType = strings.TrimSpace(contentType); contentType != "" {
+			allowedTypes = append(allowedTypes, contentType)
+		}
+	}
+
+	return &Service{
+		repository:      repository,
+		postsRepository: postsRepository,
+		storage:         storage,
+		maxSize:         int64(MaxUploadSize()),
+		allowedTypes:    allowedTypes,
+	}
+}
+
+// Upload stores a file and records it as an attachment of the user, linked to postId when given.
+// The content type is detected from the file's content; the one sent by the client is ignored.
+func (self *Service) Upload(
+	ctx context.Context,
+	fileHeader *multipart.FileHeader,
+	postId *string,
+	user models.JwtUser,
+) (*models.AttachmentDto, int, error) {
+	if fileHeader.Size == 0 {
+		return &models.AttachmentDto{}, 400, fmt.Errorf("The file is empty.")
+	}
+
+	if fileHeader.Size > self.maxSize {
+		return &models.AttachmentDto{}, 413, fmt.Errorf("The file exceeds the maximum upload size of %d bytes.", self.maxSize)
+	}
+
+	if postId != nil {
+		if statusCode, err := self.checkPost(ctx, *postId, user); err != nil {
+			return &models.AttachmentDto{}, statusCode, err
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return &models.AttachmentDto{}, 400, fmt.Errorf("The file couldn't be read.")
+	}
+	defer file.Close()
+
+	contentType, err := mimetype.DetectReader(file)
+	if err != nil {
+		return &models.AttachmentDto{}, 400, fmt.Errorf("The file couldn't be read.")
+	}
+
+	if !self.allowed(contentType) {
+		return &models.AttachmentDto{}, 415, fmt.Errorf("Files of type %s are not allowed.", contentType.String())
+	}
+
+	entity := models.NewAttachment(user.UserID, postId, fileName(fileHeader.Filename), contentType.String(), fileHeader.Size)
+	entity.StorageKey = fmt.Sprintf("attachments/%s/%s%s", entity.CreatedAt.Format("2006/01"), entity.ID, contentType.Extension())
+
+	if strings.HasPrefix(contentType.String(), "image/") {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return &models.AttachmentDto{}, 500, err
+		}
+
+		// Formats without a registered decoder simply go without dimensions.
+		if config, _, err := image.DecodeConfig(file); err == nil {
+			entity.Width = &config.Width
+			entity.Height = &config.Height
+		}
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return &models.AttachmentDto{}, 500, err
+	}
+
+	if err := self.storage.Put(ctx, entity.StorageKey, file, fileHeader.Size, entity.ContentType); err != nil {
+		return &models.AttachmentDto{}, 500, err
+	}
+
+	created, err := self.repository.Create(ctx, entity)
+	if err != nil {
+		self.deleteObject(entity.StorageKey)
+		return &models.AttachmentDto{}, 500, err
+	}
+
+	return created.ToDto(self.storage.URL(created.StorageKey)), 201, nil
+}
+
+// FindByID returns an attachment to its owner or an admin.
+func (self *Service) FindByID(ctx context.Context, id string, user models.JwtUser) (*models.AttachmentDto, int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil {
+		return &models.AttachmentDto{}, 404, fmt.Errorf("Attachment with ID %s not found.", id)
+	}
+
+	if entity.UserID != user.UserID && !user.HasRole(models.RoleAdmin) {
+		return &models.AttachmentDto{}, 404, fmt.Errorf("Attachment with ID %s not found.", id)
+	}
+
+	return entity.ToDto(self.storage.URL(entity.StorageKey)), 200, nil
+}
+
+// Open returns the stored file of an attachment to whoever may see it: its owner, admins, and the
+// viewers who may read the post it is linked to.
+func (self *Service) Open(ctx context.Context, key string, viewer *models.JwtUser) (*models.Attachment, io.ReadCloser, int, error) {
+	entity, err := self.repository.FindByStorageKey(ctx, key)
+	if err != nil || !self.canRead(ctx, entity, viewer) {
+		return nil, nil, 404, fmt.Errorf("File not found.")
+	}
+
+	body, err := self.storage.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, 404, fmt.Errorf("File not found.")
+	}
+	if err != nil {
+		return nil, nil, 500, err
+	}
+
+	return entity, body, 200, nil
+}
+
+// canRead reports whether the viewer may see the attachment. Attachments not linked to a post are only
+// seen by their owner and admins.
+func (self *Service) canRead(ctx context.Context, entity *models.Attachment, viewer *models.JwtUser) bool {
+	if viewer != nil && (entity.UserID == viewer.UserID || viewer.HasRole(models.RoleAdmin)) {
+		return true
+	}
+
+	if entity.PostID == nil {
+		return false
+	}
+
+	_, err := self.postsRepository.Reachable(viewer).FindByID(ctx, *entity.PostID)
+
+	return err == nil
+}
+
+// FindByPost lists the attachments of a post the viewer may read.
+func (self *Service) FindByPost(ctx context.Context, postId string, viewer *models.JwtUser) ([]*models.AttachmentDto, int, error) {
+	if _, err := self.postsRepository.Reachable(viewer).FindByID(ctx, postId); err != nil {
+		return nil, 404, fmt.Errorf("Post with ID %s not found.", postId)
+	}
+
+	entities, err := self.repository.FindByPost(ctx, postId)
+	if err != nil {
+		return nil, 500, err
+	}
+
+	entitiesDto := make([]*models.AttachmentDto, len(entities))
+	for i, entity := range entities {
+		entitiesDto[i] = entity.ToDto(self.storage.URL(entity.StorageKey))
+	}
+
+	return entitiesDto, 200, nil
+}
+
+// Update links an attachment to a post or unlinks it. Only its owner may do so, and only to their own posts.
+func (self *Service) Update(
+	ctx context.Context,
+	id string,
+	entityDto *models.UpdateAttachmentDto,
+	user models.JwtUser,
+) (*models.AttachmentDto, int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil || entity.UserID != user.UserID {
+		return &models.AttachmentDto{}, 404, fmt.Errorf("Attachment with ID %s not found.", id)
+	}
+
+	if entityDto.PostID != nil {
+		if statusCode, err := self.checkPost(ctx, *entityDto.PostID, user); err != nil {
+			return &models.AttachmentDto{}, statusCode, err
+		}
+	}
+
+	entity.PostID = entityDto.PostID
+
+	if err := self.repository.Update(ctx, entity); err != nil {
+		return &models.AttachmentDto{}, 500, err
+	}
+
+	return entity.ToDto(self.storage.URL(entity.StorageKey)), 200, nil
+}
+
+// Delete removes an attachment and its file. Its owner and admins may do so.
+func (self *Service) Delete(ctx context.Context, id string, user models.JwtUser) (int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil {
+		return 404, fmt.Errorf("Attachment with ID %s not found.", id)
+	}
+
+	if entity.UserID != user.UserID && !user.HasRole(models.RoleAdmin) {
+		return 403, fmt.Errorf("Only the owner can delete this attachment.")
+	}
+
+	if err := self.repository.Delete(ctx, id); err != nil {
+		return 500, err
+	}
+
+	self.deleteObject(entity.StorageKey)
+
+	return 200, nil
+}
+
+// checkPost makes sure the post exists and that the user may attach files to it.
+func (self *Service) checkPost(ctx context.Context, postId string, user models.JwtUser) (int, error) {
+	post, err := self.postsRepository.FindByID(ctx, postId)
+	if err != nil {
+		return 404, fmt.Errorf("Post with ID %s not found.", postId)
+	}
+
+	if post.UserID != user.UserID && !user.HasRole(models.RoleAdmin) {
+		return 403, fmt.Errorf("Only the author can attach files to this post.")
+	}
+
+	return 200, nil
+}
+
+// allowed matches the exact detected type, not its parents, so text/html doesn't pass as text/plain.
+func (self *Service) allowed(contentType *mimetype.MIME) bool {
+	for _, allowedType := range self.allowedTypes {
+		if contentType.Is(allowedType) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deleteObject removes a stored file. Failures are only logged since the record is already gone and the
+// orphaned object is harmless.
+func (self *Service) deleteObject(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := self.storage.Delete(ctx, key); err != nil {
+		Log(SeverityWarn, "deleteObject: failed to delete stored file", map[string]any{"key": key, "error": err.Error()})
+	}
+}
+
+// fileName keeps the base name of the client's file name, capped to the column size.
+func fileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" {
+		name = "file"
+	}
+
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+
+	return name
+}
@@ -0,0 +1,205 @@
+package bookmarks
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(Service *Service) *Handler {
+	return &Handler{service: Service}
+}
+
+func (self *Handler) FindAll(ctx *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(ctx.Query("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(ctx.Query("offset", "0"))
+
+	queryOptions := spec.QueryOptions{
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	filter, err := spec.ParseFilter(ctx.Query("filter", ""))
+	if err != nil {
+		return utils.Err(ctx, 400, "Invalid filter parameter", err)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	entities, err := self.service.FindAll(ctx.Context(), &queryOptions, filter, user)
+	if err != nil {
+		Log(SeverityError, "FindAll: failed to fetch bookmarks", map[string]any{"userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entities", nil)
+	}
+
+	return utils.Ok(ctx, 200, "", entities)
+}
+
+func (self *Handler) GetCount(ctx *fiber.Ctx) error {
+	filter, err := spec.ParseFilter(ctx.Query("filter", ""))
+	if err != nil {
+		return utils.Err(ctx, 400, "Invalid filter parameter", err)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	count, err := self.service.GetCount(ctx.Context(), filter, user)
+	if err != nil {
+		Log(SeverityError, "GetCount: failed to count bookmarks", map[string]any{"userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to count entities", nil)
+	}
+
+	return utils.Ok(ctx, 200, "", count)
+}
+
+func (self *Handler) Create(ctx *fiber.Ctx) error {
+	entityDto := models.CreateBookmarkDto{}
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	bookmark, statusCode, err := self.service.Create(ctx.Context(), &entityDto, user)
+	if err != nil {
+		Log(SeverityWarn, "Create: failed to create bookmark", map[string]any{"postId": entityDto.PostID, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", bookmark)
+}
+
+func (self *Handler) Delete(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Bookmark ID not provided", nil)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	statusCode, err := self.service.Delete(ctx.Context(), id, user)
+	if err != nil {
+		Log(SeverityWarn, "Delete: failed to delete bookmark", map[string]any{"bookmarkId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "Bookmark deleted", nil)
+}
+
+func (self *Handler) FindLists(ctx *fiber.Ctx) error {
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	lists, err := self.service.FindLists(ctx.Context(), user)
+	if err != nil {
+		Log(SeverityError, "FindLists: failed to fetch bookmark lists", map[string]any{"userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entities", nil)
+	}
+
+	return utils.Ok(ctx, 200, "", lists)
+}
+
+func (self *Handler) CreateList(ctx *fiber.Ctx) error {
+	entityDto := models.CreateBookmarkListDto{}
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	list, statusCode, err := self.service.CreateList(ctx.Context(), &entityDto, user)
+	if err != nil {
+		Log(SeverityWarn, "CreateList: failed to create bookmark list", map[string]any{"userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", list)
+}
+
+func (self *Handler) UpdateList(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Bookmark list ID not provided", nil)
+	}
+
+	entityDto := models.UpdateBookmarkListDto{}
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	list, statusCode, err := self.service.UpdateList(ctx.Context(), id, &entityDto, user)
+	if err != nil {
+		Log(SeverityWarn, "UpdateList: failed to update bookmark list", map[string]any{"listId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", list)
+}
+
+func (self *Handler) DeleteList(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Bookmark list ID not provided", nil)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	statusCode, err := self.service.DeleteList(ctx.Context(), id, user)
+	if err != nil {
+		Log(SeverityWarn, "DeleteList: failed to delete bookmark list", map[string]any{"listId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "Bookmark list deleted", nil)
+}
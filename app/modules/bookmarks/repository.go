@@ -0,0 +1,220 @@
+package bookmarks
+
+import (
+	"context"
+	"errors"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ spec.Repository[models.Bookmark] = (*Repository)(nil)
+
+// errAlreadyBookmarked is returned by Create when the post is already in the same place.
+var errAlreadyBookmarked = errors.New("entity already exists")
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Create saves the bookmark unless the user already bookmarked the post in the same list, or
+// outside of any list when it has none.
+func (self *Repository) Create(ctx context.Context, entity *models.Bookmark) (*models.Bookmark, error) {
+	if entity == nil {
+		return nil, errors.New("entity cannot be nil")
+	}
+
+	result := self.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(entity)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errAlreadyBookmarked
+	}
+
+	return entity, nil
+}
+
+func (self *Repository) FindByID(ctx context.Context, id string) (*models.Bookmark, error) {
+	var entity models.Bookmark
+
+	err := self.db.WithContext(ctx).First(&entity, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+func (self *Repository) FindAll(ctx context.Context, queryOptions *spec.QueryOptions, filter *spec.Filter) ([]models.Bookmark, error) {
+	var entities []models.Bookmark
+
+	tx := self.db.WithContext(ctx)
+	tx = spec.ApplyPagination(tx, queryOptions)
+	tx, err := spec.ApplyFilters(tx, filter, models.Bookmark{})
+	if err != nil {
+		return entities, err
+	}
+
+	err = tx.Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+func (self *Repository) Count(ctx context.Context, filter *spec.Filter) (int64, error) {
+	var count int64
+
+	tx := self.db.WithContext(ctx)
+	tx, err := spec.ApplyFilters(tx, filter, models.Bookmark{})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Model(&models.Bookmark{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (self *Repository) Update(ctx context.Context, entity *models.Bookmark) error {
+	if entity == nil {
+		return errors.New("entity cannot be nil")
+	}
+
+	result := self.db.WithContext(ctx).Model(entity).Updates(entity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
+
+func (self *Repository) Delete(ctx context.Context, id string) error {
+	result := self.db.WithContext(ctx).Delete(&models.Bookmark{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
+
+func (self *Repository) Exists(ctx context.Context, id string) (bool, error) {
+	var count int64
+
+	err := self.db.WithContext(ctx).Model(&models.Bookmark{}).Where("id = ?", id).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (self *Repository) CreateList(ctx context.Context, entity *models.BookmarkList) (*models.BookmarkList, error) {
+	if entity == nil {
+		return nil, errors.New("entity cannot be nil")
+	}
+
+	if err := self.db.WithContext(ctx).Create(entity).Error; err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+func (self *Repository) FindListByID(ctx context.Context, id string) (*models.BookmarkList, error) {
+	var entity models.BookmarkList
+
+	err := self.db.WithContext(ctx).First(&entity, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+// FindLists returns the lists of a user by name, along with the number of bookmarks in each.
+func (self *Repository) FindLists(ctx context.Context, userId string) ([]models.BookmarkListDto, error) {
+	var lists []models.BookmarkListDto
+
+	err := self.db.WithContext(ctx).
+		Model(&models.BookmarkList{}).
+		Select("bookmark_lists.id, bookmark_lists.name, bookmark_lists.created_at, bookmark_lists.updated_at, COUNT(bookmarks.id) AS bookmarks_count").
+		Joins("LEFT JOIN bookmarks ON bookmarks.list_id = bookmark_lists.id").
+		Where("bookmark_lists.user_id = ?", userId).
+		Group("bookmark_lists.id").
+		Order("bookmark_lists.name ASC").
+		Scan(&lists).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+// ListNameTaken reports whether the user has another list with the name than the one with excludeId.
+func (self *Repository) ListNameTaken(ctx context.Context, userId string, name string, excludeId string) (bool, error) {
+	var count int64
+
+	err := self.db.WithContext(ctx).
+		Model(&models.BookmarkList{}).
+		Where("user_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", userId, name, excludeId).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (self *Repository) UpdateList(ctx context.Context, entity *models.BookmarkList) error {
+	if entity == nil {
+		return errors.New("entity cannot be nil")
+	}
+
+	result := self.db.WithContext(ctx).Model(entity).Updates(entity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
+
+// DeleteList removes a list along with the bookmarks in it.
+func (self *Repository) DeleteList(ctx context.Context, id string) error {
+	result := self.db.WithContext(ctx).Delete(&models.BookmarkList{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package bookmarks
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/okira-e/go-as-your-backend/app/modules/users"
+)
+
+func SetupRoutes(api fiber.Router, handler *Handler, usersService *users.Service) {
+	api = api.Group("/bookmarks", users.AuthMiddleware(usersService))
+
+	api.Get("/", handler.FindAll)
+	api.Get("/count", handler.GetCount)
+	api.Post("/", handler.Create)
+	api.Delete("/:id", handler.Delete)
+
+	api.Get("/lists", handler.FindLists)
+	api.Post("/lists", handler.CreateList)
+	api.Patch("/lists/:id", handler.UpdateList)
+	api.Delete("/lists/:id", handler.DeleteList)
+}
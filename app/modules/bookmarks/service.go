@@ -0,0 +1,218 @@
+package bookmarks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/modules/posts"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+)
+
+type Service struct {
+	repository   *Repository
+	postsService *posts.Service
+}
+
+func NewService(repository *Repository, postsService *posts.Service) *Service {
+	return &Service{repository: repository, postsService: postsService}
+}
+
+// FindAll lists the user's bookmarks matching the filter, newest first unless the filter orders them,
+// with the bookmarked posts embedded.
+func (self *Service) FindAll(
+	ctx context.Context,
+	queryOptions *spec.QueryOptions,
+	filter *spec.Filter,
+	user models.JwtUser,
+) ([]*models.BookmarkDto, error) {
+	filter = ownedBy(filter, user.UserID)
+	if len(filter.OrderBy) == 0 {
+		filter.OrderBy = []spec.OrderByClause{{Column: "id", Direction: "DESC"}}
+	}
+
+	entities, err := self.repository.FindAll(ctx, queryOptions, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	postIds := make([]string, len(entities))
+	for i, entity := range entities {
+		postIds[i] = entity.PostID
+	}
+
+	postEntities, err := self.postsService.FindByIDs(ctx, postIds, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	postsDto, err := self.postsService.ToDtos(ctx, postEntities, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	postsById := make(map[string]*models.PostDto, len(postsDto))
+	for _, postDto := range postsDto {
+		postsById[postDto.ID] = postDto
+	}
+
+	entitiesDto := make([]*models.BookmarkDto, len(entities))
+	for i, entity := range entities {
+		entitiesDto[i] = entity.ToDto()
+		entitiesDto[i].Post = postsById[entity.PostID]
+	}
+
+	return entitiesDto, nil
+}
+
+func (self *Service) GetCount(ctx context.Context, filter *spec.Filter, user models.JwtUser) (int64, error) {
+	count, err := self.repository.Count(ctx, ownedBy(filter, user.UserID))
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// Create bookmarks a post the user can read, in one of their lists when ListID is set.
+func (self *Service) Create(
+	ctx context.Context,
+	entityDto *models.CreateBookmarkDto,
+	user models.JwtUser,
+) (*models.BookmarkDto, int, error) {
+	if _, err := self.postsService.FindByID(ctx, entityDto.PostID, &user); err != nil {
+		return &models.BookmarkDto{}, 404, fmt.Errorf("Post with ID %s not found.", entityDto.PostID)
+	}
+
+	if entityDto.ListID != nil {
+		list, err := self.repository.FindListByID(ctx, *entityDto.ListID)
+		if err != nil || list.UserID != user.UserID {
+			return &models.BookmarkDto{}, 404, fmt.Errorf("Bookmark list with ID %s not found.", *entityDto.ListID)
+		}
+	}
+
+	entity, err := self.repository.Create(ctx, entityDto.FromDto(user.UserID))
+	if errors.Is(err, errAlreadyBookmarked) {
+		if entityDto.ListID != nil {
+			return &models.BookmarkDto{}, 409, fmt.Errorf("The post is already in this list.")
+		}
+
+		return &models.BookmarkDto{}, 409, fmt.Errorf("The post is already bookmarked.")
+	}
+	if err != nil {
+		return &models.BookmarkDto{}, 500, err
+	}
+
+	return entity.ToDto(), 201, nil
+}
+
+// Delete removes one of the user's bookmarks.
+func (self *Service) Delete(ctx context.Context, id string, user models.JwtUser) (int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil || entity.UserID != user.UserID {
+		return 404, fmt.Errorf("Bookmark with ID %s not found.", id)
+	}
+
+	if err := self.repository.Delete(ctx, id); err != nil {
+		return 500, err
+	}
+
+	return 200, nil
+}
+
+func (self *Service) FindLists(ctx context.Context, user models.JwtUser) ([]models.BookmarkListDto, error) {
+	lists, err := self.repository.FindLists(ctx, user.UserID)
+	if err != nil {
+		return lists, err
+	}
+
+	return lists, nil
+}
+
+func (self *Service) CreateList(
+	ctx context.Context,
+	entityDto *models.CreateBookmarkListDto,
+	user models.JwtUser,
+) (*models.BookmarkListDto, int, error) {
+	entity := entityDto.FromDto(user.UserID)
+
+	taken, err := self.repository.ListNameTaken(ctx, user.UserID, entity.Name, entity.ID)
+	if err != nil {
+		return &models.BookmarkListDto{}, 500, err
+	}
+	if taken {
+		return &models.BookmarkListDto{}, 409, fmt.Errorf("You already have a list named %s.", entity.Name)
+	}
+
+	entity, err = self.repository.CreateList(ctx, entity)
+	if err != nil {
+		return &models.BookmarkListDto{}, 500, err
+	}
+
+	return entity.ToDto(), 201, nil
+}
+
+// UpdateList renames one of the user's lists.
+func (self *Service) UpdateList(
+	ctx context.Context,
+	id string,
+	entityDto *models.UpdateBookmarkListDto,
+	user models.JwtUser,
+) (*models.BookmarkListDto, int, error) {
+	entity, err := self.repository.FindListByID(ctx, id)
+	if err != nil || entity.UserID != user.UserID {
+		return &models.BookmarkListDto{}, 404, fmt.Errorf("Bookmark list with ID %s not found.", id)
+	}
+
+	now := time.Now().UTC()
+	entity.Name = strings.TrimSpace(entityDto.Name)
+	entity.UpdatedAt = &now
+
+	taken, err := self.repository.ListNameTaken(ctx, user.UserID, entity.Name, entity.ID)
+	if err != nil {
+		return &models.BookmarkListDto{}, 500, err
+	}
+	if taken {
+		return &models.BookmarkListDto{}, 409, fmt.Errorf("You already have a list named %s.", entity.Name)
+	}
+
+	if err := self.repository.UpdateList(ctx, entity); err != nil {
+		return &models.BookmarkListDto{}, 500, err
+	}
+
+	return entity.ToDto(), 200, nil
+}
+
+// DeleteList removes one of the user's lists and the bookmarks in it.
+func (self *Service) DeleteList(ctx context.Context, id string, user models.JwtUser) (int, error) {
+	entity, err := self.repository.FindListByID(ctx, id)
+	if err != nil || entity.UserID != user.UserID {
+		return 404, fmt.Errorf("Bookmark list with ID %s not found.", id)
+	}
+
+	if err := self.repository.DeleteList(ctx, id); err != nil {
+		return 500, err
+	}
+
+	return 200, nil
+}
+
+// ownedBy restricts the filter to the user's bookmarks, on top of the conditions given by the client.
+func ownedBy(filter *spec.Filter, userId string) *spec.Filter {
+	scoped := spec.Filter{}
+	if filter != nil {
+		scoped = *filter
+	}
+
+	scoped.Where.And = append(slices.Clone(scoped.Where.And), spec.WhereCondition{
+		Column:   "user_id",
+		Operator: "=",
+		Value:    userId,
+	})
+
+	return &scoped
+}
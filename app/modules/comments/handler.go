@@ -0,0 +1,172 @@
+package comments
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(Service *Service) *Handler {
+	return &Handler{service: Service}
+}
+
+func (self *Handler) FindThread(ctx *fiber.Ctx) error {
+	postId := ctx.Params("postId")
+	if postId == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	limit, _ := strconv.Atoi(ctx.Query("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	cursorOptions := spec.CursorOptions{
+		Cursor: ctx.Query("cursor", ""),
+		Limit:  limit,
+	}
+
+	if cursorOptions.Cursor != "" {
+		if err := utils.ValidateVar(cursorOptions.Cursor, "uuid"); err != nil {
+			return utils.Err(ctx, 400, "Invalid cursor parameter", nil)
+		}
+	}
+
+	var parentId *string
+	if parent := ctx.Query("parent_id", ""); parent != "" {
+		if err := utils.ValidateVar(parent, "uuid"); err != nil {
+			return utils.Err(ctx, 400, "Invalid parent_id parameter", nil)
+		}
+		parentId = &parent
+	}
+
+	page, statusCode, err := self.service.FindThread(ctx.Context(), postId, parentId, &cursorOptions, utils.GetOptionalUserFromContext(ctx))
+	if err != nil {
+		Log(SeverityError, "FindThread: failed to fetch comments", map[string]any{"postId": postId, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", page)
+}
+
+func (self *Handler) Create(ctx *fiber.Ctx) error {
+	postId := ctx.Params("postId")
+	if postId == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	entityDto := models.CreateCommentDto{}
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	comment, statusCode, err := self.service.Create(ctx.Context(), postId, &entityDto, user)
+	if err != nil {
+		Log(SeverityWarn, "Create: failed to create comment", map[string]any{"postId": postId, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	Log(SeverityInfo, "Create: comment created", map[string]any{"commentId": comment.ID, "postId": postId, "userId": user.UserID})
+
+	return utils.Ok(ctx, statusCode, "", comment)
+}
+
+func (self *Handler) Update(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Comment ID not provided", nil)
+	}
+
+	entityDto := models.UpdateCommentDto{}
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	comment, statusCode, err := self.service.Update(ctx.Context(), id, &entityDto, user)
+	if err != nil {
+		Log(SeverityWarn, "Update: failed to update comment", map[string]any{"commentId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", comment)
+}
+
+func (self *Handler) Delete(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Comment ID not provided", nil)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	statusCode, err := self.service.Delete(ctx.Context(), id, user)
+	if err != nil {
+		Log(SeverityWarn, "Delete: failed to delete comment", map[string]any{"commentId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	Log(SeverityInfo, "Delete: comment deleted", map[string]any{"commentId": id, "userId": user.UserID})
+
+	return utils.Ok(ctx, statusCode, "Comment deleted", nil)
+}
+
+func (self *Handler) Lock(ctx *fiber.Ctx) error {
+	return self.setLocked(ctx, true)
+}
+
+func (self *Handler) Unlock(ctx *fiber.Ctx) error {
+	return self.setLocked(ctx, false)
+}
+
+func (self *Handler) setLocked(ctx *fiber.Ctx, locked bool) error {
+	postId := ctx.Params("postId")
+	if postId == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	statusCode, err := self.service.SetLocked(ctx.Context(), postId, locked, user)
+	if err != nil {
+		Log(SeverityWarn, "setLocked: failed to change comments lock", map[string]any{"postId": postId, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	Log(SeverityInfo, "setLocked: comments lock changed", map[string]any{"postId": postId, "locked": locked, "userId": user.UserID})
+
+	return utils.Ok(ctx, statusCode, "", fiber.Map{"comments_locked": locked})
+}
@@ -0,0 +1,207 @@
+package comments
+
+import (
+	"context"
+	"errors"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ spec.Repository[models.Comment] = (*Repository)(nil)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Create inserts the comment and bumps the comment count of its post and the reply count of its parent.
+func (self *Repository) Create(ctx context.Context, entity *models.Comment) (*models.Comment, error) {
+	if entity == nil {
+		return nil, errors.New("entity cannot be nil")
+	}
+
+	err := self.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(entity).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&models.Post{}).
+			Where("id = ?", entity.PostID).
+			Update("comments_count", gorm.Expr("comments_count + 1")).Error
+		if err != nil {
+			return err
+		}
+
+		if entity.ParentID != nil {
+			return tx.Model(&models.Comment{}).
+				Where("id = ?", *entity.ParentID).
+				Update("replies_count", gorm.Expr("replies_count + 1")).Error
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+func (self *Repository) FindByID(ctx context.Context, id string) (*models.Comment, error) {
+	var entity models.Comment
+
+	err := self.db.WithContext(ctx).First(&entity, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+func (self *Repository) FindAll(ctx context.Context, queryOptions *spec.QueryOptions, filter *spec.Filter) ([]models.Comment, error) {
+	var entities []models.Comment
+
+	tx := self.db.WithContext(ctx)
+	tx = spec.ApplyPagination(tx, queryOptions)
+	tx, err := spec.ApplyFilters(tx, filter, models.Comment{})
+	if err != nil {
+		return entities, err
+	}
+
+	err = tx.Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// FindThread returns a page of the direct replies to parentId, or of the top level comments of the
+// post when parentId is nil, oldest first. Comment IDs are UUIDv7 so they double as the cursor.
+func (self *Repository) FindThread(
+	ctx context.Context,
+	postId string,
+	parentId *string,
+	cursorOptions *spec.CursorOptions,
+) (spec.Page[models.Comment], error) {
+	var entities []models.Comment
+
+	tx := self.db.WithContext(ctx).Where("post_id = ?", postId)
+	if parentId == nil {
+		tx = tx.Where("parent_id IS NULL")
+	} else {
+		tx = tx.Where("parent_id = ?", *parentId)
+	}
+
+	if cursorOptions.Cursor != "" {
+		tx = tx.Where("id > ?", cursorOptions.Cursor)
+	}
+
+	// Fetch one extra row to know whether there is a next page.
+	err := tx.Order("id ASC").Limit(cursorOptions.Limit + 1).Find(&entities).Error
+	if err != nil {
+		return spec.Page[models.Comment]{}, err
+	}
+
+	page := spec.Page[models.Comment]{Items: entities}
+	if len(entities) > cursorOptions.Limit {
+		page.Items = entities[:cursorOptions.Limit]
+		nextCursor := page.Items[len(page.Items)-1].ID
+		page.NextCursor = &nextCursor
+	}
+
+	return page, nil
+}
+
+func (self *Repository) Count(ctx context.Context, filter *spec.Filter) (int64, error) {
+	var count int64
+
+	tx := self.db.WithContext(ctx)
+	tx, err := spec.ApplyFilters(tx, filter, models.Comment{})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Model(&models.Comment{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (self *Repository) Update(ctx context.Context, entity *models.Comment) error {
+	if entity == nil {
+		return errors.New("entity cannot be nil")
+	}
+
+	result := self.db.WithContext(ctx).Model(entity).Updates(entity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
+
+// Delete blanks the comment and marks it deleted, keeping the row so its replies stay in place, and
+// takes it off its post's comment count and its parent's reply count.
+func (self *Repository) Delete(ctx context.Context, id string) error {
+	return self.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var entity models.Comment
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&entity, "id = ? AND deleted_at IS NULL", id).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("entity not found")
+			}
+
+			return err
+		}
+
+		err = tx.Model(&entity).Updates(map[string]any{
+			"content":    "",
+			"deleted_at": gorm.Expr("now()"),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.Post{}).
+			Where("id = ?", entity.PostID).
+			Update("comments_count", gorm.Expr("GREATEST(comments_count - 1, 0)")).Error
+		if err != nil {
+			return err
+		}
+
+		if entity.ParentID != nil {
+			return tx.Model(&models.Comment{}).
+				Where("id = ?", *entity.ParentID).
+				Update("replies_count", gorm.Expr("GREATEST(replies_count - 1, 0)")).Error
+		}
+
+		return nil
+	})
+}
+
+func (self *Repository) Exists(ctx context.Context, id string) (bool, error) {
+	var count int64
+
+	err := self.db.WithContext(ctx).Model(&models.Comment{}).Where("id = ?", id).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,41 @@
+package comments
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/okira-e/go-as-your-backend/app/modules/users"
+)
+
+func SetupRoutes(api fiber.Router, handler *Handler, usersService *users.Service) {
+	postComments := api.Group("/posts/:postId/comments")
+
+	postComments.Get("/", users.OptionalAuthMiddleware(usersService), handler.FindThread)
+	postComments.Post(
+		"/",
+		users.AuthMiddleware(usersService),
+		users.RequireVerifiedEmail(usersService),
+		handler.Create,
+	)
+	postComments.Post(
+		"/lock",
+		users.AuthMiddleware(usersService),
+		handler.Lock,
+	)
+	postComments.Post(
+		"/unlock",
+		users.AuthMiddleware(usersService),
+		handler.Unlock,
+	)
+
+	api = api.Group("/comments")
+
+	api.Patch(
+		"/:id",
+		users.AuthMiddleware(usersService),
+		handler.Update,
+	)
+	api.Delete(
+		"/:id",
+		users.AuthMiddleware(usersService),
+		handler.Delete,
+	)
+}
@@ -0,0 +1,164 @@
+package comments
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/modules/posts"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+type Service struct {
+	repository      *Repository
+	postsRepository *posts.Repository
+}
+
+func NewService(repository *Repository, postsRepository *posts.Repository) *Service {
+	return &Service{repository: repository, postsRepository: postsRepository}
+}
+
+// FindThread returns a page of the comments on a post that reply to parentId, or of its top level
+// comments when parentId is nil. viewer is nil for anonymous requests.
+func (self *Service) FindThread(
+	ctx context.Context,
+	postId string,
+	parentId *string,
+	cursorOptions *spec.CursorOptions,
+	viewer *models.JwtUser,
+) (spec.Page[*models.CommentDto], int, error) {
+	post, err := self.postsRepository.Reachable(viewer).FindByID(ctx, postId)
+	if err != nil || !post.IsPublic() {
+		return spec.Page[*models.CommentDto]{}, 404, fmt.Errorf("Post with ID %s not found.", postId)
+	}
+
+	page, err := self.repository.FindThread(ctx, postId, parentId, cursorOptions)
+	if err != nil {
+		return spec.Page[*models.CommentDto]{}, 500, err
+	}
+
+	entitiesDto := make([]*models.CommentDto, len(page.Items))
+	for i, entity := range page.Items {
+		entitiesDto[i] = entity.ToDto()
+	}
+
+	return spec.Page[*models.CommentDto]{Items: entitiesDto, NextCursor: page.NextCursor}, 200, nil
+}
+
+func (self *Service) Create(
+	ctx context.Context,
+	postId string,
+	entityDto *models.CreateCommentDto,
+	user models.JwtUser,
+) (*models.CommentDto, int, error) {
+	post, err := self.postsRepository.Reachable(&user).FindByID(ctx, postId)
+	if err != nil || !post.IsPublic() {
+		return &models.CommentDto{}, 404, fmt.Errorf("Post with ID %s not found.", postId)
+	}
+
+	if post.CommentsLocked && !user.HasRole(models.RoleAdmin, models.RoleModerator) {
+		return &models.CommentDto{}, 403, fmt.Errorf("Comments on this post are locked.")
+	}
+
+	depth := 0
+	if entityDto.ParentID != nil {
+		parent, err := self.repository.FindByID(ctx, *entityDto.ParentID)
+		if err != nil || parent.PostID != postId {
+			return &models.CommentDto{}, 404, fmt.Errorf("Comment with ID %s not found on this post.", *entityDto.ParentID)
+		}
+
+		if parent.DeletedAt != nil {
+			return &models.CommentDto{}, 400, fmt.Errorf("Deleted comments can't be replied to.")
+		}
+
+		depth = parent.Depth + 1
+	}
+
+	maxDepth := utils.GetEnvInt("COMMENTS_MAX_DEPTH", 5)
+	if depth > maxDepth {
+		return &models.CommentDto{}, 400, fmt.Errorf("Replies can't be nested more than %d levels deep.", maxDepth)
+	}
+
+	entity := entityDto.FromDto(postId, user.UserID, depth)
+
+	entity, err = self.repository.Create(ctx, entity)
+	if err != nil {
+		return &models.CommentDto{}, 500, err
+	}
+
+	return entity.ToDto(), 201, nil
+}
+
+// Update edits the content of a comment. Only its author may do so.
+func (self *Service) Update(
+	ctx context.Context,
+	id string,
+	entityDto *models.UpdateCommentDto,
+	user models.JwtUser,
+) (*models.CommentDto, int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil || entity.DeletedAt != nil {
+		return &models.CommentDto{}, 404, fmt.Errorf("Comment with ID %s not found.", id)
+	}
+
+	if entity.UserID != user.UserID {
+		return &models.CommentDto{}, 403, fmt.Errorf("Only the author can edit this comment.")
+	}
+
+	post, err := self.postsRepository.FindByID(ctx, entity.PostID)
+	if err != nil {
+		return &models.CommentDto{}, 404, fmt.Errorf("Post with ID %s not found.", entity.PostID)
+	}
+
+	if post.CommentsLocked && !user.HasRole(models.RoleAdmin, models.RoleModerator) {
+		return &models.CommentDto{}, 403, fmt.Errorf("Comments on this post are locked.")
+	}
+
+	now := time.Now().UTC()
+	entity.Content = entityDto.Content
+	entity.UpdatedAt = &now
+
+	if err := self.repository.Update(ctx, entity); err != nil {
+		return &models.CommentDto{}, 500, err
+	}
+
+	return entity.ToDto(), 200, nil
+}
+
+// Delete removes a comment. Its author and moderators may do so.
+func (self *Service) Delete(ctx context.Context, id string, user models.JwtUser) (int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil || entity.DeletedAt != nil {
+		return 404, fmt.Errorf("Comment with ID %s not found.", id)
+	}
+
+	if entity.UserID != user.UserID && !user.HasRole(models.RoleAdmin, models.RoleModerator) {
+		return 403, fmt.Errorf("Only the author or a moderator can delete this comment.")
+	}
+
+	if err := self.repository.Delete(ctx, id); err != nil {
+		return 500, err
+	}
+
+	return 200, nil
+}
+
+// SetLocked locks or unlocks the comments on a post. The post's author and moderators may do so.
+func (self *Service) SetLocked(ctx context.Context, postId string, locked bool, user models.JwtUser) (int, error) {
+	post, err := self.postsRepository.FindByID(ctx, postId)
+	if err != nil {
+		return 404, fmt.Errorf("Post with ID %s not found.", postId)
+	}
+
+	if post.UserID != user.UserID && !user.HasRole(models.RoleAdmin, models.RoleModerator) {
+		return 403, fmt.Errorf("Only the author or a moderator can lock the comments of this post.")
+	}
+
+	if err := self.postsRepository.SetCommentsLocked(ctx, postId, locked); err != nil {
+		return 500, err
+	}
+
+	return 200, nil
+}
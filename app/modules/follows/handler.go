@@ -0,0 +1,65 @@
+package follows
+
+import (
+	"github.com/gofiber/fiber/v2"
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(Service *Service) *Handler {
+	return &Handler{service: Service}
+}
+
+func (self *Handler) Get(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "User ID not provided", nil)
+	}
+
+	follow, statusCode, err := self.service.Get(ctx.Context(), id, utils.GetOptionalUserFromContext(ctx))
+	if err != nil {
+		Log(SeverityWarn, "Get: failed to fetch follows", map[string]any{"userId": id, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", follow)
+}
+
+func (self *Handler) Follow(ctx *fiber.Ctx) error {
+	return self.setFollowing(ctx, true)
+}
+
+func (self *Handler) Unfollow(ctx *fiber.Ctx) error {
+	return self.setFollowing(ctx, false)
+}
+
+func (self *Handler) setFollowing(ctx *fiber.Ctx, follow bool) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "User ID not provided", nil)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	var result *models.FollowDto
+	var statusCode int
+	if follow {
+		result, statusCode, err = self.service.Follow(ctx.Context(), id, user)
+	} else {
+		result, statusCode, err = self.service.Unfollow(ctx.Context(), id, user)
+	}
+	if err != nil {
+		Log(SeverityWarn, "setFollowing: failed to update follow", map[string]any{"userId": id, "followerId": user.UserID, "follow": follow, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", result)
+}
@@ -0,0 +1,70 @@
+package follows
+
+import (
+	"context"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Follow makes the follower follow the followee. Following a user twice is a no-op.
+func (self *Repository) Follow(ctx context.Context, follow *models.UserFollow) error {
+	return self.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(follow).Error
+}
+
+func (self *Repository) Unfollow(ctx context.Context, followerId string, followeeId string) error {
+	return self.db.WithContext(ctx).
+		Where("follower_id = ? AND followee_id = ?", followerId, followeeId).
+		Delete(&models.UserFollow{}).Error
+}
+
+func (self *Repository) IsFollowing(ctx context.Context, followerId string, followeeId string) (bool, error) {
+	var count int64
+
+	err := self.db.WithContext(ctx).
+		Model(&models.UserFollow{}).
+		Where("follower_id = ? AND followee_id = ?", followerId, followeeId).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// CountFollows returns the number of followers of a user and the number of users they follow.
+func (self *Repository) CountFollows(ctx context.Context, userId string) (int64, int64, error) {
+	var followers, following int64
+
+	err := self.db.WithContext(ctx).Model(&models.UserFollow{}).Where("followee_id = ?", userId).Count(&followers).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = self.db.WithContext(ctx).Model(&models.UserFollow{}).Where("follower_id = ?", userId).Count(&following).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return followers, following, nil
+}
+
+func (self *Repository) UserExists(ctx context.Context, userId string) (bool, error) {
+	var count int64
+
+	err := self.db.WithContext(ctx).Model(&models.User{}).Where("id = ? AND is_active", userId).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
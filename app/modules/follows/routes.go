@@ -0,0 +1,14 @@
+package follows
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/okira-e/go-as-your-backend/app/modules/users"
+)
+
+func SetupRoutes(api fiber.Router, handler *Handler, usersService *users.Service) {
+	api = api.Group("/users/:id/follow")
+
+	api.Get("/", users.OptionalAuthMiddleware(usersService), handler.Get)
+	api.Put("/", users.AuthMiddleware(usersService), handler.Follow)
+	api.Delete("/", users.AuthMiddleware(usersService), handler.Unfollow)
+}
@@ -0,0 +1,88 @@
+package follows
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+)
+
+type Service struct {
+	repository *Repository
+}
+
+func NewService(repository *Repository) *Service {
+	return &Service{repository: repository}
+}
+
+// Get returns the follow counts of a user and whether the viewer follows them. viewer is nil for
+// anonymous requests.
+func (self *Service) Get(ctx context.Context, userId string, viewer *models.JwtUser) (*models.FollowDto, int, error) {
+	exists, err := self.repository.UserExists(ctx, userId)
+	if err != nil {
+		return &models.FollowDto{}, 500, err
+	}
+	if !exists {
+		return &models.FollowDto{}, 404, fmt.Errorf("User with ID %s not found.", userId)
+	}
+
+	return self.followDto(ctx, userId, viewer)
+}
+
+// Follow makes the user follow another one, which lets them read that user's followers-only posts.
+func (self *Service) Follow(ctx context.Context, userId string, user models.JwtUser) (*models.FollowDto, int, error) {
+	if userId == user.UserID {
+		return &models.FollowDto{}, 400, fmt.Errorf("You can't follow yourself.")
+	}
+
+	exists, err := self.repository.UserExists(ctx, userId)
+	if err != nil {
+		return &models.FollowDto{}, 500, err
+	}
+	if !exists {
+		return &models.FollowDto{}, 404, fmt.Errorf("User with ID %s not found.", userId)
+	}
+
+	follow := models.UserFollow{
+		FollowerID: user.UserID,
+		FolloweeID: userId,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	if err := self.repository.Follow(ctx, &follow); err != nil {
+		return &models.FollowDto{}, 500, err
+	}
+
+	return self.followDto(ctx, userId, &user)
+}
+
+func (self *Service) Unfollow(ctx context.Context, userId string, user models.JwtUser) (*models.FollowDto, int, error) {
+	if err := self.repository.Unfollow(ctx, user.UserID, userId); err != nil {
+		return &models.FollowDto{}, 500, err
+	}
+
+	return self.followDto(ctx, userId, &user)
+}
+
+func (self *Service) followDto(ctx context.Context, userId string, viewer *models.JwtUser) (*models.FollowDto, int, error) {
+	followers, following, err := self.repository.CountFollows(ctx, userId)
+	if err != nil {
+		return &models.FollowDto{}, 500, err
+	}
+
+	isFollowing := false
+	if viewer != nil {
+		isFollowing, err = self.repository.IsFollowing(ctx, viewer.UserID, userId)
+		if err != nil {
+			return &models.FollowDto{}, 500, err
+		}
+	}
+
+	return &models.FollowDto{
+		UserID:         userId,
+		Following:      isFollowing,
+		FollowersCount: followers,
+		FollowingCount: following,
+	}, 200, nil
+}
@@ -0,0 +1,164 @@
+package moderation
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(Service *Service) *Handler {
+	return &Handler{service: Service}
+}
+
+func (self *Handler) Report(ctx *fiber.Ctx) error {
+	postId := ctx.Params("postId")
+	if postId == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	entityDto := models.CreatePostReportDto{}
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	report, statusCode, err := self.service.Report(ctx.Context(), postId, &entityDto, user)
+	if err != nil {
+		Log(SeverityWarn, "Report: failed to report post", map[string]any{"postId": postId, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", report)
+}
+
+func (self *Handler) FindQueue(ctx *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(ctx.Query("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(ctx.Query("offset", "0"))
+
+	queryOptions := spec.QueryOptions{
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	items, err := self.service.FindQueue(ctx.Context(), &queryOptions)
+	if err != nil {
+		Log(SeverityError, "FindQueue: failed to fetch the moderation queue", map[string]any{"error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entities", nil)
+	}
+
+	return utils.Ok(ctx, 200, "", items)
+}
+
+func (self *Handler) CountQueue(ctx *fiber.Ctx) error {
+	count, err := self.service.CountQueue(ctx.Context())
+	if err != nil {
+		Log(SeverityError, "CountQueue: failed to count the moderation queue", map[string]any{"error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entities count", nil)
+	}
+
+	return utils.Ok(ctx, 200, "", count)
+}
+
+func (self *Handler) FindReports(ctx *fiber.Ctx) error {
+	postId := ctx.Params("postId")
+	if postId == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	limit, _ := strconv.Atoi(ctx.Query("limit", "50"))
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(ctx.Query("offset", "0"))
+
+	queryOptions := spec.QueryOptions{
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	reports, statusCode, err := self.service.FindReports(ctx.Context(), postId, &queryOptions)
+	if err != nil {
+		Log(SeverityWarn, "FindReports: failed to fetch reports", map[string]any{"postId": postId, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", reports)
+}
+
+func (self *Handler) Act(ctx *fiber.Ctx) error {
+	postId := ctx.Params("postId")
+	if postId == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	entityDto := models.CreateModerationActionDto{}
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	action, statusCode, err := self.service.Act(ctx.Context(), postId, &entityDto, user)
+	if err != nil {
+		Log(SeverityWarn, "Act: failed to moderate post", map[string]any{"postId": postId, "action": entityDto.Action, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	Log(SeverityInfo, "Act: post moderated", map[string]any{"postId": postId, "action": action.Action, "userId": user.UserID})
+
+	return utils.Ok(ctx, statusCode, "", action)
+}
+
+func (self *Handler) FindActions(ctx *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(ctx.Query("limit", "50"))
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	offset, _ := strconv.Atoi(ctx.Query("offset", "0"))
+
+	queryOptions := spec.QueryOptions{
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	filter, err := spec.ParseFilter(ctx.Query("filter", ""))
+	if err != nil {
+		return utils.Err(ctx, 400, "Invalid filter parameter", err)
+	}
+
+	actions, err := self.service.FindActions(ctx.Context(), &queryOptions, filter)
+	if err != nil {
+		Log(SeverityError, "FindActions: failed to fetch moderation actions", map[string]any{"error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entities", nil)
+	}
+
+	return utils.Ok(ctx, 200, "", actions)
+}
@@ -0,0 +1,218 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errAlreadyReported is returned by CreateReport when the user has an open report on the post.
+var errAlreadyReported = errors.New("entity already exists")
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Transaction runs fn with a repository bound to a single database transaction.
+func (self *Repository) Transaction(ctx context.Context, fn func(repository *Repository) error) error {
+	return self.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&Repository{db: tx})
+	})
+}
+
+// CreateReport saves the report unless the reporter already has an open report on the post.
+func (self *Repository) CreateReport(ctx context.Context, entity *models.PostReport) (*models.PostReport, error) {
+	if entity == nil {
+		return nil, errors.New("entity cannot be nil")
+	}
+
+	result := self.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(entity)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errAlreadyReported
+	}
+
+	return entity, nil
+}
+
+// FindQueue lists the posts with open reports, most reported first.
+func (self *Repository) FindQueue(ctx context.Context, queryOptions *spec.QueryOptions) ([]models.ModerationQueueItemDto, error) {
+	var items []models.ModerationQueueItemDto
+
+	tx := self.db.WithContext(ctx).
+		Model(&models.PostReport{}).
+		Select(`posts.id AS post_id, posts.title, posts.slug, posts.user_id, posts.hidden,
+			COUNT(post_reports.id) AS reports_count,
+			MIN(post_reports.created_at) AS first_reported_at,
+			MAX(post_reports.created_at) AS last_reported_at`).
+		Joins("JOIN posts ON posts.id = post_reports.post_id").
+		Where("post_reports.resolved_at IS NULL").
+		Group("posts.id").
+		Order("reports_count DESC, last_reported_at DESC")
+	tx = spec.ApplyPagination(tx, queryOptions)
+
+	err := tx.Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// CountQueue counts the posts with open reports.
+func (self *Repository) CountQueue(ctx context.Context) (int64, error) {
+	var count int64
+
+	err := self.db.WithContext(ctx).
+		Model(&models.PostReport{}).
+		Where("resolved_at IS NULL").
+		Distinct("post_id").
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CountOpenReasons returns, for each of the given posts, the number of open reports for each reason.
+func (self *Repository) CountOpenReasons(ctx context.Context, postIds []string) (map[string]map[models.ReportReason]int64, error) {
+	var rows []struct {
+		PostID string
+		Reason models.ReportReason
+		Count  int64
+	}
+
+	counts := map[string]map[models.ReportReason]int64{}
+	if len(postIds) == 0 {
+		return counts, nil
+	}
+
+	err := self.db.WithContext(ctx).
+		Model(&models.PostReport{}).
+		Select("post_id, reason, COUNT(*) AS count").
+		Where("post_id IN ? AND resolved_at IS NULL", postIds).
+		Group("post_id, reason").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if counts[row.PostID] == nil {
+			counts[row.PostID] = map[models.ReportReason]int64{}
+		}
+		counts[row.PostID][row.Reason] = row.Count
+	}
+
+	return counts, nil
+}
+
+// FindReports lists the reports on a post, open and resolved, newest first.
+func (self *Repository) FindReports(ctx context.Context, postId string, queryOptions *spec.QueryOptions) ([]models.PostReport, error) {
+	var entities []models.PostReport
+
+	tx := self.db.WithContext(ctx).Where("post_id = ?", postId).Order("id DESC")
+	tx = spec.ApplyPagination(tx, queryOptions)
+
+	err := tx.Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// ResolveReports closes the open reports on a post with the given action and returns how many it closed.
+func (self *Repository) ResolveReports(ctx context.Context, postId string, actionId string, at time.Time) (int64, error) {
+	result := self.db.WithContext(ctx).
+		Model(&models.PostReport{}).
+		Where("post_id = ? AND resolved_at IS NULL", postId).
+		Updates(map[string]any{
+			"resolved_at": at,
+			"action_id":   actionId,
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (self *Repository) CreateAction(ctx context.Context, entity *models.ModerationAction) (*models.ModerationAction, error) {
+	if entity == nil {
+		return nil, errors.New("entity cannot be nil")
+	}
+
+	if err := self.db.WithContext(ctx).Create(entity).Error; err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+// SetReportsResolved stores the number of reports an action closed.
+func (self *Repository) SetReportsResolved(ctx context.Context, actionId string, count int64) error {
+	return self.db.WithContext(ctx).
+		Model(&models.ModerationAction{}).
+		Where("id = ?", actionId).
+		Update("reports_resolved", count).Error
+}
+
+func (self *Repository) FindActions(ctx context.Context, queryOptions *spec.QueryOptions, filter *spec.Filter) ([]models.ModerationAction, error) {
+	var entities []models.ModerationAction
+
+	tx := self.db.WithContext(ctx)
+	tx = spec.ApplyPagination(tx, queryOptions)
+	tx, err := spec.ApplyFilters(tx, filter, models.ModerationAction{})
+	if err != nil {
+		return entities, err
+	}
+
+	err = tx.Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// SetPostHidden hides a post from everyone but moderators and admins, or shows it again.
+func (self *Repository) SetPostHidden(ctx context.Context, postId string, hidden bool) error {
+	result := self.db.WithContext(ctx).
+		Model(&models.Post{}).
+		Where("id = ?", postId).
+		Update("hidden", hidden)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
+
+// DeletePost removes a post. Its reports, comments, reactions and the like are deleted with it.
+func (self *Repository) DeletePost(ctx context.Context, postId string) error {
+	result := self.db.WithContext(ctx).Delete(&models.Post{}, "id = ?", postId)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
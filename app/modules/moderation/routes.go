@@ -0,0 +1,28 @@
+package moderation
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/modules/users"
+)
+
+func SetupRoutes(api fiber.Router, handler *Handler, usersService *users.Service) {
+	api.Post(
+		"/posts/:postId/reports",
+		users.AuthMiddleware(usersService),
+		users.RequireVerifiedEmail(usersService),
+		handler.Report,
+	)
+
+	api = api.Group(
+		"/moderation",
+		users.AuthMiddleware(usersService),
+		users.RoleMiddleware(models.RoleAdmin, models.RoleModerator),
+	)
+
+	api.Get("/queue", handler.FindQueue)
+	api.Get("/queue/count", handler.CountQueue)
+	api.Get("/actions", handler.FindActions)
+	api.Get("/posts/:postId/reports", handler.FindReports)
+	api.Post("/posts/:postId/actions", handler.Act)
+}
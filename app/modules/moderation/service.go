@@ -0,0 +1,191 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/modules/posts"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+)
+
+type Service struct {
+	repository      *Repository
+	postsRepository *posts.Repository
+}
+
+func NewService(repository *Repository, postsRepository *posts.Repository) *Service {
+	return &Service{repository: repository, postsRepository: postsRepository}
+}
+
+// Report files a report on a post the user can read. A user has at most one open report per post.
+func (self *Service) Report(
+	ctx context.Context,
+	postId string,
+	entityDto *models.CreatePostReportDto,
+	user models.JwtUser,
+) (*models.PostReportDto, int, error) {
+	post, err := self.postsRepository.Reachable(&user).FindByID(ctx, postId)
+	if err != nil {
+		return &models.PostReportDto{}, 404, fmt.Errorf("Post with ID %s not found.", postId)
+	}
+
+	if post.UserID == user.UserID {
+		return &models.PostReportDto{}, 400, fmt.Errorf("You can't report your own post.")
+	}
+
+	entity, err := self.repository.CreateReport(ctx, entityDto.FromDto(postId, user.UserID))
+	if errors.Is(err, errAlreadyReported) {
+		return &models.PostReportDto{}, 409, fmt.Errorf("You already reported this post.")
+	}
+	if err != nil {
+		return &models.PostReportDto{}, 500, err
+	}
+
+	return entity.ToDto(), 201, nil
+}
+
+// FindQueue lists the posts with open reports, most reported first, with the reasons they were reported for.
+func (self *Service) FindQueue(ctx context.Context, queryOptions *spec.QueryOptions) ([]models.ModerationQueueItemDto, error) {
+	items, err := self.repository.FindQueue(ctx, queryOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	postIds := make([]string, len(items))
+	for i, item := range items {
+		postIds[i] = item.PostID
+	}
+
+	reasons, err := self.repository.CountOpenReasons(ctx, postIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		items[i].Reasons = reasons[items[i].PostID]
+		if items[i].Reasons == nil {
+			items[i].Reasons = map[models.ReportReason]int64{}
+		}
+	}
+
+	return items, nil
+}
+
+func (self *Service) CountQueue(ctx context.Context) (int64, error) {
+	count, err := self.repository.CountQueue(ctx)
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// FindReports lists the reports on a post, open and resolved, newest first.
+func (self *Service) FindReports(
+	ctx context.Context,
+	postId string,
+	queryOptions *spec.QueryOptions,
+) ([]*models.PostReportDto, int, error) {
+	if _, err := self.postsRepository.FindByID(ctx, postId); err != nil {
+		return nil, 404, fmt.Errorf("Post with ID %s not found.", postId)
+	}
+
+	entities, err := self.repository.FindReports(ctx, postId, queryOptions)
+	if err != nil {
+		return nil, 500, err
+	}
+
+	entitiesDto := make([]*models.PostReportDto, len(entities))
+	for i, entity := range entities {
+		entitiesDto[i] = entity.ToDto()
+	}
+
+	return entitiesDto, 200, nil
+}
+
+// Act hides, restores, deletes a post or dismisses its reports. The action is recorded and closes the
+// open reports on the post.
+func (self *Service) Act(
+	ctx context.Context,
+	postId string,
+	entityDto *models.CreateModerationActionDto,
+	user models.JwtUser,
+) (*models.ModerationActionDto, int, error) {
+	post, err := self.postsRepository.FindByID(ctx, postId)
+	if err != nil {
+		return &models.ModerationActionDto{}, 404, fmt.Errorf("Post with ID %s not found.", postId)
+	}
+
+	switch entityDto.Action {
+	case models.ModerationActionHide:
+		if post.Hidden {
+			return &models.ModerationActionDto{}, 409, fmt.Errorf("The post is already hidden.")
+		}
+	case models.ModerationActionRestore:
+		if !post.Hidden {
+			return &models.ModerationActionDto{}, 409, fmt.Errorf("The post isn't hidden.")
+		}
+	}
+
+	entity := entityDto.FromDto(post, user.UserID)
+
+	err = self.repository.Transaction(ctx, func(repository *Repository) error {
+		if _, err := repository.CreateAction(ctx, entity); err != nil {
+			return err
+		}
+
+		resolved, err := repository.ResolveReports(ctx, post.ID, entity.ID, entity.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		entity.ReportsResolved = resolved
+		if err := repository.SetReportsResolved(ctx, entity.ID, resolved); err != nil {
+			return err
+		}
+
+		switch entity.Action {
+		case models.ModerationActionHide:
+			return repository.SetPostHidden(ctx, post.ID, true)
+		case models.ModerationActionRestore:
+			return repository.SetPostHidden(ctx, post.ID, false)
+		case models.ModerationActionDelete:
+			return repository.DeletePost(ctx, post.ID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return &models.ModerationActionDto{}, 500, err
+	}
+
+	return entity.ToDto(), 201, nil
+}
+
+// FindActions lists the recorded moderation actions, newest first unless the filter orders them.
+func (self *Service) FindActions(
+	ctx context.Context,
+	queryOptions *spec.QueryOptions,
+	filter *spec.Filter,
+) ([]*models.ModerationActionDto, error) {
+	if filter == nil {
+		filter = &spec.Filter{}
+	}
+	if len(filter.OrderBy) == 0 {
+		filter.OrderBy = []spec.OrderByClause{{Column: "id", Direction: "DESC"}}
+	}
+
+	entities, err := self.repository.FindActions(ctx, queryOptions, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	entitiesDto := make([]*models.ModerationActionDto, len(entities))
+	for i, entity := range entities {
+		entitiesDto[i] = entity.ToDto()
+	}
+
+	return entitiesDto, nil
+}
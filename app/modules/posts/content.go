@@ -0,0 +1,85 @@
+package posts
+
+import (
+	"bytes"
+	"html"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+const (
+	excerptLength = 200
+	// wordsPerMinute is an average adult reading speed.
+	wordsPerMinute = 200
+)
+
+// markdown renders GitHub flavoured Markdown. Raw HTML in the source is dropped.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// htmlPolicy is the allowlist rendered HTML goes through before it's stored. On top of the usual user
+// content elements, it keeps the `language-*` classes fenced code blocks get so clients can highlight them.
+var htmlPolicy = func() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code", "pre")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+
+	return policy
+}()
+
+var textPolicy = bluemonday.StrictPolicy()
+
+// renderedContent is what is derived from a post's Markdown when it's written.
+type renderedContent struct {
+	HTML               string
+	Excerpt            string
+	ReadingTimeMinutes int
+}
+
+// renderContent renders Markdown to sanitized HTML and derives an excerpt and reading time from it.
+func renderContent(source string) (renderedContent, error) {
+	var rendered bytes.Buffer
+	if err := markdown.Convert([]byte(source), &rendered); err != nil {
+		return renderedContent{}, err
+	}
+
+	safeHTML := htmlPolicy.SanitizeBytes(rendered.Bytes())
+
+	words := strings.Fields(html.UnescapeString(string(textPolicy.SanitizeBytes(safeHTML))))
+
+	readingTime := 0
+	if len(words) > 0 {
+		readingTime = int(math.Ceil(float64(len(words)) / wordsPerMinute))
+	}
+
+	return renderedContent{
+		HTML:               string(safeHTML),
+		Excerpt:            excerpt(words),
+		ReadingTimeMinutes: readingTime,
+	}, nil
+}
+
+// excerpt joins words up to excerptLength characters, cutting at a word boundary.
+func excerpt(words []string) string {
+	var text strings.Builder
+
+	for _, word := range words {
+		length := utf8.RuneCountInString(text.String())
+		if length > 0 && length+1+utf8.RuneCountInString(word) > excerptLength {
+			return text.String() + "…"
+		}
+
+		if length > 0 {
+			text.WriteByte(' ')
+		}
+		text.WriteString(word)
+	}
+
+	return text.String()
+}
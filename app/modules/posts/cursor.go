@@ -0,0 +1,52 @@
+package posts
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+// FeedCursor is the position of a post in the public feed, which is ordered by publication time and ID.
+type FeedCursor struct {
+	PublishedAt time.Time
+	ID          string
+}
+
+// encodeFeedCursor returns the opaque cursor pointing right after the post.
+func encodeFeedCursor(post *models.Post) string {
+	var publishedAt time.Time
+	if post.PublishedAt != nil {
+		publishedAt = *post.PublishedAt
+	}
+
+	raw := publishedAt.UTC().Format(time.RFC3339Nano) + "|" + post.ID
+
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeFeedCursor(cursor string) (*FeedCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	publishedAtText, id, found := strings.Cut(string(raw), "|")
+	if !found {
+		return nil, errors.New("invalid cursor")
+	}
+
+	publishedAt, err := time.Parse(time.RFC3339Nano, publishedAtText)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	if err := utils.ValidateVar(id, "uuid"); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &FeedCursor{PublishedAt: publishedAt, ID: id}, nil
+}
@@ -0,0 +1,358 @@
+package posts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+// feedSize is the number of posts included in every feed.
+const feedSize = 20
+
+type feedFormat string
+
+const (
+	feedFormatRSS  feedFormat = "rss"
+	feedFormatAtom feedFormat = "atom"
+	feedFormatJSON feedFormat = "json"
+)
+
+var feedContentTypes = map[feedFormat]string{
+	feedFormatRSS:  "application/rss+xml; charset=utf-8",
+	feedFormatAtom: "application/atom+xml; charset=utf-8",
+	feedFormatJSON: "application/feed+json; charset=utf-8",
+}
+
+// feedInfo describes the feed itself, independent of the output format.
+type feedInfo struct {
+	Title       string
+	Description string
+	HomeURL     string
+	SelfURL     string
+	Updated     time.Time
+}
+
+// newFeedInfo builds the feed metadata. Post links point to the client application.
+func newFeedInfo(selfURL string, tag string) feedInfo {
+	title := utils.GetEnv("FEED_TITLE", "Posts")
+
+	if tag != "" {
+		title += " tagged " + models.NormalizeTagName(tag)
+	}
+
+	return feedInfo{
+		Title:       title,
+		Description: "Latest published posts",
+		HomeURL:     strings.TrimRight(utils.GetEnv("CLIENT_URL", ""), "/"),
+		SelfURL:     selfURL,
+	}
+}
+
+func (self feedInfo) postURL(post *models.Post) string {
+	return self.HomeURL + "/posts/" + post.Slug
+}
+
+// feedLastModified returns the most recent change across the given posts.
+func feedLastModified(entities []models.Post) time.Time {
+	var lastModified time.Time
+
+	for _, entity := range entities {
+		modified := postModifiedAt(&entity)
+		if modified.After(lastModified) {
+			lastModified = modified
+		}
+	}
+
+	return lastModified
+}
+
+// feedETag is derived from the format and the identity and modification time of each post,
+// so it changes whenever a post enters, leaves or changes within the feed.
+func feedETag(format feedFormat, info feedInfo, entities []models.Post) string {
+	hash := sha256.New()
+	hash.Write([]byte(string(format) + "\n" + info.Title + "\n"))
+
+	for _, entity := range entities {
+		hash.Write([]byte(entity.ID + ":" + postModifiedAt(&entity).UTC().Format(time.RFC3339Nano) + "\n"))
+	}
+
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+func postModifiedAt(post *models.Post) time.Time {
+	if post.UpdatedAt != nil {
+		return *post.UpdatedAt
+	}
+	if post.PublishedAt != nil {
+		return *post.PublishedAt
+	}
+
+	return post.CreatedAt
+}
+
+func postPublishedAt(post *models.Post) time.Time {
+	if post.PublishedAt != nil {
+		return *post.PublishedAt
+	}
+
+	return post.CreatedAt
+}
+
+func authorName(user *models.User) string {
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
+
+func postContentHTML(post *models.Post) string {
+	if post.ContentHTML != nil {
+		return *post.ContentHTML
+	}
+
+	return ""
+}
+
+func postTagNames(post *models.Post) []string {
+	names := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		names = append(names, tag.Name)
+	}
+
+	return names
+}
+
+// renderFeed serializes the posts in the requested format.
+func renderFeed(format feedFormat, info feedInfo, entities []models.Post) ([]byte, error) {
+	switch format {
+	case feedFormatRSS:
+		return renderRSS(info, entities)
+	case feedFormatAtom:
+		return renderAtom(info, entities)
+	default:
+		return renderJSONFeed(info, entities)
+	}
+}
+
+type rssFeed struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	DCNS      string     `xml:"xmlns:dc,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string      `xml:"title"`
+	Link          string      `xml:"link"`
+	Description   string      `xml:"description"`
+	AtomLink      rssAtomLink `xml:"atom:link"`
+	LastBuildDate string      `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem   `xml:"item"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Creator     string   `xml:"dc:creator"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"content:encoded"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func renderRSS(info feedInfo, entities []models.Post) ([]byte, error) {
+	feed := rssFeed{
+		Version:   "2.0",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		DCNS:      "http://purl.org/dc/elements/1.1/",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       info.Title,
+			Link:        info.HomeURL,
+			Description: info.Description,
+			AtomLink: rssAtomLink{
+				Href: info.SelfURL,
+				Rel:  "self",
+				Type: "application/rss+xml",
+			},
+			Items: make([]rssItem, 0, len(entities)),
+		},
+	}
+
+	if !info.Updated.IsZero() {
+		feed.Channel.LastBuildDate = info.Updated.UTC().Format(time.RFC1123Z)
+	}
+
+	for _, entity := range entities {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       entity.Title,
+			Link:        info.postURL(&entity),
+			GUID:        rssGUID{Value: "urn:uuid:" + entity.ID},
+			PubDate:     postPublishedAt(&entity).UTC().Format(time.RFC1123Z),
+			Creator:     authorName(&entity.User),
+			Categories:  postTagNames(&entity),
+			Description: entity.Excerpt,
+			Content:     postContentHTML(&entity),
+		})
+	}
+
+	output, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), output...), nil
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Links      []atomLink     `xml:"link"`
+	Author     atomAuthor     `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    atomText       `xml:"summary"`
+	Content    atomText       `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+func renderAtom(info feedInfo, entities []models.Post) ([]byte, error) {
+	updated := info.Updated
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+
+	feed := atomFeed{
+		Title:   info.Title,
+		ID:      info.SelfURL,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: info.SelfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: info.HomeURL, Rel: "alternate"},
+		},
+		Entries: make([]atomEntry, 0, len(entities)),
+	}
+
+	for _, entity := range entities {
+		categories := make([]atomCategory, 0, len(entity.Tags))
+		for _, name := range postTagNames(&entity) {
+			categories = append(categories, atomCategory{Term: name})
+		}
+
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:      entity.Title,
+			ID:         "urn:uuid:" + entity.ID,
+			Updated:    postModifiedAt(&entity).UTC().Format(time.RFC3339),
+			Published:  postPublishedAt(&entity).UTC().Format(time.RFC3339),
+			Links:      []atomLink{{Href: info.postURL(&entity), Rel: "alternate"}},
+			Author:     atomAuthor{Name: authorName(&entity.User)},
+			Categories: categories,
+			Summary:    atomText{Type: "text", Value: entity.Excerpt},
+			Content:    atomText{Type: "html", Value: postContentHTML(&entity)},
+		})
+	}
+
+	output, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), output...), nil
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	Summary       string           `json:"summary"`
+	DatePublished string           `json:"date_published"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []jsonFeedAuthor `json:"authors"`
+	Tags          []string         `json:"tags"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+func renderJSONFeed(info feedInfo, entities []models.Post) ([]byte, error) {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       info.Title,
+		HomePageURL: info.HomeURL,
+		FeedURL:     info.SelfURL,
+		Description: info.Description,
+		Items:       make([]jsonFeedItem, 0, len(entities)),
+	}
+
+	for _, entity := range entities {
+		feed.Items = append(feed.Items, jsonFeedItem{
+			ID:            entity.ID,
+			URL:           info.postURL(&entity),
+			Title:         entity.Title,
+			ContentHTML:   postContentHTML(&entity),
+			Summary:       entity.Excerpt,
+			DatePublished: postPublishedAt(&entity).UTC().Format(time.RFC3339),
+			DateModified:  postModifiedAt(&entity).UTC().Format(time.RFC3339),
+			Authors:       []jsonFeedAuthor{{Name: authorName(&entity.User)}},
+			Tags:          postTagNames(&entity),
+		})
+	}
+
+	return json.Marshal(feed)
+}
@@ -1,7 +1,11 @@
 package posts
 
 import (
+	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	. "github.com/okira-e/go-as-your-backend/app/logging"
@@ -32,42 +36,117 @@ func (self *Handler) FindAll(ctx *fiber.Ctx) error {
 		return utils.Err(ctx, 400, "Invalid filter parameter", err)
 	}
 
-	entities, err := self.service.FindAll(ctx.Context(), &queryOptions, filter)
+	entities, err := self.service.FindAll(ctx.Context(), &queryOptions, filter, utils.GetOptionalUserFromContext(ctx))
 	if err != nil {
 		Log(SeverityError, "FindAll: failed to fetch posts", map[string]any{"error": err.Error()})
 		return utils.Err(ctx, 500, "Failed to fetch entities", nil)
 	}
 
-	entitiesDto := make([]*models.PostDto, len(entities))
-	for i, entity := range entities {
-		entitiesDto[i] = entity.ToDto()
+	entitiesDto, err := self.service.ToDtos(ctx.Context(), entities, utils.GetOptionalUserFromContext(ctx))
+	if err != nil {
+		Log(SeverityError, "FindAll: failed to fetch post details", map[string]any{"error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entities", nil)
 	}
 
 	return utils.Ok(ctx, 200, "", entitiesDto)
 }
 
 func (self *Handler) GetPublished(ctx *fiber.Ctx) error {
-	entities, err := self.service.GetPublished(ctx.Context())
+	limit, _ := strconv.Atoi(ctx.Query("limit", "10"))
+	if limit <= 0 || limit > 100 {
+		limit = 10
+	}
+
+	entities, err := self.service.GetPublished(ctx.Context(), limit, utils.GetOptionalUserFromContext(ctx))
 	if err != nil {
 		Log(SeverityError, "GetPublished: failed to fetch posts", map[string]any{"error": err.Error()})
 		return utils.Err(ctx, 500, "Failed to fetch entities", nil)
 	}
 
-	entitiesDto := make([]*models.PostDto, len(entities))
-	for i, entity := range entities {
-		entitiesDto[i] = entity.ToDto()
+	entitiesDto, err := self.service.ToDtos(ctx.Context(), entities, utils.GetOptionalUserFromContext(ctx))
+	if err != nil {
+		Log(SeverityError, "GetPublished: failed to fetch post details", map[string]any{"error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entities", nil)
 	}
 
 	return utils.Ok(ctx, 200, "", entitiesDto)
 }
 
+// GetFeed pages through the published posts, newest first. Pass the next_cursor of a page as cursor
+// to get the following one.
+func (self *Handler) GetFeed(ctx *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(ctx.Query("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	page, statusCode, err := self.service.GetFeed(ctx.Context(), ctx.Query("cursor", ""), limit, utils.GetOptionalUserFromContext(ctx))
+	if err != nil {
+		Log(SeverityError, "GetFeed: failed to fetch posts", map[string]any{"error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", page)
+}
+
+func (self *Handler) FindOne(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	entity, err := self.service.FindByID(ctx.Context(), id, utils.GetOptionalUserFromContext(ctx))
+	if err != nil {
+		return utils.Err(ctx, 404, "Post not found", nil)
+	}
+
+	entityDto, err := self.service.ToDto(ctx.Context(), entity, utils.GetOptionalUserFromContext(ctx))
+	if err != nil {
+		Log(SeverityError, "FindOne: failed to fetch post details", map[string]any{"postId": id, "error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entity", nil)
+	}
+
+	if entity.IsPublic() {
+		utils.SetViewedPost(ctx, entity.ID)
+	}
+
+	return utils.Ok(ctx, 200, "", entityDto)
+}
+
+func (self *Handler) FindBySlug(ctx *fiber.Ctx) error {
+	slug := ctx.Params("slug")
+	if slug == "" {
+		return utils.Err(ctx, 400, "Slug not provided", nil)
+	}
+
+	entity, redirect, err := self.service.FindBySlug(ctx.Context(), slug, utils.GetOptionalUserFromContext(ctx))
+	if err != nil || !entity.IsPublic() {
+		return utils.Err(ctx, 404, "Post not found", nil)
+	}
+
+	if redirect {
+		location := strings.TrimSuffix(ctx.Path(), slug) + entity.Slug
+		return ctx.Redirect(location, 301)
+	}
+
+	entityDto, err := self.service.ToDto(ctx.Context(), entity, utils.GetOptionalUserFromContext(ctx))
+	if err != nil {
+		Log(SeverityError, "FindBySlug: failed to fetch post details", map[string]any{"slug": slug, "error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entity", nil)
+	}
+
+	utils.SetViewedPost(ctx, entity.ID)
+
+	return utils.Ok(ctx, 200, "", entityDto)
+}
+
 func (self *Handler) GetCount(ctx *fiber.Ctx) error {
 	filter, err := spec.ParseFilter(ctx.Query("filter", ""))
 	if err != nil {
 		return utils.Err(ctx, 400, "Invalid filter parameter", err)
 	}
 
-	count, err := self.service.GetCount(ctx.Context(), filter)
+	count, err := self.service.GetCount(ctx.Context(), filter, utils.GetOptionalUserFromContext(ctx))
 	if err != nil {
 		Log(SeverityError, "GetCount: failed to fetch count", map[string]any{"error": err.Error()})
 		return utils.Err(ctx, 500, "Failed to fetch entities count", nil)
@@ -93,13 +172,279 @@ func (self *Handler) Create(ctx *fiber.Ctx) error {
 		return utils.Err(ctx, 401, "Missing user in headers", nil)
 	}
 
-	post, err := self.service.Create(ctx.Context(), &entityDto, user.UserID)
+	post, statusCode, err := self.service.Create(ctx.Context(), &entityDto, user.UserID)
 	if err != nil {
 		Log(SeverityError, "Create: failed to create post", map[string]any{"userId": user.UserID, "error": err.Error()})
-		return utils.Err(ctx, 500, "Failed to create entity", err.Error())
+		return utils.Err(ctx, statusCode, "Failed to create entity", err.Error())
 	}
 
 	Log(SeverityInfo, "Create: post created", map[string]any{"postId": post.ID, "userId": user.UserID})
 
-	return utils.Ok(ctx, 201, "", post)
+	return utils.Ok(ctx, statusCode, "", post)
+}
+
+func (self *Handler) Update(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	entityDto := models.UpdatePostDto{}
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	post, statusCode, err := self.service.Update(ctx.Context(), id, &entityDto, user)
+	if err != nil {
+		Log(SeverityWarn, "Update: failed to update post", map[string]any{"postId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	Log(SeverityInfo, "Update: post updated", map[string]any{"postId": post.ID, "userId": user.UserID})
+
+	return utils.Ok(ctx, statusCode, "", post)
+}
+
+func (self *Handler) Transition(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	entityDto := models.TransitionPostDto{}
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	post, statusCode, err := self.service.Transition(ctx.Context(), id, &entityDto, user)
+	if err != nil {
+		Log(SeverityWarn, "Transition: failed to change post status", map[string]any{"postId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	Log(SeverityInfo, "Transition: post status changed", map[string]any{"postId": post.ID, "status": post.Status, "userId": user.UserID})
+
+	return utils.Ok(ctx, statusCode, "", post)
+}
+
+func (self *Handler) FindRevisions(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	limit, _ := strconv.Atoi(ctx.Query("limit", "100"))
+	offset, _ := strconv.Atoi(ctx.Query("offset", "0"))
+
+	queryOptions := spec.QueryOptions{
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	revisions, statusCode, err := self.service.FindRevisions(ctx.Context(), id, &queryOptions, user)
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", revisions)
+}
+
+func (self *Handler) DiffRevisions(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	from, err := strconv.Atoi(ctx.Query("from"))
+	if err != nil {
+		return utils.Err(ctx, 400, "Invalid from parameter", nil)
+	}
+
+	to, err := strconv.Atoi(ctx.Query("to"))
+	if err != nil {
+		return utils.Err(ctx, 400, "Invalid to parameter", nil)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	diff, statusCode, err := self.service.DiffRevisions(ctx.Context(), id, from, to, user)
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", diff)
+}
+
+func (self *Handler) Revert(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	number, err := strconv.Atoi(ctx.Params("rev"))
+	if err != nil {
+		return utils.Err(ctx, 400, "Invalid revision number", nil)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	post, statusCode, err := self.service.Revert(ctx.Context(), id, number, user)
+	if err != nil {
+		Log(SeverityWarn, "Revert: failed to revert post", map[string]any{"postId": id, "revision": number, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	Log(SeverityInfo, "Revert: post reverted", map[string]any{"postId": id, "revision": number, "userId": user.UserID})
+
+	return utils.Ok(ctx, statusCode, "", post)
+}
+
+func (self *Handler) React(ctx *fiber.Ctx) error {
+	return self.setReaction(ctx, true)
+}
+
+func (self *Handler) Unreact(ctx *fiber.Ctx) error {
+	return self.setReaction(ctx, false)
+}
+
+func (self *Handler) setReaction(ctx *fiber.Ctx, react bool) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Post ID not provided", nil)
+	}
+
+	kind := ctx.Params("kind")
+	if !slices.Contains(models.ReactionKinds, kind) {
+		return utils.Err(ctx, 400, "Unknown reaction kind", models.ReactionKinds)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	var reactions *models.PostReactionsDto
+	var statusCode int
+	if react {
+		reactions, statusCode, err = self.service.React(ctx.Context(), id, kind, user)
+	} else {
+		reactions, statusCode, err = self.service.Unreact(ctx.Context(), id, kind, user)
+	}
+	if err != nil {
+		Log(SeverityWarn, "setReaction: failed to change reaction", map[string]any{"postId": id, "kind": kind, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", reactions)
+}
+
+func (self *Handler) FeedRSS(ctx *fiber.Ctx) error {
+	return self.feed(ctx, feedFormatRSS)
+}
+
+func (self *Handler) FeedAtom(ctx *fiber.Ctx) error {
+	return self.feed(ctx, feedFormatAtom)
+}
+
+func (self *Handler) FeedJSON(ctx *fiber.Ctx) error {
+	return self.feed(ctx, feedFormatJSON)
+}
+
+func (self *Handler) feed(ctx *fiber.Ctx, format feedFormat) error {
+	authorId := ctx.Query("author", "")
+	tag := ctx.Query("tag", "")
+
+	if err := utils.ValidateVar(authorId, "omitempty,uuid"); err != nil {
+		return utils.Err(ctx, 400, "Invalid author ID", nil)
+	}
+
+	entities, err := self.service.FindFeed(ctx.Context(), authorId, tag)
+	if err != nil {
+		Log(SeverityError, "feed: failed to fetch posts", map[string]any{"format": format, "error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entities", nil)
+	}
+
+	info := newFeedInfo(ctx.BaseURL()+ctx.OriginalURL(), tag)
+	info.Updated = feedLastModified(entities)
+	if authorId != "" && len(entities) > 0 {
+		info.Title += " by " + authorName(&entities[0].User)
+	}
+
+	etag := feedETag(format, info, entities)
+	ctx.Set(fiber.HeaderETag, etag)
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	if !info.Updated.IsZero() {
+		ctx.Set(fiber.HeaderLastModified, info.Updated.UTC().Format(http.TimeFormat))
+	}
+
+	if feedNotModified(ctx, etag, info.Updated) {
+		return ctx.SendStatus(304)
+	}
+
+	body, err := renderFeed(format, info, entities)
+	if err != nil {
+		Log(SeverityError, "feed: failed to render feed", map[string]any{"format": format, "error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to render feed", nil)
+	}
+
+	ctx.Set(fiber.HeaderContentType, feedContentTypes[format])
+	return ctx.Send(body)
+}
+
+// feedNotModified evaluates the conditional request headers. If-None-Match takes
+// precedence over If-Modified-Since, as required by RFC 9110.
+func feedNotModified(ctx *fiber.Ctx, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := ctx.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if ifModifiedSince := ctx.Get(fiber.HeaderIfModifiedSince); ifModifiedSince != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		if err != nil {
+			return false
+		}
+
+		// HTTP dates have second precision.
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
 }
@@ -3,20 +3,135 @@ package posts
 import (
 	"context"
 	"errors"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/okira-e/go-as-your-backend/app/models"
 	"github.com/okira-e/go-as-your-backend/app/spec"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var _ spec.Repository[models.Post] = (*Repository)(nil)
+
+const (
+	reactionsCountSQL = "(SELECT COUNT(*) FROM post_reactions WHERE post_reactions.post_id = posts.id)"
+	likesCountSQL     = "(SELECT COUNT(*) FROM post_reactions WHERE post_reactions.post_id = posts.id AND post_reactions.kind = 'like')"
+)
+
+// virtualColumns are the filterable columns of posts that live in other tables.
+var virtualColumns = map[string]spec.VirtualColumn{
+	// reactions_count and likes_count let feeds sort by engagement, e.g. "most liked".
+	"reactions_count": {
+		Where:   compareCount(reactionsCountSQL),
+		OrderBy: reactionsCountSQL,
+	},
+	"likes_count": {
+		Where:   compareCount(likesCountSQL),
+		OrderBy: likesCountSQL,
+	},
+	// tag matches posts with a tag of the given name, or any of the given names with IN.
+	"tag": {
+		Where: func(operator string, value any) (string, []any, bool) {
+			if operator != "=" && operator != "IN" {
+				return "", nil, false
+			}
+
+			return `posts.id IN (
+				SELECT post_tags.post_id FROM post_tags
+				JOIN tags ON tags.id = post_tags.tag_id
+				WHERE tags.name IN (?)
+			)`, []any{normalizeTagNames(value)}, true
+		},
+	},
+}
+
+// normalizeTagNames normalizes the tag names of a filter value, a name or a list of names, like the
+// stored names are.
+func normalizeTagNames(value any) any {
+	switch value := value.(type) {
+	case string:
+		return models.NormalizeTagName(value)
+	case []any:
+		names := make([]any, len(value))
+		for i, name := range value {
+			names[i] = normalizeTagNames(name)
+		}
+		return names
+	case []string:
+		names := make([]string, len(value))
+		for i, name := range value {
+			names[i] = models.NormalizeTagName(name)
+		}
+		return names
+	default:
+		return value
+	}
+}
+
+// compareCount builds the condition of a virtual column holding a count.
+func compareCount(countSQL string) func(operator string, value any) (string, []any, bool) {
+	return func(operator string, value any) (string, []any, bool) {
+		switch operator {
+		case "=", ">", "<", ">=", "<=":
+			return countSQL + " " + operator + " ?", []any{value}, true
+		default:
+			return "", nil, false
+		}
+	}
+}
+
 type Repository struct {
 	db *gorm.DB
 }
 
-func NewRepository(db *gorm.DB) spec.Repository[models.Post] {
+func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// Visible returns a repository whose queries only see the posts the viewer may find in listings, counts
+// and feeds: public posts, followers-only posts of the authors the viewer follows and the viewer's own
+// posts. Admins see every post. A nil viewer is anonymous.
+func (self *Repository) Visible(viewer *models.JwtUser) *Repository {
+	return self.scoped(viewer, []models.PostVisibility{models.PostVisibilityPublic})
+}
+
+// Reachable is Visible for a post opened directly, e.g. through a link: unlisted posts are included.
+func (self *Repository) Reachable(viewer *models.JwtUser) *Repository {
+	return self.scoped(viewer, []models.PostVisibility{models.PostVisibilityPublic, models.PostVisibilityUnlisted})
+}
+
+// scoped restricts the repository to the viewer's own posts and the published posts with one of the open
+// visibilities, or followers-only published posts of the authors the viewer follows. Posts hidden by a
+// moderator are only seen by moderators and admins.
+func (self *Repository) scoped(viewer *models.JwtUser, open []models.PostVisibility) *Repository {
+	if viewer != nil && viewer.HasRole(models.RoleAdmin) {
+		return self
+	}
+
+	moderator := viewer != nil && viewer.HasRole(models.RoleModerator)
+
+	scope := func(tx *gorm.DB) *gorm.DB {
+		if !moderator {
+			tx = tx.Where("NOT posts.hidden")
+		}
+
+		if viewer == nil {
+			return tx.Where("posts.status = ? AND posts.visibility IN ?", models.PostStatusPublished, open)
+		}
+
+		return tx.Where(
+			`posts.user_id = ? OR (posts.status = ? AND (posts.visibility IN ? OR (posts.visibility = ? AND posts.user_id IN (
+				SELECT user_follows.followee_id FROM user_follows WHERE user_follows.follower_id = ?
+			))))`,
+			viewer.UserID, models.PostStatusPublished, open, models.PostVisibilityFollowers, viewer.UserID,
+		)
+	}
+
+	// The session keeps the scope on every query started from the returned repository.
+	return &Repository{db: self.db.Scopes(scope).Session(&gorm.Session{})}
+}
+
 func (self *Repository) Create(ctx context.Context, entity *models.Post) (*models.Post, error) {
 	if entity == nil {
 		return nil, errors.New("entity cannot be nil")
@@ -33,7 +148,7 @@ func (self *Repository) Create(ctx context.Context, entity *models.Post) (*model
 func (self *Repository) FindByID(ctx context.Context, id string) (*models.Post, error) {
 	var entity models.Post
 
-	err := self.db.WithContext(ctx).First(&entity, id).Error
+	err := self.db.WithContext(ctx).Preload("Tags").Preload("User").First(&entity, "id = ?", id).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("entity not found")
@@ -48,9 +163,9 @@ func (self *Repository) FindByID(ctx context.Context, id string) (*models.Post,
 func (self *Repository) FindAll(ctx context.Context, queryOptions *spec.QueryOptions, filter *spec.Filter) ([]models.Post, error) {
 	var entities []models.Post
 
-	tx := self.db.WithContext(ctx)
+	tx := self.db.WithContext(ctx).Preload("Tags").Preload("User")
 	tx = spec.ApplyPagination(tx, queryOptions)
-	tx, err := spec.ApplyFilters(tx, filter, models.Post{})
+	tx, err := spec.ApplyFiltersWith(tx, filter, models.Post{}, virtualColumns)
 	if err != nil {
 		return entities, err
 	}
@@ -67,7 +182,7 @@ func (self *Repository) Count(ctx context.Context, filter *spec.Filter) (int64,
 	var count int64
 
 	tx := self.db.WithContext(ctx)
-	tx, err := spec.ApplyFilters(tx, filter, models.Post{})
+	tx, err := spec.ApplyFiltersWith(tx, filter, models.Post{}, virtualColumns)
 	if err != nil {
 		return 0, err
 	}
@@ -84,7 +199,14 @@ func (self *Repository) Update(ctx context.Context, entity *models.Post) error {
 		return errors.New("entity cannot be nil")
 	}
 
-	result := self.db.WithContext(ctx).Model(entity).Updates(entity)
+	// Select every column so zero values such as `published = false` are written too. Columns owned
+	// by other modules, such as comments and moderation, are left alone so a concurrent change isn't
+	// overwritten.
+	result := self.db.WithContext(ctx).
+		Model(entity).
+		Select("*").
+		Omit(clause.Associations, "CommentsCount", "CommentsLocked", "Hidden").
+		Updates(entity)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -96,7 +218,7 @@ func (self *Repository) Update(ctx context.Context, entity *models.Post) error {
 }
 
 func (self *Repository) Delete(ctx context.Context, id string) error {
-	result := self.db.WithContext(ctx).Delete(&models.Post{}, id)
+	result := self.db.WithContext(ctx).Delete(&models.Post{}, "id = ?", id)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -117,3 +239,353 @@ func (self *Repository) Exists(ctx context.Context, id string) (bool, error) {
 
 	return count > 0, nil
 }
+
+// PublishScheduled publishes every scheduled post whose time has come and returns how many were published.
+func (self *Repository) PublishScheduled(ctx context.Context, now time.Time) (int64, error) {
+	result := self.db.WithContext(ctx).
+		Model(&models.Post{}).
+		Where("status = ? AND scheduled_for <= ?", models.PostStatusScheduled, now).
+		Updates(map[string]any{
+			"status":        models.PostStatusPublished,
+			"published":     true,
+			"published_at":  gorm.Expr("scheduled_for"),
+			"scheduled_for": nil,
+			"updated_at":    now,
+		})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// FindPublishedPage returns up to limit public posts, newest first, that come after the cursor.
+// Posts are ordered by publication time, with the ID breaking ties so no post is skipped or repeated.
+func (self *Repository) FindPublishedPage(ctx context.Context, after *FeedCursor, limit int) ([]models.Post, error) {
+	var entities []models.Post
+
+	tx := self.db.WithContext(ctx).
+		Preload("Tags").
+		Preload("User").
+		Where("status = ? AND NOT hidden", models.PostStatusPublished)
+
+	if after != nil {
+		tx = tx.Where("(published_at, id) < (?, ?)", after.PublishedAt, after.ID)
+	}
+
+	err := tx.Order("published_at DESC").Order("id DESC").Limit(limit).Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// Transaction runs fn with a repository bound to a single database transaction.
+func (self *Repository) Transaction(ctx context.Context, fn func(repository *Repository) error) error {
+	return self.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&Repository{db: tx})
+	})
+}
+
+func (self *Repository) FindBySlug(ctx context.Context, slug string) (*models.Post, error) {
+	var entity models.Post
+
+	err := self.db.WithContext(ctx).Preload("Tags").Preload("User").First(&entity, "slug = ?", slug).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+// FindSlugRedirect returns the post that used to be reachable under the given slug.
+func (self *Repository) FindSlugRedirect(ctx context.Context, slug string) (*models.Post, error) {
+	var entity models.Post
+
+	err := self.db.WithContext(ctx).
+		Preload("Tags").
+		Joins("JOIN post_slugs ON post_slugs.post_id = posts.id").
+		Where("post_slugs.slug = ?", slug).
+		First(&entity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+// isSlugConflict reports whether err is the violation of the unique slug of posts, which happens when
+// another post took the slug between checking it and saving.
+func isSlugConflict(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "posts_slug_key"
+}
+
+// SlugTaken reports whether a slug is used, currently or historically, by any post other than postId.
+func (self *Repository) SlugTaken(ctx context.Context, slug string, postId string) (bool, error) {
+	var count int64
+
+	err := self.db.WithContext(ctx).
+		Raw(
+			`SELECT (SELECT COUNT(*) FROM posts WHERE slug = ? AND id::text <> ?) +
+			        (SELECT COUNT(*) FROM post_slugs WHERE slug = ? AND post_id::text <> ?)`,
+			slug, postId, slug, postId,
+		).
+		Scan(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// RetireSlug keeps oldSlug around as a redirect to the post and drops newSlug from the post's
+// history, since it is about to become current again.
+func (self *Repository) RetireSlug(ctx context.Context, postId string, oldSlug string, newSlug string) error {
+	tx := self.db.WithContext(ctx)
+
+	err := tx.Where("post_id = ? AND slug = ?", postId, newSlug).Delete(&models.PostSlug{}).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.PostSlug{
+		Slug:   oldSlug,
+		PostID: postId,
+	}).Error
+}
+
+func (self *Repository) SetCommentsLocked(ctx context.Context, id string, locked bool) error {
+	result := self.db.WithContext(ctx).Model(&models.Post{}).Where("id = ?", id).Update("comments_locked", locked)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
+
+// FindTagsByName returns the existing tags among the given names.
+func (self *Repository) FindTagsByName(ctx context.Context, names []string) ([]models.Tag, error) {
+	var tags []models.Tag
+
+	if len(names) == 0 {
+		return tags, nil
+	}
+
+	err := self.db.WithContext(ctx).Where("name IN ?", names).Find(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// ReplaceTags sets the tags of a post to exactly the given ones.
+func (self *Repository) ReplaceTags(ctx context.Context, entity *models.Post, tags []models.Tag) error {
+	return self.db.WithContext(ctx).Model(entity).Association("Tags").Replace(tags)
+}
+
+// CreateRevision stores a revision under the next number of its post. Call it in the same
+// transaction as the post update, whose row lock keeps numbers from being handed out twice.
+func (self *Repository) CreateRevision(ctx context.Context, revision *models.PostRevision) error {
+	return self.db.WithContext(ctx).
+		Raw(
+			`INSERT INTO post_revisions (id, post_id, number, title, content, editor_id)
+			SELECT ?, ?, COALESCE(MAX(number), 0) + 1, ?, ?, ? FROM post_revisions WHERE post_id = ?
+			RETURNING number, created_at`,
+			revision.ID, revision.PostID, revision.Title, revision.Content, revision.EditorID, revision.PostID,
+		).
+		Scan(revision).Error
+}
+
+// FindRevisions lists the revisions of a post, newest first.
+func (self *Repository) FindRevisions(ctx context.Context, postId string, queryOptions *spec.QueryOptions) ([]models.PostRevision, error) {
+	var revisions []models.PostRevision
+
+	tx := self.db.WithContext(ctx).Where("post_id = ?", postId).Order("number DESC")
+	tx = spec.ApplyPagination(tx, queryOptions)
+
+	err := tx.Find(&revisions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (self *Repository) FindRevision(ctx context.Context, postId string, number int) (*models.PostRevision, error) {
+	var revision models.PostRevision
+
+	err := self.db.WithContext(ctx).First(&revision, "post_id = ? AND number = ?", postId, number).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// FindUnrendered returns posts whose content hasn't been rendered to HTML yet.
+func (self *Repository) FindUnrendered(ctx context.Context, limit int) ([]models.Post, error) {
+	var entities []models.Post
+
+	err := self.db.WithContext(ctx).Where("content_html IS NULL").Limit(limit).Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// SaveRendered writes the fields derived from a post's content without touching updated_at.
+func (self *Repository) SaveRendered(ctx context.Context, entity *models.Post) error {
+	return self.db.WithContext(ctx).Model(entity).UpdateColumns(map[string]any{
+		"content_html":         entity.ContentHTML,
+		"excerpt":              entity.Excerpt,
+		"reading_time_minutes": entity.ReadingTimeMinutes,
+	}).Error
+}
+
+// CountReactions returns, for each of the given posts, the number of reactions of each kind.
+func (self *Repository) CountReactions(ctx context.Context, postIds []string) (map[string]map[string]int64, error) {
+	var rows []struct {
+		PostID string
+		Kind   string
+		Count  int64
+	}
+
+	counts := map[string]map[string]int64{}
+	if len(postIds) == 0 {
+		return counts, nil
+	}
+
+	err := self.db.WithContext(ctx).
+		Model(&models.PostReaction{}).
+		Select("post_id, kind, COUNT(*) AS count").
+		Where("post_id IN ?", postIds).
+		Group("post_id, kind").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if counts[row.PostID] == nil {
+			counts[row.PostID] = map[string]int64{}
+		}
+		counts[row.PostID][row.Kind] = row.Count
+	}
+
+	return counts, nil
+}
+
+// FindUserReactions returns, for each of the given posts, the kinds the user reacted with.
+func (self *Repository) FindUserReactions(ctx context.Context, postIds []string, userId string) (map[string][]string, error) {
+	var reactions []models.PostReaction
+
+	kinds := map[string][]string{}
+	if len(postIds) == 0 {
+		return kinds, nil
+	}
+
+	err := self.db.WithContext(ctx).
+		Where("post_id IN ? AND user_id = ?", postIds, userId).
+		Order("created_at ASC").
+		Find(&reactions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reaction := range reactions {
+		kinds[reaction.PostID] = append(kinds[reaction.PostID], reaction.Kind)
+	}
+
+	return kinds, nil
+}
+
+// FindBookmarked returns which of the posts the user bookmarked, in any list.
+func (self *Repository) FindBookmarked(ctx context.Context, postIds []string, userId string) (map[string]bool, error) {
+	var bookmarkedIds []string
+
+	bookmarked := map[string]bool{}
+	if len(postIds) == 0 {
+		return bookmarked, nil
+	}
+
+	err := self.db.WithContext(ctx).
+		Model(&models.Bookmark{}).
+		Distinct("post_id").
+		Where("post_id IN ? AND user_id = ?", postIds, userId).
+		Pluck("post_id", &bookmarkedIds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, postId := range bookmarkedIds {
+		bookmarked[postId] = true
+	}
+
+	return bookmarked, nil
+}
+
+// SeriesPart is a post of a series along with the series it belongs to.
+type SeriesPart struct {
+	SeriesID    string
+	SeriesTitle string
+	Position    int
+	PostID      string
+	Title       string
+	Slug        string
+}
+
+// FindSeriesParts returns every part of the series the given posts belong to, ordered by series and
+// position. Scoped repositories leave out the parts the viewer may not read.
+func (self *Repository) FindSeriesParts(ctx context.Context, postIds []string) ([]SeriesPart, error) {
+	var parts []SeriesPart
+
+	if len(postIds) == 0 {
+		return parts, nil
+	}
+
+	err := self.db.WithContext(ctx).
+		Model(&models.Post{}).
+		Select(`series_posts.series_id, series.title AS series_title, series_posts.position,
+			posts.id AS post_id, posts.title, posts.slug`).
+		Joins("JOIN series_posts ON series_posts.post_id = posts.id").
+		Joins("JOIN series ON series.id = series_posts.series_id").
+		Where("series_posts.series_id IN (SELECT series_posts.series_id FROM series_posts WHERE series_posts.post_id IN ?)", postIds).
+		Order("series_posts.series_id ASC, series_posts.position ASC").
+		Scan(&parts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return parts, nil
+}
+
+// React stores the reaction. Reacting twice with the same kind is a no-op.
+func (self *Repository) React(ctx context.Context, reaction *models.PostReaction) error {
+	return self.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(reaction).Error
+}
+
+func (self *Repository) Unreact(ctx context.Context, postId string, userId string, kind string) error {
+	return self.db.WithContext(ctx).
+		Where("post_id = ? AND user_id = ? AND kind = ?", postId, userId, kind).
+		Delete(&models.PostReaction{}).Error
+}
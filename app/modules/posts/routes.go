@@ -5,15 +5,58 @@ import (
 	"github.com/okira-e/go-as-your-backend/app/modules/users"
 )
 
-func SetupRoutes(api fiber.Router, handler *Handler, usersService *users.Service) {
+// SetupRoutes registers the posts endpoints. trackView runs around the handlers that serve a single
+// post, to count its views.
+func SetupRoutes(api fiber.Router, handler *Handler, usersService *users.Service, trackView fiber.Handler) {
 	api = api.Group("/posts")
 
-	api.Get("/", handler.FindAll)
-	api.Get("/published", handler.GetPublished)
-	api.Get("/count", handler.GetCount)
+	api.Get("/", users.OptionalAuthMiddleware(usersService), handler.FindAll)
+	api.Get("/published", users.OptionalAuthMiddleware(usersService), handler.GetPublished)
+	api.Get("/feed", users.OptionalAuthMiddleware(usersService), handler.GetFeed)
+	api.Get("/count", users.OptionalAuthMiddleware(usersService), handler.GetCount)
+	api.Get("/feed.rss", handler.FeedRSS)
+	api.Get("/feed.atom", handler.FeedAtom)
+	api.Get("/feed.json", handler.FeedJSON)
+	api.Get("/by-slug/:slug", trackView, users.OptionalAuthMiddleware(usersService), handler.FindBySlug)
+	api.Get("/:id", trackView, users.OptionalAuthMiddleware(usersService), handler.FindOne)
 	api.Post(
 		"/",
 		users.AuthMiddleware(usersService),
 		handler.Create,
 	)
+	api.Patch(
+		"/:id",
+		users.AuthMiddleware(usersService),
+		handler.Update,
+	)
+	api.Post(
+		"/:id/transition",
+		users.AuthMiddleware(usersService),
+		handler.Transition,
+	)
+	api.Get(
+		"/:id/revisions",
+		users.AuthMiddleware(usersService),
+		handler.FindRevisions,
+	)
+	api.Get(
+		"/:id/revisions/diff",
+		users.AuthMiddleware(usersService),
+		handler.DiffRevisions,
+	)
+	api.Post(
+		"/:id/revisions/:rev/revert",
+		users.AuthMiddleware(usersService),
+		handler.Revert,
+	)
+	api.Put(
+		"/:id/reactions/:kind",
+		users.AuthMiddleware(usersService),
+		handler.React,
+	)
+	api.Delete(
+		"/:id/reactions/:kind",
+		users.AuthMiddleware(usersService),
+		handler.Unreact,
+	)
 }
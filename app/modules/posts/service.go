@@ -2,21 +2,49 @@ package posts
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
+	. "github.com/okira-e/go-as-your-backend/app/logging"
 	"github.com/okira-e/go-as-your-backend/app/models"
 	"github.com/okira-e/go-as-your-backend/app/spec"
+	"github.com/okira-e/go-as-your-backend/app/utils"
 )
 
+// maxSlugLength leaves room for a collision suffix within the 255 characters of the column.
+const maxSlugLength = 200
+
+// maxSlugAttempts is how many times saving a post is tried when other posts keep taking its slug.
+const maxSlugAttempts = 3
+
+// statusTransitions lists the statuses a post is allowed to move to from each status.
+var statusTransitions = map[models.PostStatus][]models.PostStatus{
+	models.PostStatusDraft:     {models.PostStatusInReview},
+	models.PostStatusInReview:  {models.PostStatusDraft, models.PostStatusScheduled, models.PostStatusPublished},
+	models.PostStatusScheduled: {models.PostStatusInReview, models.PostStatusPublished},
+	models.PostStatusPublished: {models.PostStatusArchived},
+	models.PostStatusArchived:  {models.PostStatusDraft},
+}
+
 type Service struct {
-	repository spec.Repository[models.Post]
+	repository *Repository
 }
 
-func NewService(repository spec.Repository[models.Post]) *Service {
+func NewService(repository *Repository) *Service {
 	return &Service{repository: repository}
 }
 
-func (self *Service) FindAll(ctx context.Context, queryOptions *spec.QueryOptions, filter *spec.Filter) ([]models.Post, error) {
-	entities, err := self.repository.FindAll(ctx, queryOptions, filter)
+// FindAll lists the posts matching the filter among those the viewer may read. viewer is nil for
+// anonymous requests.
+func (self *Service) FindAll(
+	ctx context.Context,
+	queryOptions *spec.QueryOptions,
+	filter *spec.Filter,
+	viewer *models.JwtUser,
+) ([]models.Post, error) {
+	entities, err := self.repository.Visible(viewer).FindAll(ctx, queryOptions, filter)
 	if err != nil {
 		return entities, err
 	}
@@ -24,24 +52,137 @@ func (self *Service) FindAll(ctx context.Context, queryOptions *spec.QueryOption
 	return entities, nil
 }
 
-func (self *Service) GetPublished(ctx context.Context) ([]models.Post, error) {
+// FindByID returns the post if the viewer may read it. Unlisted posts can be read by anyone.
+func (self *Service) FindByID(ctx context.Context, id string, viewer *models.JwtUser) (*models.Post, error) {
+	return self.repository.Reachable(viewer).FindByID(ctx, id)
+}
+
+// FindByIDs returns the posts with the given IDs that the viewer may read, in no particular order.
+// Like FindByID, unlisted posts are included.
+func (self *Service) FindByIDs(ctx context.Context, ids []string, viewer *models.JwtUser) ([]models.Post, error) {
+	if len(ids) == 0 {
+		return []models.Post{}, nil
+	}
+
+	filter := spec.Filter{
+		Where: spec.WhereClause{
+			And: []spec.WhereCondition{
+				{
+					Column:   "id",
+					Operator: "IN",
+					Value:    ids,
+				},
+			},
+		},
+	}
+
+	return self.repository.Reachable(viewer).FindAll(ctx, nil, &filter)
+}
+
+// GetPublished returns the limit most recently published posts the viewer may see.
+func (self *Service) GetPublished(ctx context.Context, limit int, viewer *models.JwtUser) ([]models.Post, error) {
+	return self.findPublished(ctx, limit, "", "", viewer)
+}
+
+// GetFeed returns a page of the public feed: published posts, newest first, with their authors.
+// cursor is empty for the first page and otherwise the NextCursor of the previous page.
+func (self *Service) GetFeed(
+	ctx context.Context,
+	cursor string,
+	limit int,
+	viewer *models.JwtUser,
+) (spec.Page[*models.PostDto], int, error) {
+	var after *FeedCursor
+	if cursor != "" {
+		decoded, err := decodeFeedCursor(cursor)
+		if err != nil {
+			return spec.Page[*models.PostDto]{}, 400, fmt.Errorf("Invalid cursor.")
+		}
+		after = decoded
+	}
+
+	// Fetch one extra post to know whether there is a next page.
+	entities, err := self.repository.Visible(viewer).FindPublishedPage(ctx, after, limit+1)
+	if err != nil {
+		return spec.Page[*models.PostDto]{}, 500, err
+	}
+
+	var nextCursor *string
+	if len(entities) > limit {
+		entities = entities[:limit]
+		encoded := encodeFeedCursor(&entities[len(entities)-1])
+		nextCursor = &encoded
+	}
+
+	entitiesDto, err := self.ToDtos(ctx, entities, viewer)
+	if err != nil {
+		return spec.Page[*models.PostDto]{}, 500, err
+	}
+
+	return spec.Page[*models.PostDto]{Items: entitiesDto, NextCursor: nextCursor}, 200, nil
+}
+
+// FindFeed returns the newest public posts for the syndication feeds,
+// optionally narrowed down to a single author or tag.
+func (self *Service) FindFeed(ctx context.Context, authorId string, tag string) ([]models.Post, error) {
+	return self.findPublished(ctx, feedSize, authorId, tag, nil)
+}
+
+func (self *Service) findPublished(
+	ctx context.Context,
+	limit int,
+	authorId string,
+	tag string,
+	viewer *models.JwtUser,
+) ([]models.Post, error) {
 	queryOptions := spec.QueryOptions{
-		Limit: 10,
+		Limit: limit,
 	}
 
 	filter := spec.Filter{
 		Where: spec.WhereClause{
 			And: []spec.WhereCondition{
 				{
-					Column:   "published",
+					Column:   "status",
 					Operator: "=",
-					Value:    true,
+					Value:    models.PostStatusPublished,
 				},
+				{
+					Column:   "hidden",
+					Operator: "=",
+					Value:    false,
+				},
+			},
+		},
+		OrderBy: []spec.OrderByClause{
+			{
+				Column:    "published_at",
+				Direction: "DESC",
+			},
+			{
+				Column:    "id",
+				Direction: "DESC",
 			},
 		},
 	}
 
-	entities, err := self.repository.FindAll(ctx, &queryOptions, &filter)
+	if authorId != "" {
+		filter.Where.And = append(filter.Where.And, spec.WhereCondition{
+			Column:   "user_id",
+			Operator: "=",
+			Value:    authorId,
+		})
+	}
+
+	if tag != "" {
+		filter.Where.And = append(filter.Where.And, spec.WhereCondition{
+			Column:   "tag",
+			Operator: "=",
+			Value:    models.NormalizeTagName(tag),
+		})
+	}
+
+	entities, err := self.repository.Visible(viewer).FindAll(ctx, &queryOptions, &filter)
 	if err != nil {
 		return entities, err
 	}
@@ -49,8 +190,9 @@ func (self *Service) GetPublished(ctx context.Context) ([]models.Post, error) {
 	return entities, nil
 }
 
-func (self *Service) GetCount(ctx context.Context, filter *spec.Filter) (int64, error) {
-	count, err := self.repository.Count(ctx, filter)
+// GetCount counts the posts matching the filter among those the viewer may read.
+func (self *Service) GetCount(ctx context.Context, filter *spec.Filter, viewer *models.JwtUser) (int64, error) {
+	count, err := self.repository.Visible(viewer).Count(ctx, filter)
 	if err != nil {
 		return count, err
 	}
@@ -58,13 +200,586 @@ func (self *Service) GetCount(ctx context.Context, filter *spec.Filter) (int64,
 	return count, nil
 }
 
-func (self *Service) Create(ctx context.Context, entityDto *models.CreatePostDto, userId string) (*models.PostDto, error) {
+// ToDtos converts posts to DTOs along with their reaction counts, their place in their series and, for an
+// authenticated viewer, the viewer's own reactions and bookmarks. viewer is nil for anonymous requests.
+func (self *Service) ToDtos(ctx context.Context, entities []models.Post, viewer *models.JwtUser) ([]*models.PostDto, error) {
+	postIds := make([]string, len(entities))
+	for i, entity := range entities {
+		postIds[i] = entity.ID
+	}
+
+	reactionCounts, err := self.repository.CountReactions(ctx, postIds)
+	if err != nil {
+		return nil, err
+	}
+
+	seriesParts, err := self.repository.Reachable(viewer).FindSeriesParts(ctx, postIds)
+	if err != nil {
+		return nil, err
+	}
+
+	myReactions := map[string][]string{}
+	bookmarked := map[string]bool{}
+	if viewer != nil {
+		myReactions, err = self.repository.FindUserReactions(ctx, postIds, viewer.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		bookmarked, err = self.repository.FindBookmarked(ctx, postIds, viewer.UserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	entitiesDto := make([]*models.PostDto, len(entities))
+	for i, entity := range entities {
+		entitiesDto[i] = entity.ToDto()
+
+		if counts, ok := reactionCounts[entity.ID]; ok {
+			entitiesDto[i].Reactions = counts
+		}
+		if kinds, ok := myReactions[entity.ID]; ok {
+			entitiesDto[i].MyReactions = kinds
+		}
+		entitiesDto[i].Bookmarked = bookmarked[entity.ID]
+		entitiesDto[i].Series = seriesNavigation(seriesParts, entity.ID)
+	}
+
+	return entitiesDto, nil
+}
+
+// ToDto is ToDtos for a single post.
+func (self *Service) ToDto(ctx context.Context, entity *models.Post, viewer *models.JwtUser) (*models.PostDto, error) {
+	entitiesDto, err := self.ToDtos(ctx, []models.Post{*entity}, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	return entitiesDto[0], nil
+}
+
+// FindBySlug returns the post currently using the slug if the viewer may read it. When the slug belonged
+// to a post before its title changed, the post is returned along with redirect set to true.
+func (self *Service) FindBySlug(ctx context.Context, slug string, viewer *models.JwtUser) (*models.Post, bool, error) {
+	repository := self.repository.Reachable(viewer)
+
+	entity, err := repository.FindBySlug(ctx, slug)
+	if err == nil {
+		return entity, false, nil
+	}
+
+	entity, err = repository.FindSlugRedirect(ctx, slug)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return entity, true, nil
+}
+
+func (self *Service) Create(ctx context.Context, entityDto *models.CreatePostDto, userId string) (*models.PostDto, int, error) {
 	entity := entityDto.FromDto(userId)
 
-	entity, err := self.repository.Create(ctx, entity)
+	tags, err := self.resolveTags(ctx, entityDto.Tags)
+	if err != nil {
+		return &models.PostDto{}, 400, err
+	}
+	entity.Tags = tags
+
+	slug, err := self.uniqueSlug(ctx, entity.Title, entity.ID)
+	if err != nil {
+		return &models.PostDto{}, 500, fmt.Errorf("Couldn't generate a slug for the post. %s", err)
+	}
+	entity.Slug = slug
+
+	if err := render(entity); err != nil {
+		return &models.PostDto{}, 400, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = self.repository.Transaction(ctx, func(repository *Repository) error {
+			if _, err := repository.Create(ctx, entity); err != nil {
+				return err
+			}
+
+			return repository.CreateRevision(ctx, models.NewPostRevision(entity, userId))
+		})
+		if err == nil || !isSlugConflict(err) || attempt == maxSlugAttempts {
+			break
+		}
+
+		// Another post took the slug in the meantime. It is taken now, so the next one is picked.
+		if entity.Slug, err = self.uniqueSlug(ctx, entity.Title, entity.ID); err != nil {
+			return &models.PostDto{}, 500, fmt.Errorf("Couldn't generate a slug for the post. %s", err)
+		}
+	}
+	if err != nil {
+		return &models.PostDto{}, 500, err
+	}
+
+	return entity.ToDto(), 201, nil
+}
+
+// Update edits a post. Only the author or an admin may do so. A new title gives the post a new
+// slug, and the old one keeps redirecting to it. Changing the title or content stores a revision.
+func (self *Service) Update(
+	ctx context.Context,
+	id string,
+	entityDto *models.UpdatePostDto,
+	user models.JwtUser,
+) (*models.PostDto, int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil {
+		return &models.PostDto{}, 404, fmt.Errorf("Post with ID %s not found.", id)
+	}
+
+	if !canManage(entity, user) {
+		return &models.PostDto{}, 403, fmt.Errorf("Only the author can edit this post.")
+	}
+
+	return self.update(ctx, entity, entityDto, user, false)
+}
+
+// update applies the changes to a post the user may manage. A revision is stored when the title or
+// content changed, or always with forceRevision.
+func (self *Service) update(
+	ctx context.Context,
+	entity *models.Post,
+	entityDto *models.UpdatePostDto,
+	user models.JwtUser,
+	forceRevision bool,
+) (*models.PostDto, int, error) {
+	var err error
+
+	oldSlug := entity.Slug
+	oldTitle := entity.Title
+	oldContent := entity.Content
+
+	if entityDto.Title != nil && *entityDto.Title != entity.Title {
+		entity.Title = *entityDto.Title
+
+		entity.Slug, err = self.uniqueSlug(ctx, entity.Title, entity.ID)
+		if err != nil {
+			return &models.PostDto{}, 500, fmt.Errorf("Couldn't generate a slug for the post. %s", err)
+		}
+	}
+
+	if entityDto.Content != nil && *entityDto.Content != entity.Content {
+		entity.Content = *entityDto.Content
+
+		if err := render(entity); err != nil {
+			return &models.PostDto{}, 400, err
+		}
+	}
+
+	if entityDto.Tags != nil {
+		entity.Tags, err = self.resolveTags(ctx, *entityDto.Tags)
+		if err != nil {
+			return &models.PostDto{}, 400, err
+		}
+	}
+
+	if entityDto.Visibility != nil {
+		entity.Visibility = *entityDto.Visibility
+	}
+
+	now := time.Now().UTC()
+	entity.UpdatedAt = &now
+
+	for attempt := 1; ; attempt++ {
+		err = self.repository.Transaction(ctx, func(repository *Repository) error {
+			if err := repository.Update(ctx, entity); err != nil {
+				return err
+			}
+
+			if entityDto.Tags != nil {
+				if err := repository.ReplaceTags(ctx, entity, entity.Tags); err != nil {
+					return err
+				}
+			}
+
+			if forceRevision || entity.Title != oldTitle || entity.Content != oldContent {
+				if err := repository.CreateRevision(ctx, models.NewPostRevision(entity, user.UserID)); err != nil {
+					return err
+				}
+			}
+
+			if entity.Slug != oldSlug {
+				return repository.RetireSlug(ctx, entity.ID, oldSlug, entity.Slug)
+			}
+
+			return nil
+		})
+		if err == nil || !isSlugConflict(err) || attempt == maxSlugAttempts {
+			break
+		}
+
+		// Another post took the slug in the meantime. It is taken now, so the next one is picked.
+		if entity.Slug, err = self.uniqueSlug(ctx, entity.Title, entity.ID); err != nil {
+			return &models.PostDto{}, 500, fmt.Errorf("Couldn't generate a slug for the post. %s", err)
+		}
+	}
 	if err != nil {
-		return &models.PostDto{}, err
+		return &models.PostDto{}, 500, err
 	}
 
-	return entity.ToDto(), nil
+	return entity.ToDto(), 200, nil
+}
+
+// resolveTags looks up the tags with the given names. Tags are managed by privileged roles, so
+// naming a tag that doesn't exist is an error.
+func (self *Service) resolveTags(ctx context.Context, names []string) ([]models.Tag, error) {
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name = models.NormalizeTagName(name)
+		if !slices.Contains(normalized, name) {
+			normalized = append(normalized, name)
+		}
+	}
+
+	tags, err := self.repository.FindTagsByName(ctx, normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tags) != len(normalized) {
+		var unknown []string
+		for _, name := range normalized {
+			if !slices.ContainsFunc(tags, func(tag models.Tag) bool { return tag.Name == name }) {
+				unknown = append(unknown, name)
+			}
+		}
+
+		return nil, fmt.Errorf("Unknown tags: %s.", strings.Join(unknown, ", "))
+	}
+
+	return tags, nil
+}
+
+// uniqueSlug derives a slug from the title that no other post uses or used to use, appending
+// "-2", "-3" and so on when needed.
+func (self *Service) uniqueSlug(ctx context.Context, title string, postId string) (string, error) {
+	base := utils.Slugify(title)
+	if len(base) > maxSlugLength {
+		base = strings.TrimRight(base[:maxSlugLength], "-")
+	}
+	if base == "" {
+		base = "post"
+	}
+
+	for i := 1; i <= 100; i++ {
+		slug := base
+		if i > 1 {
+			slug = fmt.Sprintf("%s-%d", base, i)
+		}
+
+		taken, err := self.repository.SlugTaken(ctx, slug, postId)
+		if err != nil {
+			return "", err
+		}
+
+		if !taken {
+			return slug, nil
+		}
+	}
+
+	// Very common titles fall back to a suffix taken from the post's ID, which is unique.
+	return base + "-" + postId[len(postId)-12:], nil
+}
+
+// Transition moves a post to another editorial status. Only the author or an admin may do so.
+func (self *Service) Transition(
+	ctx context.Context,
+	id string,
+	entityDto *models.TransitionPostDto,
+	user models.JwtUser,
+) (*models.PostDto, int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil {
+		return &models.PostDto{}, 404, fmt.Errorf("Post with ID %s not found.", id)
+	}
+
+	if !canManage(entity, user) {
+		return &models.PostDto{}, 403, fmt.Errorf("Only the author can change the status of this post.")
+	}
+
+	if !slices.Contains(statusTransitions[entity.Status], entityDto.Status) {
+		return &models.PostDto{}, 409, fmt.Errorf("A post can't move from %s to %s.", entity.Status, entityDto.Status)
+	}
+
+	now := time.Now().UTC()
+
+	entity.ScheduledFor = nil
+	switch entityDto.Status {
+	case models.PostStatusScheduled:
+		if entityDto.ScheduledFor == nil || !entityDto.ScheduledFor.After(now) {
+			return &models.PostDto{}, 400, fmt.Errorf("Scheduling a post requires a future scheduled_for.")
+		}
+		scheduledFor := entityDto.ScheduledFor.UTC()
+		entity.ScheduledFor = &scheduledFor
+	case models.PostStatusPublished:
+		entity.PublishedAt = &now
+	}
+
+	entity.Status = entityDto.Status
+	entity.Published = entityDto.Status == models.PostStatusPublished
+	entity.UpdatedAt = &now
+
+	if err := self.repository.Update(ctx, entity); err != nil {
+		return &models.PostDto{}, 500, err
+	}
+
+	return entity.ToDto(), 200, nil
+}
+
+// FindRevisions lists the revisions of a post, newest first. Only the author or an admin may see them.
+func (self *Service) FindRevisions(
+	ctx context.Context,
+	id string,
+	queryOptions *spec.QueryOptions,
+	user models.JwtUser,
+) ([]*models.PostRevisionDto, int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil {
+		return nil, 404, fmt.Errorf("Post with ID %s not found.", id)
+	}
+
+	if !canManage(entity, user) {
+		return nil, 403, fmt.Errorf("Only the author can see the revisions of this post.")
+	}
+
+	revisions, err := self.repository.FindRevisions(ctx, id, queryOptions)
+	if err != nil {
+		return nil, 500, err
+	}
+
+	revisionsDto := make([]*models.PostRevisionDto, len(revisions))
+	for i, revision := range revisions {
+		revisionsDto[i] = revision.ToDto()
+	}
+
+	return revisionsDto, 200, nil
+}
+
+// DiffRevisions returns a unified diff going from one revision of a post to another.
+func (self *Service) DiffRevisions(
+	ctx context.Context,
+	id string,
+	from int,
+	to int,
+	user models.JwtUser,
+) (*models.PostRevisionDiffDto, int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil {
+		return &models.PostRevisionDiffDto{}, 404, fmt.Errorf("Post with ID %s not found.", id)
+	}
+
+	if !canManage(entity, user) {
+		return &models.PostRevisionDiffDto{}, 403, fmt.Errorf("Only the author can see the revisions of this post.")
+	}
+
+	fromRevision, err := self.repository.FindRevision(ctx, id, from)
+	if err != nil {
+		return &models.PostRevisionDiffDto{}, 404, fmt.Errorf("Revision %d of post %s not found.", from, id)
+	}
+
+	toRevision, err := self.repository.FindRevision(ctx, id, to)
+	if err != nil {
+		return &models.PostRevisionDiffDto{}, 404, fmt.Errorf("Revision %d of post %s not found.", to, id)
+	}
+
+	diff := utils.UnifiedDiff(
+		fmt.Sprintf("revision %d", from),
+		fmt.Sprintf("revision %d", to),
+		revisionText(fromRevision),
+		revisionText(toRevision),
+	)
+
+	return &models.PostRevisionDiffDto{From: from, To: to, Diff: diff}, 200, nil
+}
+
+// Revert restores the title and content of an earlier revision. History is never rewritten, the
+// restored state is stored as a new revision.
+func (self *Service) Revert(ctx context.Context, id string, number int, user models.JwtUser) (*models.PostDto, int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil {
+		return &models.PostDto{}, 404, fmt.Errorf("Post with ID %s not found.", id)
+	}
+
+	if !canManage(entity, user) {
+		return &models.PostDto{}, 403, fmt.Errorf("Only the author can edit this post.")
+	}
+
+	revision, err := self.repository.FindRevision(ctx, id, number)
+	if err != nil {
+		return &models.PostDto{}, 404, fmt.Errorf("Revision %d of post %s not found.", number, id)
+	}
+
+	entityDto := models.UpdatePostDto{
+		Title:   &revision.Title,
+		Content: &revision.Content,
+	}
+
+	// A revert is recorded even when the post already matches the revision.
+	return self.update(ctx, entity, &entityDto, user, true)
+}
+
+// React adds the user's reaction of the given kind to a published post the user may read.
+func (self *Service) React(ctx context.Context, id string, kind string, user models.JwtUser) (*models.PostReactionsDto, int, error) {
+	entity, err := self.repository.Reachable(&user).FindByID(ctx, id)
+	if err != nil || !entity.IsPublic() {
+		return &models.PostReactionsDto{}, 404, fmt.Errorf("Post with ID %s not found.", id)
+	}
+
+	reaction := models.PostReaction{
+		PostID: id,
+		UserID: user.UserID,
+		Kind:   kind,
+	}
+
+	if err := self.repository.React(ctx, &reaction); err != nil {
+		return &models.PostReactionsDto{}, 500, err
+	}
+
+	return self.reactions(ctx, entity, user)
+}
+
+// Unreact removes the user's reaction of the given kind from a post the user may read. Unlike reacting,
+// it doesn't require the post to be public.
+func (self *Service) Unreact(ctx context.Context, id string, kind string, user models.JwtUser) (*models.PostReactionsDto, int, error) {
+	entity, err := self.repository.Reachable(&user).FindByID(ctx, id)
+	if err != nil {
+		return &models.PostReactionsDto{}, 404, fmt.Errorf("Post with ID %s not found.", id)
+	}
+
+	if err := self.repository.Unreact(ctx, id, user.UserID, kind); err != nil {
+		return &models.PostReactionsDto{}, 500, err
+	}
+
+	return self.reactions(ctx, entity, user)
+}
+
+func (self *Service) reactions(ctx context.Context, entity *models.Post, user models.JwtUser) (*models.PostReactionsDto, int, error) {
+	entityDto, err := self.ToDto(ctx, entity, &user)
+	if err != nil {
+		return &models.PostReactionsDto{}, 500, err
+	}
+
+	return &models.PostReactionsDto{Counts: entityDto.Reactions, Mine: entityDto.MyReactions}, 200, nil
+}
+
+// RenderMissing renders the content of posts written before content was rendered on write.
+func (self *Service) RenderMissing(ctx context.Context) (int, error) {
+	rendered := 0
+
+	for {
+		entities, err := self.repository.FindUnrendered(ctx, 100)
+		if err != nil {
+			return rendered, err
+		}
+
+		if len(entities) == 0 {
+			return rendered, nil
+		}
+
+		for i := range entities {
+			if err := render(&entities[i]); err != nil {
+				return rendered, err
+			}
+
+			if err := self.repository.SaveRendered(ctx, &entities[i]); err != nil {
+				return rendered, err
+			}
+
+			rendered++
+		}
+	}
+}
+
+// PublishScheduled publishes the scheduled posts that are due.
+func (self *Service) PublishScheduled(ctx context.Context) (int64, error) {
+	return self.repository.PublishScheduled(ctx, time.Now().UTC())
+}
+
+// RunScheduler publishes due scheduled posts every interval. It blocks, so run it in its own goroutine.
+func (self *Service) RunScheduler(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		count, err := self.PublishScheduled(context.Background())
+		if err != nil {
+			Log(SeverityError, "RunScheduler: failed to publish scheduled posts", map[string]any{"error": err.Error()})
+			continue
+		}
+
+		if count > 0 {
+			Log(SeverityInfo, "RunScheduler: published scheduled posts", map[string]any{"count": count})
+		}
+	}
+}
+
+// render fills the fields derived from the post's Markdown content.
+func render(post *models.Post) error {
+	rendered, err := renderContent(post.Content)
+	if err != nil {
+		return fmt.Errorf("Couldn't render the post content. %s", err)
+	}
+
+	post.ContentHTML = &rendered.HTML
+	post.Excerpt = rendered.Excerpt
+	post.ReadingTimeMinutes = rendered.ReadingTimeMinutes
+
+	return nil
+}
+
+// canManage reports whether the user may edit and manage the post.
+func canManage(post *models.Post, user models.JwtUser) bool {
+	return post.UserID == user.UserID || user.HasRole(models.RoleAdmin)
+}
+
+// seriesNavigation places a post among the parts of its series, which are ordered by series and position.
+// It returns nil when the post isn't part of a series.
+func seriesNavigation(parts []SeriesPart, postId string) *models.PostSeriesDto {
+	index := slices.IndexFunc(parts, func(part SeriesPart) bool { return part.PostID == postId })
+	if index < 0 {
+		return nil
+	}
+
+	seriesId := parts[index].SeriesID
+	first := slices.IndexFunc(parts, func(part SeriesPart) bool { return part.SeriesID == seriesId })
+	last := first
+	for last < len(parts) && parts[last].SeriesID == seriesId {
+		last++
+	}
+
+	navigation := &models.PostSeriesDto{
+		ID:       seriesId,
+		Title:    parts[index].SeriesTitle,
+		Position: index - first + 1,
+		Total:    last - first,
+	}
+
+	if index > first {
+		navigation.Previous = seriesPartDto(parts[index-1], index-first)
+	}
+	if index+1 < last {
+		navigation.Next = seriesPartDto(parts[index+1], index-first+2)
+	}
+
+	return navigation
+}
+
+func seriesPartDto(part SeriesPart, position int) *models.SeriesPartDto {
+	return &models.SeriesPartDto{
+		Position: position,
+		PostID:   part.PostID,
+		Title:    part.Title,
+		Slug:     part.Slug,
+	}
+}
+
+// revisionText is what revisions are diffed on: the title as a heading followed by the content.
+func revisionText(revision *models.PostRevision) string {
+	return "# " + revision.Title + "\n\n" + revision.Content
 }
@@ -0,0 +1,179 @@
+package series
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(Service *Service) *Handler {
+	return &Handler{service: Service}
+}
+
+func (self *Handler) FindAll(ctx *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(ctx.Query("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(ctx.Query("offset", "0"))
+
+	queryOptions := spec.QueryOptions{
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	filter, err := spec.ParseFilter(ctx.Query("filter", ""))
+	if err != nil {
+		return utils.Err(ctx, 400, "Invalid filter parameter", err)
+	}
+
+	entities, err := self.service.FindAll(ctx.Context(), &queryOptions, filter)
+	if err != nil {
+		Log(SeverityError, "FindAll: failed to fetch series", map[string]any{"error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entities", nil)
+	}
+
+	return utils.Ok(ctx, 200, "", entities)
+}
+
+func (self *Handler) GetCount(ctx *fiber.Ctx) error {
+	filter, err := spec.ParseFilter(ctx.Query("filter", ""))
+	if err != nil {
+		return utils.Err(ctx, 400, "Invalid filter parameter", err)
+	}
+
+	count, err := self.service.GetCount(ctx.Context(), filter)
+	if err != nil {
+		Log(SeverityError, "GetCount: failed to fetch count", map[string]any{"error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entities count", nil)
+	}
+
+	return utils.Ok(ctx, 200, "", count)
+}
+
+func (self *Handler) FindOne(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Series ID not provided", nil)
+	}
+
+	entity, statusCode, err := self.service.FindByID(ctx.Context(), id, utils.GetOptionalUserFromContext(ctx))
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", entity)
+}
+
+func (self *Handler) Create(ctx *fiber.Ctx) error {
+	entityDto := models.CreateSeriesDto{}
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	entity, statusCode, err := self.service.Create(ctx.Context(), &entityDto, user)
+	if err != nil {
+		Log(SeverityWarn, "Create: failed to create series", map[string]any{"userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", entity)
+}
+
+func (self *Handler) Update(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Series ID not provided", nil)
+	}
+
+	entityDto := models.UpdateSeriesDto{}
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	entity, statusCode, err := self.service.Update(ctx.Context(), id, &entityDto, user)
+	if err != nil {
+		Log(SeverityWarn, "Update: failed to update series", map[string]any{"seriesId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", entity)
+}
+
+func (self *Handler) SetPosts(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Series ID not provided", nil)
+	}
+
+	entityDto := models.SetSeriesPostsDto{}
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	entity, statusCode, err := self.service.SetPosts(ctx.Context(), id, &entityDto, user)
+	if err != nil {
+		Log(SeverityWarn, "SetPosts: failed to change series parts", map[string]any{"seriesId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", entity)
+}
+
+func (self *Handler) Delete(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Series ID not provided", nil)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	statusCode, err := self.service.Delete(ctx.Context(), id, user)
+	if err != nil {
+		Log(SeverityWarn, "Delete: failed to delete series", map[string]any{"seriesId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "Series deleted", nil)
+}
@@ -0,0 +1,202 @@
+package series
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Transaction runs fn with a repository bound to a single database transaction.
+func (self *Repository) Transaction(ctx context.Context, fn func(repository *Repository) error) error {
+	return self.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&Repository{db: tx})
+	})
+}
+
+func (self *Repository) Create(ctx context.Context, entity *models.Series) (*models.Series, error) {
+	if entity == nil {
+		return nil, errors.New("entity cannot be nil")
+	}
+
+	if err := self.db.WithContext(ctx).Create(entity).Error; err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+func (self *Repository) FindByID(ctx context.Context, id string) (*models.Series, error) {
+	var entity models.Series
+
+	err := self.db.WithContext(ctx).First(&entity, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+// LockByID returns the series and locks its row until the end of the transaction, so changes to its
+// parts are applied one at a time.
+func (self *Repository) LockByID(ctx context.Context, id string) (*models.Series, error) {
+	var entity models.Series
+
+	err := self.db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&entity, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+func (self *Repository) FindAll(ctx context.Context, queryOptions *spec.QueryOptions, filter *spec.Filter) ([]models.Series, error) {
+	var entities []models.Series
+
+	tx := self.db.WithContext(ctx)
+	tx = spec.ApplyPagination(tx, queryOptions)
+	tx, err := spec.ApplyFilters(tx, filter, models.Series{})
+	if err != nil {
+		return entities, err
+	}
+
+	err = tx.Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+func (self *Repository) Count(ctx context.Context, filter *spec.Filter) (int64, error) {
+	var count int64
+
+	tx := self.db.WithContext(ctx)
+	tx, err := spec.ApplyFilters(tx, filter, models.Series{})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Model(&models.Series{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (self *Repository) Update(ctx context.Context, entity *models.Series) error {
+	if entity == nil {
+		return errors.New("entity cannot be nil")
+	}
+
+	result := self.db.WithContext(ctx).Model(entity).Select("*").Updates(entity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
+
+// Delete removes a series. Its posts are left untouched.
+func (self *Repository) Delete(ctx context.Context, id string) error {
+	result := self.db.WithContext(ctx).Delete(&models.Series{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
+
+// FindPostIDs returns the IDs of the posts of a series, in order.
+func (self *Repository) FindPostIDs(ctx context.Context, seriesId string) ([]string, error) {
+	var postIds []string
+
+	err := self.db.WithContext(ctx).
+		Model(&models.SeriesPost{}).
+		Where("series_id = ?", seriesId).
+		Order("position ASC").
+		Pluck("post_id", &postIds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return postIds, nil
+}
+
+// FindSeriesOfPosts returns the series each of the given posts belongs to, for those in a series.
+func (self *Repository) FindSeriesOfPosts(ctx context.Context, postIds []string) (map[string]string, error) {
+	var rows []models.SeriesPost
+
+	seriesIds := map[string]string{}
+	if len(postIds) == 0 {
+		return seriesIds, nil
+	}
+
+	err := self.db.WithContext(ctx).Where("post_id IN ?", postIds).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		seriesIds[row.PostID] = row.SeriesID
+	}
+
+	return seriesIds, nil
+}
+
+// ReplacePosts makes the given posts, in order, the parts of the series. A post can only be part of one
+// series, so it fails with errPostInAnotherSeries when another series has one of them.
+func (self *Repository) ReplacePosts(ctx context.Context, seriesId string, postIds []string) error {
+	err := self.db.WithContext(ctx).Where("series_id = ?", seriesId).Delete(&models.SeriesPost{}).Error
+	if err != nil {
+		return err
+	}
+
+	if len(postIds) == 0 {
+		return nil
+	}
+
+	rows := make([]models.SeriesPost, len(postIds))
+	for i, postId := range postIds {
+		rows[i] = models.SeriesPost{
+			SeriesID: seriesId,
+			PostID:   postId,
+			Position: i + 1,
+		}
+	}
+
+	err = self.db.WithContext(ctx).Create(&rows).Error
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "series_posts_post_id_key" {
+		return errPostInAnotherSeries
+	}
+
+	return err
+}
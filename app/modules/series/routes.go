@@ -0,0 +1,34 @@
+package series
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/okira-e/go-as-your-backend/app/modules/users"
+)
+
+func SetupRoutes(api fiber.Router, handler *Handler, usersService *users.Service) {
+	api = api.Group("/series")
+
+	api.Get("/", handler.FindAll)
+	api.Get("/count", handler.GetCount)
+	api.Get("/:id", users.OptionalAuthMiddleware(usersService), handler.FindOne)
+	api.Post(
+		"/",
+		users.AuthMiddleware(usersService),
+		handler.Create,
+	)
+	api.Patch(
+		"/:id",
+		users.AuthMiddleware(usersService),
+		handler.Update,
+	)
+	api.Put(
+		"/:id/posts",
+		users.AuthMiddleware(usersService),
+		handler.SetPosts,
+	)
+	api.Delete(
+		"/:id",
+		users.AuthMiddleware(usersService),
+		handler.Delete,
+	)
+}
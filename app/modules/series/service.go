@@ -0,0 +1,323 @@
+package series
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/modules/posts"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+)
+
+// errStaleVersion is returned from a transaction when the series changed since the version a request
+// was based on.
+var errStaleVersion = fmt.Errorf("The series was changed in the meantime. Reload it and try again.")
+
+// errPostInAnotherSeries is returned when another series claimed one of the posts after they were checked.
+var errPostInAnotherSeries = fmt.Errorf("A post is already part of another series.")
+
+type Service struct {
+	repository      *Repository
+	postsRepository *posts.Repository
+}
+
+func NewService(repository *Repository, postsRepository *posts.Repository) *Service {
+	return &Service{repository: repository, postsRepository: postsRepository}
+}
+
+// FindAll lists series matching the filter, newest first unless the filter orders them. Parts are
+// only listed by FindByID.
+func (self *Service) FindAll(
+	ctx context.Context,
+	queryOptions *spec.QueryOptions,
+	filter *spec.Filter,
+) ([]*models.SeriesDto, error) {
+	if filter == nil {
+		filter = &spec.Filter{}
+	}
+	if len(filter.OrderBy) == 0 {
+		filter.OrderBy = []spec.OrderByClause{{Column: "id", Direction: "DESC"}}
+	}
+
+	entities, err := self.repository.FindAll(ctx, queryOptions, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	entitiesDto := make([]*models.SeriesDto, len(entities))
+	for i, entity := range entities {
+		entitiesDto[i] = entity.ToDto()
+	}
+
+	return entitiesDto, nil
+}
+
+func (self *Service) GetCount(ctx context.Context, filter *spec.Filter) (int64, error) {
+	count, err := self.repository.Count(ctx, filter)
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// FindByID returns a series with the parts the viewer may read. Series without any such part are only
+// shown to their author and admins.
+func (self *Service) FindByID(ctx context.Context, id string, viewer *models.JwtUser) (*models.SeriesDto, int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil {
+		return &models.SeriesDto{}, 404, fmt.Errorf("Series with ID %s not found.", id)
+	}
+
+	entityDto, err := self.toDto(ctx, entity, viewer)
+	if err != nil {
+		return &models.SeriesDto{}, 500, err
+	}
+
+	if len(entityDto.Parts) == 0 && (viewer == nil || !canManage(entity, *viewer)) {
+		return &models.SeriesDto{}, 404, fmt.Errorf("Series with ID %s not found.", id)
+	}
+
+	return entityDto, 200, nil
+}
+
+// Create starts a series made of the user's posts, in the given order.
+func (self *Service) Create(ctx context.Context, entityDto *models.CreateSeriesDto, user models.JwtUser) (*models.SeriesDto, int, error) {
+	entity := entityDto.FromDto(user.UserID)
+
+	if statusCode, err := self.validatePosts(ctx, entityDto.PostIDs, entity); err != nil {
+		return &models.SeriesDto{}, statusCode, err
+	}
+
+	err := self.repository.Transaction(ctx, func(repository *Repository) error {
+		if _, err := repository.Create(ctx, entity); err != nil {
+			return err
+		}
+
+		return repository.ReplacePosts(ctx, entity.ID, entityDto.PostIDs)
+	})
+	if errors.Is(err, errPostInAnotherSeries) {
+		return &models.SeriesDto{}, 409, err
+	}
+	if err != nil {
+		return &models.SeriesDto{}, 500, err
+	}
+
+	return self.respond(ctx, entity, user, 201)
+}
+
+// Update edits the title or description of a series. Only its author or an admin may do so.
+func (self *Service) Update(
+	ctx context.Context,
+	id string,
+	entityDto *models.UpdateSeriesDto,
+	user models.JwtUser,
+) (*models.SeriesDto, int, error) {
+	var entity *models.Series
+	statusCode := 500
+
+	err := self.repository.Transaction(ctx, func(repository *Repository) error {
+		var err error
+
+		entity, err = repository.LockByID(ctx, id)
+		if err != nil {
+			statusCode = 404
+			return fmt.Errorf("Series with ID %s not found.", id)
+		}
+
+		if !canManage(entity, user) {
+			statusCode = 403
+			return fmt.Errorf("Only the author can edit this series.")
+		}
+
+		if entityDto.Title != nil {
+			entity.Title = *entityDto.Title
+		}
+		if entityDto.Description != nil {
+			entity.Description = entityDto.Description
+		}
+
+		now := time.Now().UTC()
+		entity.UpdatedAt = &now
+
+		return repository.Update(ctx, entity)
+	})
+	if err != nil {
+		return &models.SeriesDto{}, statusCode, err
+	}
+
+	return self.respond(ctx, entity, user, 200)
+}
+
+// SetPosts replaces the parts of a series with the given posts, in order, which reorders, adds or
+// removes parts. Changes are serialized on the series row, and a request based on an older version of
+// the series is rejected so concurrent reorders can't silently undo each other.
+func (self *Service) SetPosts(
+	ctx context.Context,
+	id string,
+	entityDto *models.SetSeriesPostsDto,
+	user models.JwtUser,
+) (*models.SeriesDto, int, error) {
+	var entity *models.Series
+	statusCode := 500
+
+	err := self.repository.Transaction(ctx, func(repository *Repository) error {
+		var err error
+
+		entity, err = repository.LockByID(ctx, id)
+		if err != nil {
+			statusCode = 404
+			return fmt.Errorf("Series with ID %s not found.", id)
+		}
+
+		if !canManage(entity, user) {
+			statusCode = 403
+			return fmt.Errorf("Only the author can change the parts of this series.")
+		}
+
+		if entity.Version != entityDto.Version {
+			statusCode = 409
+			return errStaleVersion
+		}
+
+		if code, err := self.validatePosts(ctx, entityDto.PostIDs, entity); err != nil {
+			statusCode = code
+			return err
+		}
+
+		if err := repository.ReplacePosts(ctx, entity.ID, entityDto.PostIDs); err != nil {
+			if errors.Is(err, errPostInAnotherSeries) {
+				statusCode = 409
+			}
+			return err
+		}
+
+		now := time.Now().UTC()
+		entity.Version++
+		entity.UpdatedAt = &now
+
+		return repository.Update(ctx, entity)
+	})
+	if err != nil {
+		return &models.SeriesDto{}, statusCode, err
+	}
+
+	return self.respond(ctx, entity, user, 200)
+}
+
+// Delete removes a series. Its posts are kept.
+func (self *Service) Delete(ctx context.Context, id string, user models.JwtUser) (int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil {
+		return 404, fmt.Errorf("Series with ID %s not found.", id)
+	}
+
+	if !canManage(entity, user) {
+		return 403, fmt.Errorf("Only the author can delete this series.")
+	}
+
+	if err := self.repository.Delete(ctx, id); err != nil {
+		return 500, err
+	}
+
+	return 200, nil
+}
+
+// validatePosts checks that the posts exist, belong to the author of the series and aren't part of
+// another series.
+func (self *Service) validatePosts(ctx context.Context, postIds []string, entity *models.Series) (int, error) {
+	if len(postIds) == 0 {
+		return 200, nil
+	}
+
+	postEntities, err := self.findPosts(ctx, self.postsRepository, postIds)
+	if err != nil {
+		return 500, err
+	}
+
+	for _, postId := range postIds {
+		index := slices.IndexFunc(postEntities, func(post models.Post) bool { return post.ID == postId })
+		if index < 0 || postEntities[index].UserID != entity.UserID {
+			return 400, fmt.Errorf("Post with ID %s not found among the author's posts.", postId)
+		}
+	}
+
+	seriesIds, err := self.repository.FindSeriesOfPosts(ctx, postIds)
+	if err != nil {
+		return 500, err
+	}
+
+	for _, postId := range postIds {
+		if seriesId, ok := seriesIds[postId]; ok && seriesId != entity.ID {
+			return 409, fmt.Errorf("Post with ID %s is already part of another series.", postId)
+		}
+	}
+
+	return 200, nil
+}
+
+func (self *Service) findPosts(ctx context.Context, repository *posts.Repository, postIds []string) ([]models.Post, error) {
+	filter := spec.Filter{
+		Where: spec.WhereClause{
+			And: []spec.WhereCondition{
+				{
+					Column:   "id",
+					Operator: "IN",
+					Value:    postIds,
+				},
+			},
+		},
+	}
+
+	return repository.FindAll(ctx, nil, &filter)
+}
+
+func (self *Service) respond(ctx context.Context, entity *models.Series, user models.JwtUser, statusCode int) (*models.SeriesDto, int, error) {
+	entityDto, err := self.toDto(ctx, entity, &user)
+	if err != nil {
+		return &models.SeriesDto{}, 500, err
+	}
+
+	return entityDto, statusCode, nil
+}
+
+// toDto converts a series to a DTO along with the parts the viewer may read, numbered in order.
+func (self *Service) toDto(ctx context.Context, entity *models.Series, viewer *models.JwtUser) (*models.SeriesDto, error) {
+	entityDto := entity.ToDto()
+
+	postIds, err := self.repository.FindPostIDs(ctx, entity.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(postIds) == 0 {
+		return entityDto, nil
+	}
+
+	postEntities, err := self.findPosts(ctx, self.postsRepository.Reachable(viewer), postIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, postId := range postIds {
+		index := slices.IndexFunc(postEntities, func(post models.Post) bool { return post.ID == postId })
+		if index < 0 {
+			continue
+		}
+
+		entityDto.Parts = append(entityDto.Parts, models.SeriesPartDto{
+			Position: len(entityDto.Parts) + 1,
+			PostID:   postId,
+			Title:    postEntities[index].Title,
+			Slug:     postEntities[index].Slug,
+		})
+	}
+
+	return entityDto, nil
+}
+
+func canManage(entity *models.Series, user models.JwtUser) bool {
+	return entity.UserID == user.UserID || user.HasRole(models.RoleAdmin)
+}
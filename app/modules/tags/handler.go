@@ -0,0 +1,115 @@
+package tags
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(Service *Service) *Handler {
+	return &Handler{service: Service}
+}
+
+func (self *Handler) FindAll(ctx *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(ctx.Query("limit", "100"))
+	offset, _ := strconv.Atoi(ctx.Query("offset", "0"))
+
+	queryOptions := spec.QueryOptions{
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	entities, err := self.service.FindAllWithUsage(ctx.Context(), &queryOptions)
+	if err != nil {
+		Log(SeverityError, "FindAll: failed to fetch tags", map[string]any{"error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entities", nil)
+	}
+
+	return utils.Ok(ctx, 200, "", entities)
+}
+
+func (self *Handler) GetCount(ctx *fiber.Ctx) error {
+	filter, err := spec.ParseFilter(ctx.Query("filter", ""))
+	if err != nil {
+		return utils.Err(ctx, 400, "Invalid filter parameter", err)
+	}
+
+	count, err := self.service.GetCount(ctx.Context(), filter)
+	if err != nil {
+		Log(SeverityError, "GetCount: failed to fetch count", map[string]any{"error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch entities count", nil)
+	}
+
+	return utils.Ok(ctx, 200, "", count)
+}
+
+func (self *Handler) Create(ctx *fiber.Ctx) error {
+	var entityDto models.CreateTagDto
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	tag, statusCode, err := self.service.Create(ctx.Context(), &entityDto)
+	if err != nil {
+		Log(SeverityWarn, "Create: failed to create tag", map[string]any{"name": entityDto.Name, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	Log(SeverityInfo, "Create: tag created", map[string]any{"tagId": tag.ID, "name": tag.Name})
+
+	return utils.Ok(ctx, statusCode, "", tag)
+}
+
+func (self *Handler) Update(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Tag ID not provided", nil)
+	}
+
+	var entityDto models.UpdateTagDto
+
+	if err := ctx.BodyParser(&entityDto); err != nil {
+		return utils.Err(ctx, 400, "Invalid request body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(entityDto); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	tag, statusCode, err := self.service.Update(ctx.Context(), id, &entityDto)
+	if err != nil {
+		Log(SeverityWarn, "Update: failed to update tag", map[string]any{"tagId": id, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", tag)
+}
+
+func (self *Handler) Delete(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if id == "" {
+		return utils.Err(ctx, 400, "Tag ID not provided", nil)
+	}
+
+	statusCode, err := self.service.Delete(ctx.Context(), id)
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	Log(SeverityInfo, "Delete: tag deleted", map[string]any{"tagId": id})
+
+	return utils.Ok(ctx, statusCode, "Tag deleted", nil)
+}
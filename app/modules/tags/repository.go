@@ -0,0 +1,142 @@
+package tags
+
+import (
+	"context"
+	"errors"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"gorm.io/gorm"
+)
+
+var _ spec.Repository[models.Tag] = (*Repository)(nil)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (self *Repository) Create(ctx context.Context, entity *models.Tag) (*models.Tag, error) {
+	if entity == nil {
+		return nil, errors.New("entity cannot be nil")
+	}
+
+	err := self.db.WithContext(ctx).Create(entity).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+func (self *Repository) FindByID(ctx context.Context, id string) (*models.Tag, error) {
+	var entity models.Tag
+
+	err := self.db.WithContext(ctx).First(&entity, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+func (self *Repository) FindAll(ctx context.Context, queryOptions *spec.QueryOptions, filter *spec.Filter) ([]models.Tag, error) {
+	var entities []models.Tag
+
+	tx := self.db.WithContext(ctx)
+	tx = spec.ApplyPagination(tx, queryOptions)
+	tx, err := spec.ApplyFilters(tx, filter, models.Tag{})
+	if err != nil {
+		return entities, err
+	}
+
+	err = tx.Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+func (self *Repository) Count(ctx context.Context, filter *spec.Filter) (int64, error) {
+	var count int64
+
+	tx := self.db.WithContext(ctx)
+	tx, err := spec.ApplyFilters(tx, filter, models.Tag{})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Model(&models.Tag{}).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (self *Repository) Update(ctx context.Context, entity *models.Tag) error {
+	if entity == nil {
+		return errors.New("entity cannot be nil")
+	}
+
+	result := self.db.WithContext(ctx).Model(entity).Updates(entity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
+
+func (self *Repository) Delete(ctx context.Context, id string) error {
+	result := self.db.WithContext(ctx).Delete(&models.Tag{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
+
+func (self *Repository) Exists(ctx context.Context, id string) (bool, error) {
+	var count int64
+
+	err := self.db.WithContext(ctx).Model(&models.Tag{}).Where("id = ?", id).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// FindAllWithUsage lists tags along with the number of public posts using each, most used first.
+func (self *Repository) FindAllWithUsage(ctx context.Context, queryOptions *spec.QueryOptions) ([]models.TagDto, error) {
+	var entities []models.TagDto
+
+	tx := self.db.WithContext(ctx).
+		Model(&models.Tag{}).
+		Select("tags.id, tags.name, tags.created_at, COUNT(posts.id) AS usage_count").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("LEFT JOIN posts ON posts.id = post_tags.post_id AND posts.status = ? AND posts.visibility = ? AND NOT posts.hidden", models.PostStatusPublished, models.PostVisibilityPublic).
+		Group("tags.id").
+		Order("usage_count DESC, tags.name ASC")
+	tx = spec.ApplyPagination(tx, queryOptions)
+
+	err := tx.Scan(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
@@ -0,0 +1,32 @@
+package tags
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/modules/users"
+)
+
+func SetupRoutes(api fiber.Router, handler *Handler, usersService *users.Service) {
+	api = api.Group("/tags")
+
+	api.Get("/", handler.FindAll)
+	api.Get("/count", handler.GetCount)
+	api.Post(
+		"/",
+		users.AuthMiddleware(usersService),
+		users.RoleMiddleware(models.RoleAdmin, models.RoleModerator),
+		handler.Create,
+	)
+	api.Patch(
+		"/:id",
+		users.AuthMiddleware(usersService),
+		users.RoleMiddleware(models.RoleAdmin, models.RoleModerator),
+		handler.Update,
+	)
+	api.Delete(
+		"/:id",
+		users.AuthMiddleware(usersService),
+		users.RoleMiddleware(models.RoleAdmin, models.RoleModerator),
+		handler.Delete,
+	)
+}
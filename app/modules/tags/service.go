@@ -0,0 +1,128 @@
+package tags
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+)
+
+type Service struct {
+	repository *Repository
+}
+
+func NewService(repository *Repository) *Service {
+	return &Service{repository: repository}
+}
+
+func (self *Service) FindAllWithUsage(ctx context.Context, queryOptions *spec.QueryOptions) ([]models.TagDto, error) {
+	entities, err := self.repository.FindAllWithUsage(ctx, queryOptions)
+	if err != nil {
+		return entities, err
+	}
+
+	return entities, nil
+}
+
+func (self *Service) GetCount(ctx context.Context, filter *spec.Filter) (int64, error) {
+	count, err := self.repository.Count(ctx, filter)
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+func (self *Service) Create(ctx context.Context, entityDto *models.CreateTagDto) (*models.TagDto, int, error) {
+	entity := entityDto.FromDto()
+	if entity.Name == "" {
+		return &models.TagDto{}, 400, fmt.Errorf("Tag name can't be blank.")
+	}
+
+	taken, err := self.nameTaken(ctx, entity.Name, entity.ID)
+	if err != nil {
+		return &models.TagDto{}, 500, err
+	}
+	if taken {
+		return &models.TagDto{}, 409, fmt.Errorf("Tag %s already exists.", entity.Name)
+	}
+
+	entity, err = self.repository.Create(ctx, entity)
+	if err != nil {
+		return &models.TagDto{}, 500, err
+	}
+
+	return entity.ToDto(), 201, nil
+}
+
+// Update renames a tag. Posts keep the tag under its new name.
+func (self *Service) Update(ctx context.Context, id string, entityDto *models.UpdateTagDto) (*models.TagDto, int, error) {
+	entity, err := self.repository.FindByID(ctx, id)
+	if err != nil {
+		return &models.TagDto{}, 404, fmt.Errorf("Tag with ID %s not found.", id)
+	}
+
+	entity.Name = models.NormalizeTagName(entityDto.Name)
+	if entity.Name == "" {
+		return &models.TagDto{}, 400, fmt.Errorf("Tag name can't be blank.")
+	}
+
+	taken, err := self.nameTaken(ctx, entity.Name, entity.ID)
+	if err != nil {
+		return &models.TagDto{}, 500, err
+	}
+	if taken {
+		return &models.TagDto{}, 409, fmt.Errorf("Tag %s already exists.", entity.Name)
+	}
+
+	if err := self.repository.Update(ctx, entity); err != nil {
+		return &models.TagDto{}, 500, err
+	}
+
+	return entity.ToDto(), 200, nil
+}
+
+// Delete removes a tag from every post using it.
+func (self *Service) Delete(ctx context.Context, id string) (int, error) {
+	exists, err := self.repository.Exists(ctx, id)
+	if err != nil {
+		return 500, err
+	}
+	if !exists {
+		return 404, fmt.Errorf("Tag with ID %s not found.", id)
+	}
+
+	if err := self.repository.Delete(ctx, id); err != nil {
+		return 500, err
+	}
+
+	return 200, nil
+}
+
+func (self *Service) nameTaken(ctx context.Context, name string, id string) (bool, error) {
+	filter := spec.Filter{
+		Where: spec.WhereClause{
+			And: []spec.WhereCondition{
+				{
+					Column:   "name",
+					Operator: "=",
+					Value:    name,
+				},
+			},
+		},
+	}
+
+	existingTags, err := self.repository.FindAll(ctx, nil, &filter)
+	if err != nil {
+		return false, fmt.Errorf("Couldn't fetch existing tags for validation. %s", err)
+	}
+
+	for _, tag := range existingTags {
+		if tag.ID != id {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,174 @@
+package users
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/samborkent/uuidv7"
+)
+
+const (
+	tokenIssuer   = "go-as-your-backend"
+	tokenAudience = "https://api.go-as-your-backend.com"
+)
+
+// Token types, sent in the typ claim. All tokens are signed with the same keys, so a token is only
+// accepted where its type is expected.
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+	// tokenTypeMFAChallenge marks the tokens handed out between the password and the second factor.
+	tokenTypeMFAChallenge = "mfa_challenge"
+	// tokenTypeEmailVerification marks the tokens of email verification links.
+	tokenTypeEmailVerification = "verify_email"
+	// tokenTypeOIDCState marks the tokens that carry a sign in from the authorize endpoint to the callback.
+	tokenTypeOIDCState = "oidc_state"
+)
+
+// tokenClaims are the claims every token we sign carries.
+type tokenClaims struct {
+	Type string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+// newTokenClaims returns the claims of a token of the given type, valid from now until expiresAt. An
+// empty id gets a new one.
+func newTokenClaims(tokenType string, subject string, id string, expiresAt time.Time) tokenClaims {
+	if id == "" {
+		id = uuidv7.New().String()
+	}
+
+	now := time.Now()
+
+	return tokenClaims{
+		Type: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{tokenAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			NotBefore: jwt.NewNumericDate(now),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ID:        id,
+		},
+	}
+}
+
+// validate checks the type of the token. The issuer, audience and times are checked by the parser.
+func (self *tokenClaims) validate(tokenType string) error {
+	if self.Type != tokenType {
+		return fmt.Errorf("expected a token of type %q, got %q", tokenType, self.Type)
+	}
+	if self.ID == "" {
+		return fmt.Errorf("missing token ID")
+	}
+
+	return nil
+}
+
+// accessClaims are the claims of an access token, which authenticates requests.
+type accessClaims struct {
+	tokenClaims
+	UserID        string `json:"userId"`
+	Email         string `json:"email"`
+	RoleName      string `json:"roleName"`
+	SessionID     string `json:"sid"`
+	EmailVerified bool   `json:"emailVerified"`
+	MFA           bool   `json:"mfa"`
+}
+
+func (self *accessClaims) Validate() error {
+	if err := self.validate(tokenTypeAccess); err != nil {
+		return err
+	}
+	if self.UserID == "" || self.Email == "" || self.SessionID == "" {
+		return fmt.Errorf("missing user claims")
+	}
+
+	return nil
+}
+
+func (self *accessClaims) user() models.JwtUser {
+	return models.JwtUser{
+		UserID:        self.UserID,
+		Email:         self.Email,
+		RoleName:      self.RoleName,
+		EmailVerified: self.EmailVerified,
+		SessionID:     self.SessionID,
+		MFA:           self.MFA,
+	}
+}
+
+// refreshClaims are the claims of a refresh token. The token ID is the current token ID of the session.
+type refreshClaims struct {
+	tokenClaims
+	SessionID string `json:"sid"`
+}
+
+func (self *refreshClaims) Validate() error {
+	if err := self.validate(tokenTypeRefresh); err != nil {
+		return err
+	}
+	if self.SessionID == "" {
+		return fmt.Errorf("missing session")
+	}
+
+	return nil
+}
+
+// mfaChallengeClaims are the claims of an MFA challenge token. The subject is the user who entered
+// their password.
+type mfaChallengeClaims struct {
+	tokenClaims
+}
+
+func (self *mfaChallengeClaims) Validate() error {
+	if err := self.validate(tokenTypeMFAChallenge); err != nil {
+		return err
+	}
+	if self.Subject == "" {
+		return fmt.Errorf("missing user")
+	}
+
+	return nil
+}
+
+// emailVerificationClaims are the claims of an email verification link. The subject is the user, and
+// the link only works while they still have the email it was sent to.
+type emailVerificationClaims struct {
+	tokenClaims
+	Email string `json:"email"`
+}
+
+func (self *emailVerificationClaims) Validate() error {
+	if err := self.validate(tokenTypeEmailVerification); err != nil {
+		return err
+	}
+	if self.Subject == "" || self.Email == "" {
+		return fmt.Errorf("missing user claims")
+	}
+
+	return nil
+}
+
+// oidcStateClaims carry what the callback needs to finish a sign in with an identity provider.
+type oidcStateClaims struct {
+	tokenClaims
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+func (self *oidcStateClaims) Validate() error {
+	if err := self.validate(tokenTypeOIDCState); err != nil {
+		return err
+	}
+	if self.Provider == "" || self.State == "" || self.Verifier == "" {
+		return fmt.Errorf("missing sign in claims")
+	}
+
+	return nil
+}
@@ -1,7 +1,9 @@
 package users
 
 import (
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	. "github.com/okira-e/go-as-your-backend/app/logging"
@@ -90,58 +92,48 @@ func (self *Handler) Login(ctx *fiber.Ctx) error {
 		return utils.Err(ctx, 400, "Validation failed", err.Error())
 	}
 
-	refreshToken, user, statusCode, err := self.service.Login(payload.Email, payload.Password)
+	result, statusCode, err := self.service.Login(payload.Email, payload.Password, sessionClient(ctx))
 	if err != nil {
 		Log(SeverityWarn, "Login: failed attempt", map[string]any{"email": payload.Email})
 		return utils.Err(ctx, statusCode, err.Error(), nil)
 	}
 
-	accessToken, err := self.service.GenerateAccessToken(user.ID, user.Email, user.Role.Name)
-	if err != nil {
-		Log(SeverityError, "Login: failed to generate access token", map[string]any{"userId": user.ID, "error": err.Error()})
-		return utils.Err(ctx, 500, "Failed to generate access token", nil)
+	if result.MFAToken != "" {
+		Log(SeverityInfo, "Login: password accepted, waiting for the second factor", map[string]any{"userId": result.User.ID})
+		return utils.Ok(ctx, statusCode, "Two-factor authentication required", &models.MFAChallengeDto{
+			MFARequired: true,
+			MFAToken:    result.MFAToken,
+			ExpiresIn:   int(mfaChallengeTTL().Seconds()),
+		})
 	}
 
-	domain := utils.RequireEnv("DOMAIN")
+	Log(SeverityInfo, "Login: successful", map[string]any{"userId": result.User.ID})
 
-	accessTokenExpiryStr := utils.RequireEnv("ACCESS_TOKEN_EXPIRY")
-	accessTokenExpiry, _ := strconv.Atoi(accessTokenExpiryStr)
+	return respondWithTokens(ctx, statusCode, "Login successful", result.AccessToken, result.RefreshToken, result.User.ToDto())
+}
 
-	refreshTokenExpiryStr := utils.RequireEnv("REFRESH_TOKEN_EXPIRY")
-	refreshTokenExpiry, _ := strconv.Atoi(refreshTokenExpiryStr)
+// LoginMFA completes a login for users with two-factor authentication, with the MFA token returned by
+// Login and a code from the authenticator app or a recovery code.
+func (self *Handler) LoginMFA(ctx *fiber.Ctx) error {
+	var payload models.MFALoginDto
 
-	sameSite := "Lax"
-	if utils.RequireEnv("ENV") == "prod" {
-		sameSite = "None"
+	if err := ctx.BodyParser(&payload); err != nil {
+		return utils.Err(ctx, 400, "Invalid payload body", err.Error())
 	}
 
-	ctx.Cookie(&fiber.Cookie{
-		Name:        "refresh_token",
-		Value:       refreshToken,
-		MaxAge:      refreshTokenExpiry,
-		Path:        "/",
-		Domain:      domain,
-		Secure:      utils.RequireEnv("ENV") == "prod",
-		HTTPOnly:    true,
-		SameSite:    sameSite,
-		SessionOnly: false,
-	})
+	if err := utils.ValidateStruct(payload); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
 
-	ctx.Cookie(&fiber.Cookie{
-		Name:        "access_token",
-		Value:       accessToken,
-		MaxAge:      accessTokenExpiry,
-		Path:        "/",
-		Domain:      domain,
-		Secure:      utils.RequireEnv("ENV") == "prod",
-		HTTPOnly:    true,
-		SameSite:    sameSite,
-		SessionOnly: false,
-	})
+	result, statusCode, err := self.service.CompleteMFALogin(ctx.Context(), payload.MFAToken, payload.Code, sessionClient(ctx))
+	if err != nil {
+		Log(SeverityWarn, "LoginMFA: failed attempt", map[string]any{"error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
 
-	Log(SeverityInfo, "Login: successful", map[string]any{"userId": user.ID})
+	Log(SeverityInfo, "LoginMFA: successful", map[string]any{"userId": result.User.ID})
 
-	return utils.Ok(ctx, statusCode, "Login successful", user.ToDto())
+	return respondWithTokens(ctx, statusCode, "Login successful", result.AccessToken, result.RefreshToken, result.User.ToDto())
 }
 
 func (self *Handler) Me(ctx *fiber.Ctx) error {
@@ -196,96 +188,86 @@ func (self *Handler) Register(ctx *fiber.Ctx) error {
 
 	Log(SeverityInfo, "Register: user created", map[string]any{"userId": user.ID, "email": user.Email})
 
-	refreshToken, _, statusCode, err := self.service.Login(payload.Email, payload.Password)
-	if err != nil {
-		Log(SeverityError, "Register: auto-login failed", map[string]any{"userId": user.ID, "error": err.Error()})
-		return utils.Err(ctx, statusCode, err.Error(), nil)
+	// The account works without a verified address, so a failed email is logged rather than failing the
+	// registration. The user can ask for another one.
+	if err := self.service.SendVerificationEmail(ctx.Context(), user); err != nil {
+		Log(SeverityError, "Register: failed to send the verification email", map[string]any{"userId": user.ID, "error": err.Error()})
 	}
 
-	accessToken, err := self.service.GenerateAccessToken(user.ID, user.Email, user.Role.Name)
+	result, statusCode, err := self.service.Login(payload.Email, payload.Password, sessionClient(ctx))
 	if err != nil {
-		Log(SeverityError, "Register: failed to generate access token", map[string]any{"userId": user.ID, "error": err.Error()})
-		return utils.Err(ctx, 500, "Failed to generate access token", nil)
+		Log(SeverityError, "Register: auto-login failed", map[string]any{"userId": user.ID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
 	}
 
-	domain := utils.RequireEnv("DOMAIN")
-
-	accessTokenExpiryStr := utils.RequireEnv("ACCESS_TOKEN_EXPIRY")
-	accessTokenExpiry, _ := strconv.Atoi(accessTokenExpiryStr)
-
-	refreshTokenExpiryStr := utils.RequireEnv("REFRESH_TOKEN_EXPIRY")
-	refreshTokenExpiry, _ := strconv.Atoi(refreshTokenExpiryStr)
+	return respondWithTokens(ctx, 201, "User created successfully", result.AccessToken, result.RefreshToken, user.ToDto())
+}
 
-	sameSite := "Lax"
-	if utils.RequireEnv("ENV") == "prod" {
-		sameSite = "None"
+// RefreshToken rotates the refresh token and issues a new access token. Clients without cookies send
+// the refresh token in the body and get both tokens back in the body.
+func (self *Handler) RefreshToken(ctx *fiber.Ctx) error {
+	var payload struct {
+		RefreshToken string `json:"refresh_token"`
 	}
 
-	ctx.Cookie(&fiber.Cookie{
-		Name:        "refresh_token",
-		Value:       refreshToken,
-		MaxAge:      refreshTokenExpiry,
-		Path:        "/",
-		Domain:      domain,
-		Secure:      utils.RequireEnv("ENV") == "prod",
-		HTTPOnly:    true,
-		SameSite:    sameSite,
-		SessionOnly: false,
-	})
-
-	ctx.Cookie(&fiber.Cookie{
-		Name:        "access_token",
-		Value:       accessToken,
-		MaxAge:      accessTokenExpiry,
-		Path:        "/",
-		Domain:      domain,
-		Secure:      utils.RequireEnv("ENV") == "prod",
-		HTTPOnly:    true,
-		SameSite:    sameSite,
-		SessionOnly: false,
-	})
-
-	return utils.Ok(ctx, 201, "User created successfully", user.ToDto())
-}
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&payload); err != nil {
+			return utils.Err(ctx, 400, "Invalid payload body", err.Error())
+		}
+	}
 
-func (self *Handler) RefreshToken(ctx *fiber.Ctx) error {
-	refreshToken := ctx.Cookies("refresh_token")
+	refreshToken := payload.RefreshToken
+	inBody := refreshToken != "" || tokensInBody(ctx)
+	if refreshToken == "" {
+		refreshToken = ctx.Cookies("refresh_token")
+	}
 	if refreshToken == "" {
 		return utils.Err(ctx, 401, "Refresh token not found", nil)
 	}
 
-	accessToken, statusCode, err := self.service.Refresh(refreshToken)
+	accessToken, newRefreshToken, statusCode, err := self.service.Refresh(refreshToken)
 	if err != nil {
 		Log(SeverityWarn, "RefreshToken: failed to refresh", map[string]any{"error": err.Error()})
 		return utils.Err(ctx, statusCode, err.Error(), nil)
 	}
 
-	domain := utils.RequireEnv("DOMAIN")
-
-	accessTokenExpiryStr := utils.RequireEnv("ACCESS_TOKEN_EXPIRY")
-	accessTokenExpiry, _ := strconv.Atoi(accessTokenExpiryStr)
-
-	sameSite := "Lax"
-	if utils.RequireEnv("ENV") == "prod" {
-		sameSite = "None"
+	if inBody {
+		return utils.Ok(ctx, statusCode, "Token refreshed successfully", tokensDto(accessToken, newRefreshToken, nil))
 	}
 
-	ctx.Cookie(&fiber.Cookie{
-		Name:        "access_token",
-		Value:       accessToken,
-		MaxAge:      accessTokenExpiry,
-		Path:        "/",
-		Domain:      domain,
-		Secure:      utils.RequireEnv("ENV") == "prod",
-		HTTPOnly:    true,
-		SameSite:    sameSite,
-		SessionOnly: false,
-	})
+	setAuthCookies(ctx, accessToken, newRefreshToken)
 
 	return utils.Ok(ctx, statusCode, "Token refreshed successfully", fiber.Map{})
 }
 
+// Logout revokes the session of the request, named by the refresh token in the body or cookie or by
+// the access token, and clears the cookies. It succeeds even when there is no session to revoke.
 func (self *Handler) Logout(ctx *fiber.Ctx) error {
+	var payload struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&payload); err != nil {
+			return utils.Err(ctx, 400, "Invalid payload body", err.Error())
+		}
+	}
+
+	bearer, _ := bearerToken(ctx)
+	for _, token := range []string{payload.RefreshToken, ctx.Cookies("refresh_token"), bearer, ctx.Cookies("access_token")} {
+		sessionId, ok := self.service.sessionIdFromToken(token)
+		if !ok {
+			continue
+		}
+
+		if err := self.service.Logout(ctx.Context(), sessionId); err != nil {
+			Log(SeverityError, "Logout: failed to revoke session", map[string]any{"sessionId": sessionId, "error": err.Error()})
+			return utils.Err(ctx, 500, "Failed to log out", nil)
+		}
+
+		break
+	}
+
 	domain := utils.RequireEnv("DOMAIN")
 
 	sameSite := "Lax"
@@ -328,18 +310,412 @@ func (self *Handler) ValidateToken(ctx *fiber.Ctx) error {
 		return utils.Err(ctx, 400, "Validation failed", err.Error())
 	}
 
-	jwtSecret := utils.RequireEnv("JWT_SECRET")
-	if jwtSecret == "" {
-		Log(SeverityError, "ValidateToken: JWT_SECRET not configured", nil)
-		return utils.Err(ctx, 500, "JWT secret not configured", nil)
+	var claims accessClaims
+	if err := self.service.parseToken(token, &claims); err != nil {
+		return utils.Err(ctx, 401, "Invalid token", nil)
 	}
 
-	claims, err := validateToken(token, []byte(jwtSecret))
+	// A token outlives a revoked session until it expires, so the session is checked too.
+	active, err := self.service.IsSessionActive(ctx.Context(), claims.SessionID)
 	if err != nil {
-		return utils.Err(ctx, 401, "Invalid token", nil)
+		Log(SeverityError, "ValidateToken: failed to check the session", map[string]any{"sessionId": claims.SessionID, "error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to check the session", nil)
+	}
+	if !active {
+		return utils.Err(ctx, 401, "Session expired. Log in again.", nil)
 	}
 
 	return utils.Ok(ctx, 200, "Token is valid", fiber.Map{
 		"claims": claims,
 	})
 }
+
+// VerifyEmail verifies the email address named by the token of a verification link. It is a POST, not
+// the link itself, so mail scanners that open links don't verify addresses on their own.
+func (self *Handler) VerifyEmail(ctx *fiber.Ctx) error {
+	var payload struct {
+		Token string `json:"token"    validate:"required"`
+	}
+
+	if err := ctx.BodyParser(&payload); err != nil {
+		return utils.Err(ctx, 400, "Invalid payload body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(payload); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	user, statusCode, err := self.service.VerifyEmail(ctx.Context(), payload.Token)
+	if err != nil {
+		Log(SeverityWarn, "VerifyEmail: failed to verify", map[string]any{"error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "Email verified", user)
+}
+
+func (self *Handler) ResendVerificationEmail(ctx *fiber.Ctx) error {
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	statusCode, err := self.service.ResendVerificationEmail(ctx.Context(), user)
+	if err != nil {
+		Log(SeverityWarn, "ResendVerificationEmail: failed to resend", map[string]any{"userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "Verification email sent", nil)
+}
+
+// ForgotPassword emails a password reset link. It answers 202 whether or not an account uses the
+// address, so it can't be used to find out who has an account.
+func (self *Handler) ForgotPassword(ctx *fiber.Ctx) error {
+	var payload models.ForgotPasswordDto
+
+	if err := ctx.BodyParser(&payload); err != nil {
+		return utils.Err(ctx, 400, "Invalid payload body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(payload); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	self.service.ForgotPassword(payload.Email)
+
+	return utils.Ok(ctx, 202, "If an account uses this email address, a reset link was sent to it", nil)
+}
+
+// UnlockAccount lifts a lockout after too many failed logins, with the token of the link emailed when
+// it happened.
+func (self *Handler) UnlockAccount(ctx *fiber.Ctx) error {
+	var payload models.UnlockAccountDto
+
+	if err := ctx.BodyParser(&payload); err != nil {
+		return utils.Err(ctx, 400, "Invalid payload body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(payload); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	statusCode, err := self.service.UnlockAccount(ctx.Context(), payload.Token, sessionClient(ctx))
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "Account unlocked. You can log in again", nil)
+}
+
+func (self *Handler) ResetPassword(ctx *fiber.Ctx) error {
+	var payload models.ResetPasswordDto
+
+	if err := ctx.BodyParser(&payload); err != nil {
+		return utils.Err(ctx, 400, "Invalid payload body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(payload); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	statusCode, err := self.service.ResetPassword(ctx.Context(), &payload)
+	if err != nil {
+		Log(SeverityWarn, "ResetPassword: failed to reset", map[string]any{"error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "Password reset. Log in with your new password", nil)
+}
+
+func (self *Handler) FindSessions(ctx *fiber.Ctx) error {
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	sessions, err := self.service.FindSessions(ctx.Context(), user)
+	if err != nil {
+		Log(SeverityError, "FindSessions: failed to fetch sessions", map[string]any{"userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch sessions", nil)
+	}
+
+	return utils.Ok(ctx, 200, "", sessions)
+}
+
+func (self *Handler) RevokeSession(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	if err := utils.ValidateVar(id, "required,uuid"); err != nil {
+		return utils.Err(ctx, 400, "Invalid session ID", nil)
+	}
+
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	statusCode, err := self.service.RevokeSession(ctx.Context(), id, user)
+	if err != nil {
+		Log(SeverityWarn, "RevokeSession: failed to revoke session", map[string]any{"sessionId": id, "userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "Session revoked", nil)
+}
+
+// RevokeOtherSessions logs the user out everywhere but on the device of the request.
+func (self *Handler) RevokeOtherSessions(ctx *fiber.Ctx) error {
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "Missing user in headers", nil)
+	}
+
+	count, err := self.service.RevokeOtherSessions(ctx.Context(), user)
+	if err != nil {
+		Log(SeverityError, "RevokeOtherSessions: failed to revoke sessions", map[string]any{"userId": user.UserID, "error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to revoke sessions", nil)
+	}
+
+	return utils.Ok(ctx, 200, "Other sessions revoked", fiber.Map{"revoked": count})
+}
+
+func (self *Handler) FindUserSessions(ctx *fiber.Ctx) error {
+	userId := ctx.Params("id")
+	if err := utils.ValidateVar(userId, "required,uuid"); err != nil {
+		return utils.Err(ctx, 400, "Invalid user ID", nil)
+	}
+
+	sessions, err := self.service.FindUserSessions(ctx.Context(), userId)
+	if err != nil {
+		Log(SeverityError, "FindUserSessions: failed to fetch sessions", map[string]any{"userId": userId, "error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to fetch sessions", nil)
+	}
+
+	return utils.Ok(ctx, 200, "", sessions)
+}
+
+func (self *Handler) RevokeUserSession(ctx *fiber.Ctx) error {
+	userId := ctx.Params("id")
+	sessionId := ctx.Params("sessionId")
+	if err := utils.ValidateVar(userId, "required,uuid"); err != nil {
+		return utils.Err(ctx, 400, "Invalid user ID", nil)
+	}
+	if err := utils.ValidateVar(sessionId, "required,uuid"); err != nil {
+		return utils.Err(ctx, 400, "Invalid session ID", nil)
+	}
+
+	statusCode, err := self.service.RevokeUserSession(ctx.Context(), userId, sessionId)
+	if err != nil {
+		Log(SeverityWarn, "RevokeUserSession: failed to revoke session", map[string]any{"sessionId": sessionId, "userId": userId, "error": err.Error()})
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "Session revoked", nil)
+}
+
+func (self *Handler) RevokeUserSessions(ctx *fiber.Ctx) error {
+	userId := ctx.Params("id")
+	if err := utils.ValidateVar(userId, "required,uuid"); err != nil {
+		return utils.Err(ctx, 400, "Invalid user ID", nil)
+	}
+
+	count, err := self.service.RevokeUserSessions(ctx.Context(), userId)
+	if err != nil {
+		Log(SeverityError, "RevokeUserSessions: failed to revoke sessions", map[string]any{"userId": userId, "error": err.Error()})
+		return utils.Err(ctx, 500, "Failed to revoke sessions", nil)
+	}
+
+	return utils.Ok(ctx, 200, "Sessions revoked", fiber.Map{"revoked": count})
+}
+
+func (self *Handler) MFAStatus(ctx *fiber.Ctx) error {
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "User not found in context", nil)
+	}
+
+	status, statusCode, err := self.service.MFAStatus(ctx.Context(), user.UserID)
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", status)
+}
+
+// EnrollMFA starts the two-factor authentication setup. The returned URI is meant to be shown as a QR
+// code for the authenticator app to scan.
+func (self *Handler) EnrollMFA(ctx *fiber.Ctx) error {
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "User not found in context", nil)
+	}
+
+	enrollment, statusCode, err := self.service.EnrollMFA(ctx.Context(), user.UserID)
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "Scan the code with your authenticator app, then confirm it with a code", enrollment)
+}
+
+func (self *Handler) ConfirmMFA(ctx *fiber.Ctx) error {
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "User not found in context", nil)
+	}
+
+	var payload models.MFACodeDto
+
+	if err := ctx.BodyParser(&payload); err != nil {
+		return utils.Err(ctx, 400, "Invalid payload body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(payload); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	recoveryCodes, statusCode, err := self.service.ConfirmMFA(ctx.Context(), user, payload.Code)
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "Two-factor authentication enabled. Store the recovery codes somewhere safe", recoveryCodes)
+}
+
+func (self *Handler) RegenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "User not found in context", nil)
+	}
+
+	var payload models.MFACodeDto
+
+	if err := ctx.BodyParser(&payload); err != nil {
+		return utils.Err(ctx, 400, "Invalid payload body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(payload); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	recoveryCodes, statusCode, err := self.service.RegenerateRecoveryCodes(ctx.Context(), user.UserID, payload.Code)
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "Recovery codes replaced", recoveryCodes)
+}
+
+func (self *Handler) DisableMFA(ctx *fiber.Ctx) error {
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "User not found in context", nil)
+	}
+
+	var payload models.MFACodeDto
+
+	if err := ctx.BodyParser(&payload); err != nil {
+		return utils.Err(ctx, 400, "Invalid payload body", err.Error())
+	}
+
+	if err := utils.ValidateStruct(payload); err != nil {
+		return utils.Err(ctx, 400, "Validation failed", err.Error())
+	}
+
+	statusCode, err := self.service.DisableMFA(ctx.Context(), user, payload.Code)
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "Two-factor authentication disabled", nil)
+}
+
+// OIDCProviders lists the identity providers users can sign in with.
+func (self *Handler) OIDCProviders(ctx *fiber.Ctx) error {
+	providers := self.service.OIDCProviders()
+
+	basePath := strings.TrimSuffix(ctx.Path(), "/providers")
+	for _, provider := range providers {
+		provider.AuthorizePath = basePath + "/" + provider.Name + "/authorize"
+	}
+
+	return utils.Ok(ctx, 200, "", providers)
+}
+
+// AuthorizeOIDC sends the browser to the identity provider to sign in. An optional login_hint query
+// parameter is passed on to preselect the account.
+func (self *Handler) AuthorizeOIDC(ctx *fiber.Ctx) error {
+	authURL, stateToken, statusCode, err := self.service.StartOIDCLogin(ctx.Context(), ctx.Params("provider"), ctx.Query("login_hint"))
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	setOIDCStateCookie(ctx, stateToken)
+
+	return ctx.Redirect(authURL, fiber.StatusFound)
+}
+
+// OIDCCallback is where the identity provider sends the browser back to. It logs the user in with
+// cookies and sends the browser on to the client, with an mfa_token to complete the login with when
+// the user has two-factor authentication, or with an error.
+func (self *Handler) OIDCCallback(ctx *fiber.Ctx) error {
+	provider := ctx.Params("provider")
+	stateToken := ctx.Cookies("oidc_state")
+	setOIDCStateCookie(ctx, "")
+
+	if providerError := ctx.Query("error"); providerError != "" {
+		Log(SeverityWarn, "OIDCCallback: the provider refused the sign in", map[string]any{"provider": provider, "error": providerError})
+		return redirectToClient(ctx, map[string]string{"error": "The sign in was cancelled."})
+	}
+
+	result, _, err := self.service.CompleteOIDCLogin(ctx.Context(), provider, ctx.Query("code"), ctx.Query("state"), stateToken, sessionClient(ctx))
+	if err != nil {
+		Log(SeverityWarn, "OIDCCallback: failed attempt", map[string]any{"provider": provider, "error": err.Error()})
+		return redirectToClient(ctx, map[string]string{"error": err.Error()})
+	}
+
+	if result.MFAToken != "" {
+		Log(SeverityInfo, "OIDCCallback: identity accepted, waiting for the second factor", map[string]any{"userId": result.User.ID})
+		return redirectToClient(ctx, map[string]string{
+			"mfa_token":  result.MFAToken,
+			"expires_in": strconv.Itoa(int(mfaChallengeTTL().Seconds())),
+		})
+	}
+
+	Log(SeverityInfo, "OIDCCallback: successful", map[string]any{"userId": result.User.ID, "provider": provider})
+
+	setAuthCookies(ctx, result.AccessToken, result.RefreshToken)
+
+	return redirectToClient(ctx, nil)
+}
+
+func (self *Handler) FindIdentities(ctx *fiber.Ctx) error {
+	user, err := utils.GetUserFromContext(ctx)
+	if err != nil {
+		return utils.Err(ctx, 401, "User not found in context", nil)
+	}
+
+	identities, statusCode, err := self.service.FindIdentities(ctx.Context(), user.UserID)
+	if err != nil {
+		return utils.Err(ctx, statusCode, err.Error(), nil)
+	}
+
+	return utils.Ok(ctx, statusCode, "", identities)
+}
+
+// redirectToClient ends a sign in with an identity provider on OIDC_CLIENT_REDIRECT_URL, by default the
+// client's /oidc/callback page.
+func redirectToClient(ctx *fiber.Ctx, query map[string]string) error {
+	link, err := url.Parse(utils.GetEnv("OIDC_CLIENT_REDIRECT_URL", utils.RequireEnv("CLIENT_URL")+"/oidc/callback"))
+	if err != nil {
+		return utils.Err(ctx, 500, "Invalid OIDC_CLIENT_REDIRECT_URL", nil)
+	}
+
+	values := link.Query()
+	for key, value := range query {
+		values.Set(key, value)
+	}
+	link.RawQuery = values.Encode()
+
+	return ctx.Redirect(link.String(), fiber.StatusFound)
+}
@@ -0,0 +1,71 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"gorm.io/gorm"
+)
+
+type IdentitiesRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentitiesRepository(db *gorm.DB) *IdentitiesRepository {
+	return &IdentitiesRepository{db: db}
+}
+
+func (self *IdentitiesRepository) FindByProviderSubject(ctx context.Context, provider string, subject string) (*models.UserIdentity, error) {
+	var entity models.UserIdentity
+
+	err := self.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&entity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+func (self *IdentitiesRepository) FindByUserID(ctx context.Context, userId string) ([]models.UserIdentity, error) {
+	var entities []models.UserIdentity
+
+	err := self.db.WithContext(ctx).Where("user_id = ?", userId).Order("created_at ASC").Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// Touch records a login with the identity and the email the provider gave this time.
+func (self *IdentitiesRepository) Touch(ctx context.Context, id string, email string) error {
+	return self.db.WithContext(ctx).
+		Model(&models.UserIdentity{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"email":         email,
+			"last_login_at": time.Now().UTC(),
+		}).Error
+}
+
+// Link adds the identity to its user.
+func (self *IdentitiesRepository) Link(ctx context.Context, identity *models.UserIdentity) error {
+	return self.db.WithContext(ctx).Create(identity).Error
+}
+
+// CreateUser creates a user who signed up through an identity provider, along with the identity.
+func (self *IdentitiesRepository) CreateUser(ctx context.Context, user *models.User, identity *models.UserIdentity) error {
+	return self.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(identity).Error
+	})
+}
@@ -0,0 +1,299 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/audit"
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/mailer"
+	"github.com/okira-e/go-as-your-backend/app/metrics"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+var (
+	loginFailuresMetric  = metrics.NewCounter("auth_login_failures_total", "Failed logins.", "reason")
+	loginThrottledMetric = metrics.NewCounter("auth_login_throttled_total", "Logins refused because of too many failures.", "scope")
+	accountLockedMetric  = metrics.NewCounter("auth_account_lockouts_total", "Accounts locked out after too many failed logins.")
+)
+
+// loginThrottleSettings controls how failed logins slow down further attempts. An account, or an IP
+// address, gets a few free failures. After that each failure makes it wait twice as long as the one
+// before, up to backoffMax. The account is locked out once it reaches lockoutAfter failures.
+type loginThrottleSettings struct {
+	window           time.Duration
+	accountBackoffAt int
+	ipBackoffAt      int
+	backoffMax       time.Duration
+	lockoutAfter     int
+	lockoutDuration  time.Duration
+}
+
+func loginThrottleConfig() loginThrottleSettings {
+	return loginThrottleSettings{
+		window:           time.Duration(utils.GetEnvInt("LOGIN_FAILURE_WINDOW", 3600)) * time.Second,
+		accountBackoffAt: utils.GetEnvInt("LOGIN_BACKOFF_AFTER", 3),
+		ipBackoffAt:      utils.GetEnvInt("LOGIN_IP_BACKOFF_AFTER", 20),
+		backoffMax:       time.Duration(utils.GetEnvInt("LOGIN_BACKOFF_MAX", 300)) * time.Second,
+		lockoutAfter:     utils.GetEnvInt("LOGIN_LOCKOUT_AFTER", 10),
+		lockoutDuration:  time.Duration(utils.GetEnvInt("LOGIN_LOCKOUT_DURATION", 900)) * time.Second,
+	}
+}
+
+// timingHash is compared against when the email matches no user, so logging in to an unknown account
+// takes as long as with a wrong password.
+const timingHash = "$2a$14$UmXSbBWyzMlJxVZk0taRA.kDgPvA9nY0JikGe23I6R40FPDFtV1b6"
+
+// accountThrottleKey names the failures of an email, whether or not an account uses it, so throttling
+// doesn't tell which accounts exist.
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(ip string) string {
+	if ip == "" {
+		return ""
+	}
+
+	return "ip:" + ip
+}
+
+// loginThrottled reports whether logins to the account, and from the IP address, are refused right now.
+func (self *Service) loginThrottled(ctx context.Context, email string, ip string) (bool, bool, error) {
+	keys := []string{accountThrottleKey(email)}
+	if ipKey := ipThrottleKey(ip); ipKey != "" {
+		keys = append(keys, ipKey)
+	}
+
+	throttles, err := self.loginThrottlesRepository.FindByKeys(ctx, keys...)
+	if err != nil {
+		return false, false, err
+	}
+
+	now := time.Now().UTC()
+	accountBlocked, ipBlocked := false, false
+	for _, throttle := range throttles {
+		if !throttle.IsBlocked(now) {
+			continue
+		}
+
+		if throttle.Key == keys[0] {
+			accountBlocked = true
+			loginThrottledMetric.Inc("account")
+		} else {
+			ipBlocked = true
+			loginThrottledMetric.Inc("ip")
+		}
+	}
+
+	return accountBlocked, ipBlocked, nil
+}
+
+// recordLoginFailure counts a failed login against the IP address and, unless it is already blocked,
+// the account. Reaching the lockout threshold locks the account, records an audit event and emails the
+// user a link to unlock it, if an account uses the email.
+func (self *Service) recordLoginFailure(ctx context.Context, email string, client models.SessionClient, accountBlocked bool, reason string) {
+	loginFailuresMetric.Inc(reason)
+	settings := loginThrottleConfig()
+
+	if ipKey := ipThrottleKey(client.IPAddress); ipKey != "" {
+		if _, _, err := self.countLoginFailure(ctx, ipKey, settings.ipBackoffAt, 0, settings); err != nil {
+			Log(SeverityError, "recordLoginFailure: failed to count the failure", map[string]any{"key": ipKey, "error": err.Error()})
+		}
+	}
+
+	if accountBlocked {
+		return
+	}
+
+	accountKey := accountThrottleKey(email)
+	unlockToken, lockedUntil, err := self.countLoginFailure(ctx, accountKey, settings.accountBackoffAt, settings.lockoutAfter, settings)
+	if err != nil {
+		Log(SeverityError, "recordLoginFailure: failed to count the failure", map[string]any{"key": accountKey, "error": err.Error()})
+		return
+	}
+	if unlockToken == "" {
+		return
+	}
+
+	accountLockedMetric.Inc()
+
+	var user *models.User
+	if found, err := self.findUserByEmail(ctx, email); err == nil {
+		user = &found
+	}
+
+	event := audit.Event{
+		Type:      models.AuditAccountLocked,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Metadata:  map[string]any{"email": email, "lockedUntil": lockedUntil},
+	}
+	if user != nil {
+		event.UserID = user.ID
+	}
+	if err := self.auditRecorder.Record(ctx, event); err != nil {
+		Log(SeverityError, "recordLoginFailure: failed to record the lockout", map[string]any{"email": email, "error": err.Error()})
+	}
+
+	Log(SeverityWarn, "recordLoginFailure: account locked", map[string]any{"email": email, "ip": client.IPAddress})
+
+	if user == nil {
+		return
+	}
+
+	// Sending can take a while, and the response mustn't take longer for a lockout than for any other
+	// failure.
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		if err := self.sendUnlockEmail(ctx, *user, unlockToken, lockedUntil); err != nil {
+			Log(SeverityError, "recordLoginFailure: failed to send the unlock email", map[string]any{"userId": user.ID, "error": err.Error()})
+		}
+	}()
+}
+
+// countLoginFailure adds a failure to the throttle of the key and blocks further attempts as needed. It
+// returns the token of the unlock link when the failure locked the key out. A lockoutAfter of 0 never
+// locks out.
+func (self *Service) countLoginFailure(ctx context.Context, key string, backoffAt int, lockoutAfter int, settings loginThrottleSettings) (string, time.Time, error) {
+	var unlockToken string
+	var blockedUntil time.Time
+
+	err := self.loginThrottlesRepository.Transaction(ctx, func(repository *LoginThrottlesRepository) error {
+		throttle, err := repository.LockOrCreate(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+
+		// Old failures are forgotten, and so are the failures that led to a lockout once it is over.
+		if !throttle.IsBlocked(now) && (now.Sub(throttle.LastFailureAt) > settings.window || throttle.LockedAt != nil) {
+			throttle.Failures = 0
+			throttle.LockedAt = nil
+			throttle.UnlockTokenHash = nil
+		}
+
+		throttle.Failures++
+		throttle.LastFailureAt = now
+
+		switch {
+		case lockoutAfter > 0 && throttle.Failures >= lockoutAfter && throttle.LockedAt == nil:
+			unlockToken, err = newUnlockToken()
+			if err != nil {
+				return err
+			}
+
+			tokenHash := hashResetToken(unlockToken)
+			blockedUntil = now.Add(settings.lockoutDuration)
+			throttle.BlockedUntil = &blockedUntil
+			throttle.LockedAt = &now
+			throttle.UnlockTokenHash = &tokenHash
+
+		case throttle.Failures > backoffAt:
+			blockedUntil = now.Add(loginBackoff(throttle.Failures-backoffAt, settings.backoffMax))
+			throttle.BlockedUntil = &blockedUntil
+		}
+
+		return repository.Update(ctx, throttle)
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return unlockToken, blockedUntil, nil
+}
+
+// loginBackoff is how long to refuse logins after the nth failure past the free ones: 1s, 2s, 4s, ...
+func loginBackoff(n int, max time.Duration) time.Duration {
+	backoff := time.Second
+	for i := 1; i < n && backoff < max; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, max)
+}
+
+// clearLoginFailures forgets the failed logins of the account, after a successful login or a reset.
+func (self *Service) clearLoginFailures(ctx context.Context, email string) {
+	if err := self.loginThrottlesRepository.Delete(ctx, accountThrottleKey(email)); err != nil {
+		Log(SeverityError, "clearLoginFailures: failed to clear the failures", map[string]any{"email": email, "error": err.Error()})
+	}
+}
+
+// UnlockAccount lifts a lockout with the token of the link emailed when it happened.
+func (self *Service) UnlockAccount(ctx context.Context, token string, client models.SessionClient) (int, error) {
+	throttle, err := self.loginThrottlesRepository.DeleteByUnlockToken(ctx, hashResetToken(token))
+	if err != nil {
+		return 400, fmt.Errorf("The unlock link is invalid or was already used.")
+	}
+
+	email := strings.TrimPrefix(throttle.Key, "account:")
+
+	event := audit.Event{
+		Type:      models.AuditAccountUnlocked,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Metadata:  map[string]any{"email": email},
+	}
+	if user, err := self.findUserByEmail(ctx, email); err == nil {
+		event.UserID = user.ID
+	}
+	if err := self.auditRecorder.Record(ctx, event); err != nil {
+		Log(SeverityError, "UnlockAccount: failed to record the unlock", map[string]any{"email": email, "error": err.Error()})
+	}
+
+	return 200, nil
+}
+
+func (self *Service) sendUnlockEmail(ctx context.Context, user models.User, token string, lockedUntil time.Time) error {
+	link, err := unlockLink(token)
+	if err != nil {
+		return err
+	}
+
+	return self.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your account was locked",
+		Text: fmt.Sprintf(
+			"Hi %s,\n\nThere were too many failed attempts to log in to your account, so logging in is blocked for %s. "+
+				"If it was you, unlock your account now by opening this link:\n\n%s\n\n"+
+				"If it wasn't you, someone may be guessing your password. Your account is safe, but consider choosing a stronger password.\n",
+			user.FirstName, describeDuration(time.Until(lockedUntil).Round(time.Minute)), link,
+		),
+	})
+}
+
+func newUnlockToken() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// unlockLink builds the link sent in lockout emails. It points at ACCOUNT_UNLOCK_URL, by default the
+// client's /unlock-account page, which is expected to post the token to the API.
+func unlockLink(token string) (string, error) {
+	base := utils.GetEnv("ACCOUNT_UNLOCK_URL", utils.RequireEnv("CLIENT_URL")+"/unlock-account")
+
+	link, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
@@ -0,0 +1,93 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginThrottlesRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginThrottlesRepository(db *gorm.DB) *LoginThrottlesRepository {
+	return &LoginThrottlesRepository{db: db}
+}
+
+// Transaction runs fn with the repository bound to a single database transaction.
+func (self *LoginThrottlesRepository) Transaction(ctx context.Context, fn func(repository *LoginThrottlesRepository) error) error {
+	return self.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&LoginThrottlesRepository{db: tx})
+	})
+}
+
+// FindByKeys returns the throttles of the given keys that exist.
+func (self *LoginThrottlesRepository) FindByKeys(ctx context.Context, keys ...string) ([]models.LoginThrottle, error) {
+	var entities []models.LoginThrottle
+
+	err := self.db.WithContext(ctx).Where("key IN ?", keys).Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// LockOrCreate returns the throttle of the key, creating it if needed, and locks its row until the end
+// of the transaction so concurrent failures are all counted.
+func (self *LoginThrottlesRepository) LockOrCreate(ctx context.Context, key string) (*models.LoginThrottle, error) {
+	err := self.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.LoginThrottle{Key: key, LastFailureAt: time.Now().UTC()}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var entity models.LoginThrottle
+
+	err = self.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("key = ?", key).
+		First(&entity).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+func (self *LoginThrottlesRepository) Update(ctx context.Context, entity *models.LoginThrottle) error {
+	if entity == nil {
+		return errors.New("entity cannot be nil")
+	}
+
+	return self.db.WithContext(ctx).Model(entity).Select("*").Omit("Key").Updates(entity).Error
+}
+
+// Delete forgets the failures of the key.
+func (self *LoginThrottlesRepository) Delete(ctx context.Context, key string) error {
+	return self.db.WithContext(ctx).Where("key = ?", key).Delete(&models.LoginThrottle{}).Error
+}
+
+// DeleteByUnlockToken deletes the locked throttle whose unlock link has the token with the given hash,
+// and returns it. A link works once.
+func (self *LoginThrottlesRepository) DeleteByUnlockToken(ctx context.Context, tokenHash string) (*models.LoginThrottle, error) {
+	var deleted []models.LoginThrottle
+
+	err := self.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("unlock_token_hash = ?", tokenHash).
+		Delete(&deleted).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(deleted) == 0 {
+		return nil, errors.New("entity not found")
+	}
+
+	return &deleted[0], nil
+}
@@ -0,0 +1,330 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+const (
+	recoveryCodeCount = 10
+)
+
+// mfaChallengeTTL is how long a user has to enter their code after entering their password.
+func mfaChallengeTTL() time.Duration {
+	return time.Duration(utils.GetEnvInt("MFA_CHALLENGE_EXPIRY", 300)) * time.Second
+}
+
+// CompleteMFALogin finishes a login started with the password by checking the code from the
+// authenticator app, or a recovery code, and opens the session.
+func (self *Service) CompleteMFALogin(ctx context.Context, mfaToken string, code string, client models.SessionClient) (*LoginResult, int, error) {
+	userId, err := self.parseMFAChallengeToken(mfaToken)
+	if err != nil {
+		return nil, 401, err
+	}
+
+	user, err := self.findUserByID(ctx, userId)
+	if err != nil {
+		return nil, 500, fmt.Errorf("User not found.")
+	}
+
+	// Codes are throttled like passwords. Whoever got here knows the password, so there is nothing left
+	// to hide about the account.
+	accountBlocked, ipBlocked, err := self.loginThrottled(ctx, user.Email, client.IPAddress)
+	if err != nil {
+		return nil, 500, fmt.Errorf("Error while checking failed logins. %s\n", err.Error())
+	}
+	if accountBlocked || ipBlocked {
+		return nil, 429, fmt.Errorf("Too many failed logins. Try again later.")
+	}
+
+	statusCode := 500
+
+	err = self.mfaRepository.Transaction(ctx, func(repository *MFARepository, _ *SessionsRepository) error {
+		mfa, err := repository.LockByUserID(ctx, userId)
+		if err != nil || !mfa.IsEnabled() {
+			statusCode = 401
+			return fmt.Errorf("Invalid MFA token.")
+		}
+
+		ok, err := verifySecondFactor(ctx, repository, mfa, code, true)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			statusCode = 401
+			return fmt.Errorf("Invalid code.")
+		}
+
+		return nil
+	})
+	if err != nil {
+		if statusCode == 401 {
+			self.recordLoginFailure(ctx, user.Email, client, false, "mfa")
+		}
+		return nil, statusCode, err
+	}
+
+	self.clearLoginFailures(ctx, user.Email)
+
+	return self.openSession(ctx, user, client, true)
+}
+
+// MFAStatus tells whether the user has two-factor authentication and how many recovery codes are left.
+func (self *Service) MFAStatus(ctx context.Context, userId string) (*models.MFAStatusDto, int, error) {
+	enabled, err := self.mfaRepository.IsEnabled(ctx, userId)
+	if err != nil {
+		return nil, 500, fmt.Errorf("Failed to check two-factor authentication. %s", err)
+	}
+	if !enabled {
+		return &models.MFAStatusDto{}, 200, nil
+	}
+
+	mfa, err := self.mfaRepository.FindByUserID(ctx, userId)
+	if err != nil {
+		return nil, 500, fmt.Errorf("Failed to check two-factor authentication. %s", err)
+	}
+
+	recoveryCodesLeft, err := self.mfaRepository.CountRecoveryCodes(ctx, userId)
+	if err != nil {
+		return nil, 500, fmt.Errorf("Failed to count the recovery codes. %s", err)
+	}
+
+	return &models.MFAStatusDto{
+		Enabled:           true,
+		EnabledAt:         mfa.EnabledAt,
+		RecoveryCodesLeft: recoveryCodesLeft,
+	}, 200, nil
+}
+
+// EnrollMFA generates a new TOTP secret for the user. It isn't asked for on login until the user
+// confirms it with a code. Enrolling again before confirming replaces the secret.
+func (self *Service) EnrollMFA(ctx context.Context, userId string) (*models.MFAEnrollmentDto, int, error) {
+	enabled, err := self.mfaRepository.IsEnabled(ctx, userId)
+	if err != nil {
+		return nil, 500, fmt.Errorf("Failed to check two-factor authentication. %s", err)
+	}
+	if enabled {
+		return nil, 409, fmt.Errorf("Two-factor authentication is already enabled.")
+	}
+
+	user, err := self.findUserByID(ctx, userId)
+	if err != nil {
+		return nil, 404, fmt.Errorf("User not found.")
+	}
+
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, 500, fmt.Errorf("Failed to generate a secret. %s", err)
+	}
+
+	err = self.mfaRepository.Save(ctx, &models.UserMFA{
+		UserID:    user.ID,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, 500, fmt.Errorf("Failed to save the secret. %s", err)
+	}
+
+	return &models.MFAEnrollmentDto{
+		Secret:     secret,
+		OtpauthURI: totpURI(utils.GetEnv("MFA_ISSUER", "go-as-your-backend"), user.Email, secret),
+	}, 200, nil
+}
+
+// ConfirmMFA enables two-factor authentication once the user proves with a code that their
+// authenticator app was set up, and returns the recovery codes. The current session counts as having
+// passed two-factor authentication.
+func (self *Service) ConfirmMFA(ctx context.Context, user models.JwtUser, code string) (*models.MFARecoveryCodesDto, int, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, 500, fmt.Errorf("Failed to generate the recovery codes. %s", err)
+	}
+
+	statusCode := 500
+
+	err = self.mfaRepository.Transaction(ctx, func(repository *MFARepository, sessionsRepository *SessionsRepository) error {
+		mfa, err := repository.LockByUserID(ctx, user.UserID)
+		if err != nil {
+			statusCode = 400
+			return fmt.Errorf("Start the two-factor authentication setup first.")
+		}
+		if mfa.IsEnabled() {
+			statusCode = 409
+			return fmt.Errorf("Two-factor authentication is already enabled.")
+		}
+
+		ok, err := verifySecondFactor(ctx, repository, mfa, code, false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			statusCode = 400
+			return fmt.Errorf("Invalid code.")
+		}
+
+		now := time.Now().UTC()
+		mfa.EnabledAt = &now
+		if err := repository.Save(ctx, mfa); err != nil {
+			return err
+		}
+
+		if err := repository.ReplaceRecoveryCodes(ctx, user.UserID, hashes); err != nil {
+			return err
+		}
+
+		return sessionsRepository.MarkMFA(ctx, user.SessionID)
+	})
+	if err != nil {
+		return nil, statusCode, err
+	}
+
+	Log(SeverityInfo, "ConfirmMFA: two-factor authentication enabled", map[string]any{"userId": user.UserID})
+
+	return &models.MFARecoveryCodesDto{RecoveryCodes: codes}, 200, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user, for instance once they ran low. It
+// takes a code from the authenticator app.
+func (self *Service) RegenerateRecoveryCodes(ctx context.Context, userId string, code string) (*models.MFARecoveryCodesDto, int, error) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, 500, fmt.Errorf("Failed to generate the recovery codes. %s", err)
+	}
+
+	statusCode := 500
+
+	err = self.mfaRepository.Transaction(ctx, func(repository *MFARepository, _ *SessionsRepository) error {
+		mfa, err := repository.LockByUserID(ctx, userId)
+		if err != nil || !mfa.IsEnabled() {
+			statusCode = 400
+			return fmt.Errorf("Two-factor authentication isn't enabled.")
+		}
+
+		ok, err := verifySecondFactor(ctx, repository, mfa, code, false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			statusCode = 400
+			return fmt.Errorf("Invalid code.")
+		}
+
+		return repository.ReplaceRecoveryCodes(ctx, userId, hashes)
+	})
+	if err != nil {
+		return nil, statusCode, err
+	}
+
+	return &models.MFARecoveryCodesDto{RecoveryCodes: codes}, 200, nil
+}
+
+// DisableMFA turns off two-factor authentication after checking a code from the authenticator app or
+// a recovery code. Admins can't turn it off, since they need it to act as admins.
+func (self *Service) DisableMFA(ctx context.Context, user models.JwtUser, code string) (int, error) {
+	if user.RoleName == models.RoleAdmin {
+		return 403, fmt.Errorf("Admins can't turn off two-factor authentication.")
+	}
+
+	statusCode := 500
+
+	err := self.mfaRepository.Transaction(ctx, func(repository *MFARepository, _ *SessionsRepository) error {
+		mfa, err := repository.LockByUserID(ctx, user.UserID)
+		if err != nil || !mfa.IsEnabled() {
+			statusCode = 400
+			return fmt.Errorf("Two-factor authentication isn't enabled.")
+		}
+
+		ok, err := verifySecondFactor(ctx, repository, mfa, code, true)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			statusCode = 400
+			return fmt.Errorf("Invalid code.")
+		}
+
+		return repository.Delete(ctx, user.UserID)
+	})
+	if err != nil {
+		return statusCode, err
+	}
+
+	Log(SeverityInfo, "DisableMFA: two-factor authentication disabled", map[string]any{"userId": user.UserID})
+
+	return 200, nil
+}
+
+// verifySecondFactor checks a code from the authenticator app and, if allowRecovery is set, a recovery
+// code. Accepted codes can't be used again: the time step of an app code is recorded and a recovery code
+// is consumed. Call it in a transaction holding the lock on mfa.
+func verifySecondFactor(ctx context.Context, repository *MFARepository, mfa *models.UserMFA, code string, allowRecovery bool) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if step, ok := verifyTOTP(mfa.Secret, code, time.Now()); ok {
+		if step <= mfa.LastUsedStep {
+			return false, nil
+		}
+
+		mfa.LastUsedStep = step
+		return true, repository.Save(ctx, mfa)
+	}
+
+	if !allowRecovery {
+		return false, nil
+	}
+
+	return repository.ConsumeRecoveryCode(ctx, mfa.UserID, hashRecoveryCode(code))
+}
+
+func (self *Service) generateMFAChallengeToken(user models.User) (string, error) {
+	return self.signingKeys.Sign(&mfaChallengeClaims{
+		tokenClaims: newTokenClaims(tokenTypeMFAChallenge, user.ID, "", time.Now().Add(mfaChallengeTTL())),
+	})
+}
+
+// parseMFAChallengeToken returns the user an MFA challenge token was issued to.
+func (self *Service) parseMFAChallengeToken(mfaToken string) (string, error) {
+	var claims mfaChallengeClaims
+	if err := self.parseToken(mfaToken, &claims); err != nil {
+		return "", fmt.Errorf("Invalid MFA token. Log in again.")
+	}
+
+	return claims.Subject, nil
+}
+
+// generateRecoveryCodes returns new recovery codes, formatted like "abcd-efgh", and their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		random := make([]byte, 5)
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(totpEncoding.EncodeToString(random))
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes a recovery code, ignoring case, dashes and spaces so it can be typed loosely.
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,144 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/samborkent/uuidv7"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MFARepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// Transaction runs fn with repositories bound to a single database transaction.
+func (self *MFARepository) Transaction(
+	ctx context.Context,
+	fn func(repository *MFARepository, sessionsRepository *SessionsRepository) error,
+) error {
+	return self.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&MFARepository{db: tx}, &SessionsRepository{db: tx})
+	})
+}
+
+func (self *MFARepository) FindByUserID(ctx context.Context, userId string) (*models.UserMFA, error) {
+	var entity models.UserMFA
+
+	err := self.db.WithContext(ctx).Where("user_id = ?", userId).First(&entity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+// IsEnabled reports whether the user confirmed a second factor.
+func (self *MFARepository) IsEnabled(ctx context.Context, userId string) (bool, error) {
+	var count int64
+
+	err := self.db.WithContext(ctx).
+		Model(&models.UserMFA{}).
+		Where("user_id = ? AND enabled_at IS NOT NULL", userId).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// LockByUserID returns the second factor of the user and locks its row until the end of the
+// transaction, so a code can't be accepted twice by concurrent requests.
+func (self *MFARepository) LockByUserID(ctx context.Context, userId string) (*models.UserMFA, error) {
+	var entity models.UserMFA
+
+	err := self.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ?", userId).
+		First(&entity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+// Save creates or replaces the second factor of the user.
+func (self *MFARepository) Save(ctx context.Context, entity *models.UserMFA) error {
+	if entity == nil {
+		return errors.New("entity cannot be nil")
+	}
+
+	return self.db.WithContext(ctx).Save(entity).Error
+}
+
+// Delete removes the second factor of the user along with their recovery codes.
+func (self *MFARepository) Delete(ctx context.Context, userId string) error {
+	if err := self.db.WithContext(ctx).Where("user_id = ?", userId).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	return self.db.WithContext(ctx).Where("user_id = ?", userId).Delete(&models.UserMFA{}).Error
+}
+
+// ReplaceRecoveryCodes deletes the recovery codes of the user and stores the given hashes instead.
+func (self *MFARepository) ReplaceRecoveryCodes(ctx context.Context, userId string, codeHashes []string) error {
+	if err := self.db.WithContext(ctx).Where("user_id = ?", userId).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	codes := make([]models.MFARecoveryCode, len(codeHashes))
+	for i, codeHash := range codeHashes {
+		codes[i] = models.MFARecoveryCode{
+			ID:       uuidv7.New().String(),
+			UserID:   userId,
+			CodeHash: codeHash,
+		}
+	}
+
+	return self.db.WithContext(ctx).Create(&codes).Error
+}
+
+// ConsumeRecoveryCode marks the unused recovery code with the given hash as used, and reports whether
+// there was one.
+func (self *MFARepository) ConsumeRecoveryCode(ctx context.Context, userId string, codeHash string) (bool, error) {
+	result := self.db.WithContext(ctx).
+		Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", time.Now().UTC())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (self *MFARepository) CountRecoveryCodes(ctx context.Context, userId string) (int64, error) {
+	var count int64
+
+	err := self.db.WithContext(ctx).
+		Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userId).
+		Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
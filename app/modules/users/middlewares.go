@@ -1,9 +1,11 @@
 package users
 
 import (
-	"strconv"
+	"fmt"
+	"slices"
 
 	"github.com/gofiber/fiber/v2"
+	. "github.com/okira-e/go-as-your-backend/app/logging"
 	"github.com/okira-e/go-as-your-backend/app/models"
 	"github.com/okira-e/go-as-your-backend/app/utils"
 )
@@ -11,73 +13,135 @@ import (
 // AuthMiddleware validates the user from the JWT token and adds user to context
 func AuthMiddleware(usersService *Service) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
-		jwtSecret := []byte(utils.RequireEnv("JWT_SECRET"))
+		jwtUser, err := authenticate(ctx, usersService)
+		if err != nil {
+			return utils.Err(ctx, 401, err.Error(), nil)
+		}
 
-		// -------- Try access token --------
-		if access := ctx.Cookies("access_token"); access != "" {
-			claims, err := validateToken(access, jwtSecret)
-			if err != nil {
-				return utils.Err(ctx, 401, "Invalid access token.", nil)
-			}
+		ctx.Locals("user", jwtUser)
+		return ctx.Next()
+	}
+}
 
-			jwtUser := models.JwtUser{
-				UserID:   claims["userId"].(string),
-				Email:    claims["email"].(string),
-				RoleName: claims["roleName"].(string),
-			}
+// OptionalAuthMiddleware adds the user to context when the request is authenticated and lets
+// anonymous requests through. Use utils.GetOptionalUserFromContext to read the user.
+func OptionalAuthMiddleware(usersService *Service) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if !hasCredentials(ctx) {
+			return ctx.Next()
+		}
 
+		if jwtUser, err := authenticate(ctx, usersService); err == nil {
 			ctx.Locals("user", jwtUser)
-			return ctx.Next()
 		}
 
-		// -------- Try refresh token --------
-		refresh := ctx.Cookies("refresh_token")
-		if refresh == "" {
-			return utils.Err(ctx, 401, "Missing refresh token.", nil)
+		return ctx.Next()
+	}
+}
+
+// authenticate resolves the user of the request, trying in order:
+//
+//  1. The `Authorization: Bearer` header. When present it is authoritative: an invalid token fails
+//     the request and cookies are not looked at.
+//  2. The access token cookie.
+//  3. The refresh token cookie, in which case the session is refreshed and new token cookies are set.
+//
+// The session the token was issued for must still be active.
+func authenticate(ctx *fiber.Ctx, usersService *Service) (models.JwtUser, error) {
+	// -------- Try the Authorization header --------
+	if ctx.Get(fiber.HeaderAuthorization) != "" {
+		bearer, ok := bearerToken(ctx)
+		if !ok {
+			return models.JwtUser{}, fmt.Errorf("Authorization header must be a bearer token.")
+		}
+
+		jwtUser, err := usersService.parseAccessToken(bearer)
+		if err != nil {
+			return models.JwtUser{}, fmt.Errorf("Invalid access token.")
 		}
 
-		newAccessToken, status, err := usersService.Refresh(refresh)
-		if err != nil || status != 200 {
-			return utils.Err(ctx, 401, "Session expired. "+err.Error(), nil)
+		return activeSessionUser(ctx, usersService, jwtUser)
+	}
+
+	// -------- Try access token --------
+	if access := ctx.Cookies("access_token"); access != "" {
+		jwtUser, err := usersService.parseAccessToken(access)
+		if err != nil {
+			return models.JwtUser{}, fmt.Errorf("Invalid access token.")
 		}
 
-		// -------- Set new access token cookie --------
-		accessTokenExpiryStr := utils.RequireEnv("ACCESS_TOKEN_EXPIRY")
-		accessTokenExpiry, _ := strconv.Atoi(accessTokenExpiryStr)
-
-		ctx.Cookie(&fiber.Cookie{
-			Name:   "access_token",
-			Value:  newAccessToken,
-			MaxAge: accessTokenExpiry,
-			Path:   "/",
-			// Domain:      domain,
-			Secure:      utils.RequireEnv("ENV") == "prod",
-			HTTPOnly:    true,
-			SameSite:    "Lax",
-			SessionOnly: false,
-		})
-
-		// -------- Decode refreshed token --------
-		claims, err := validateToken(newAccessToken, jwtSecret)
+		return activeSessionUser(ctx, usersService, jwtUser)
+	}
+
+	// -------- Try refresh token --------
+	refresh := ctx.Cookies("refresh_token")
+	if refresh == "" {
+		return models.JwtUser{}, fmt.Errorf("Missing access token.")
+	}
+
+	newAccessToken, newRefreshToken, status, err := usersService.Refresh(refresh)
+	if err != nil || status != 200 {
+		return models.JwtUser{}, fmt.Errorf("Session expired. %s", err)
+	}
+
+	// -------- Set the new token cookies --------
+	setAuthCookies(ctx, newAccessToken, newRefreshToken)
+
+	// -------- Decode refreshed token --------
+	jwtUser, err := usersService.parseAccessToken(newAccessToken)
+	if err != nil {
+		return models.JwtUser{}, fmt.Errorf("Invalid refreshed token.")
+	}
+
+	// -------- Continue with the user claims --------
+	return jwtUser, nil
+}
+
+// activeSessionUser returns the user of an access token, as long as the session the token was issued
+// for wasn't revoked.
+func activeSessionUser(ctx *fiber.Ctx, usersService *Service, jwtUser models.JwtUser) (models.JwtUser, error) {
+	active, err := usersService.IsSessionActive(ctx.Context(), jwtUser.SessionID)
+	if err != nil {
+		Log(SeverityError, "activeSessionUser: failed to check the session", map[string]any{"sessionId": jwtUser.SessionID, "error": err.Error()})
+		return models.JwtUser{}, fmt.Errorf("Failed to check the session.")
+	}
+	if !active {
+		return models.JwtUser{}, fmt.Errorf("Session expired. Log in again.")
+	}
+
+	return jwtUser, nil
+}
+
+// RequireVerifiedEmail only lets through users who verified their email address. Call it after
+// AuthMiddleware. Users whose token predates their verification are looked up, so they don't have to
+// wait for a new token.
+func RequireVerifiedEmail(usersService *Service) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		user, err := utils.GetUserFromContext(ctx)
 		if err != nil {
-			return utils.Err(ctx, 401, "Invalid refreshed token.", err)
+			return utils.Err(ctx, 401, "User not found in context", nil)
+		}
+
+		if user.EmailVerified {
+			return ctx.Next()
 		}
 
-		// -------- Continue with the user claims --------
-		jwtUser := models.JwtUser{
-			UserID:   claims["userId"].(string),
-			Email:    claims["email"].(string),
-			RoleName: claims["roleName"].(string),
+		verified, err := usersService.IsEmailVerified(ctx.Context(), user.UserID)
+		if err != nil {
+			Log(SeverityError, "RequireVerifiedEmail: failed to check the user", map[string]any{"userId": user.UserID, "error": err.Error()})
+			return utils.Err(ctx, 500, "Failed to check the email verification", nil)
+		}
+		if !verified {
+			return utils.Err(ctx, 403, "Verify your email address first", nil)
 		}
 
-		ctx.Locals("user", jwtUser)
 		return ctx.Next()
 	}
 }
 
-// RoleMiddleware validates that the user has the required role
+// RoleMiddleware validates that the user has one of the required roles
 // It takes the user from the Fiber Ctx. So call this after AuthMiddleware
-func RoleMiddleware(requiredRole string) fiber.Handler {
+func RoleMiddleware(requiredRoles ...string) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		user := ctx.Locals("user")
 		if user == nil {
@@ -89,7 +153,10 @@ func RoleMiddleware(requiredRole string) fiber.Handler {
 			return utils.Err(ctx, 500, "Invalid user type in context", nil)
 		}
 
-		if jwtUser.RoleName != requiredRole {
+		if !jwtUser.HasRole(requiredRoles...) {
+			if jwtUser.RoleName == models.RoleAdmin && !jwtUser.MFA && slices.Contains(requiredRoles, models.RoleAdmin) {
+				return utils.Err(ctx, 403, "Admins must log in with two-factor authentication", nil)
+			}
 			return utils.Err(ctx, 403, "Required role is missing", nil)
 		}
 
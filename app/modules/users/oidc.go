@@ -0,0 +1,246 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/oidc"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"github.com/samborkent/uuidv7"
+)
+
+const (
+	// oidcStateTTL is how long a user has to sign in at the provider.
+	oidcStateTTL = 10 * time.Minute
+)
+
+// OIDCProviders lists the identity providers users can sign in with. The authorize path is left for
+// the handler to fill in.
+func (self *Service) OIDCProviders() []*models.OIDCProviderDto {
+	providers := self.oidcProviders.List()
+
+	dtos := make([]*models.OIDCProviderDto, len(providers))
+	for i, provider := range providers {
+		dtos[i] = &models.OIDCProviderDto{
+			Name:        provider.Name(),
+			DisplayName: provider.DisplayName(),
+		}
+	}
+
+	return dtos
+}
+
+// StartOIDCLogin returns the page of the provider to send the user to, and the state token to keep
+// in the browser until the provider sends the user back. The state token holds the PKCE verifier and
+// the nonce, and is signed so the callback can trust them.
+func (self *Service) StartOIDCLogin(ctx context.Context, providerName string, loginHint string) (string, string, int, error) {
+	provider, ok := self.oidcProviders.Get(providerName)
+	if !ok {
+		return "", "", 404, fmt.Errorf("Unknown identity provider %s.", providerName)
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", "", 500, fmt.Errorf("Failed to generate the state. %s", err)
+	}
+	nonce, err := oidc.RandomString()
+	if err != nil {
+		return "", "", 500, fmt.Errorf("Failed to generate the nonce. %s", err)
+	}
+	codeVerifier, err := oidc.RandomString()
+	if err != nil {
+		return "", "", 500, fmt.Errorf("Failed to generate the code verifier. %s", err)
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallenge(codeVerifier), loginHint)
+	if err != nil {
+		Log(SeverityError, "StartOIDCLogin: failed to reach the provider", map[string]any{"provider": providerName, "error": err.Error()})
+		return "", "", 502, fmt.Errorf("The identity provider is unavailable.")
+	}
+
+	stateToken, err := self.signingKeys.Sign(&oidcStateClaims{
+		tokenClaims: newTokenClaims(tokenTypeOIDCState, "", "", time.Now().Add(oidcStateTTL)),
+		Provider:    providerName,
+		State:       state,
+		Nonce:       nonce,
+		Verifier:    codeVerifier,
+	})
+	if err != nil {
+		return "", "", 500, fmt.Errorf("Failed to sign the state. %s", err)
+	}
+
+	return authURL, stateToken, 200, nil
+}
+
+// CompleteOIDCLogin handles the provider sending the user back with a code. The identity is matched to
+// a user by the provider and subject first. A new identity is linked to the user with the same email
+// if both the provider and the user verified it, or else signs up a new user. Users with two-factor authentication get
+// an MFA challenge like with a password.
+func (self *Service) CompleteOIDCLogin(
+	ctx context.Context,
+	providerName string,
+	code string,
+	state string,
+	stateToken string,
+	client models.SessionClient,
+) (*LoginResult, int, error) {
+	provider, ok := self.oidcProviders.Get(providerName)
+	if !ok {
+		return nil, 404, fmt.Errorf("Unknown identity provider %s.", providerName)
+	}
+
+	var claims oidcStateClaims
+	if err := self.parseToken(stateToken, &claims); err != nil {
+		return nil, 400, fmt.Errorf("The sign in expired. Try again.")
+	}
+	if claims.Provider != providerName || state != claims.State {
+		return nil, 400, fmt.Errorf("The sign in expired. Try again.")
+	}
+
+	identity, err := provider.Exchange(ctx, code, claims.Verifier, claims.Nonce)
+	if err != nil {
+		Log(SeverityWarn, "CompleteOIDCLogin: failed to exchange the code", map[string]any{"provider": providerName, "error": err.Error()})
+		return nil, 401, fmt.Errorf("The identity provider didn't accept the sign in.")
+	}
+
+	user, statusCode, err := self.resolveIdentity(ctx, identity)
+	if err != nil {
+		return nil, statusCode, err
+	}
+
+	mfaEnabled, err := self.mfaRepository.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, 500, fmt.Errorf("Error while checking two-factor authentication. %s\n", err.Error())
+	}
+	if mfaEnabled {
+		mfaToken, err := self.generateMFAChallengeToken(user)
+		if err != nil {
+			return nil, 500, fmt.Errorf("Error while generating an MFA token. %s\n", err.Error())
+		}
+
+		return &LoginResult{User: user, MFAToken: mfaToken}, 200, nil
+	}
+
+	return self.openSession(ctx, user, client, false)
+}
+
+func (self *Service) FindIdentities(ctx context.Context, userId string) ([]*models.UserIdentityDto, int, error) {
+	entities, err := self.identitiesRepository.FindByUserID(ctx, userId)
+	if err != nil {
+		return nil, 500, fmt.Errorf("Failed to fetch the identities. %s", err)
+	}
+
+	dtos := make([]*models.UserIdentityDto, len(entities))
+	for i := range entities {
+		dtos[i] = entities[i].ToDto()
+	}
+
+	return dtos, 200, nil
+}
+
+// resolveIdentity finds or creates the user the identity belongs to.
+func (self *Service) resolveIdentity(ctx context.Context, identity *oidc.Identity) (models.User, int, error) {
+	existing, err := self.identitiesRepository.FindByProviderSubject(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		if err := self.identitiesRepository.Touch(ctx, existing.ID, identity.Email); err != nil {
+			return models.User{}, 500, fmt.Errorf("Failed to update the identity. %s", err)
+		}
+
+		user, err := self.findUserByID(ctx, existing.UserID)
+		if err != nil {
+			return models.User{}, 500, fmt.Errorf("User not found.")
+		}
+
+		return user, 200, nil
+	}
+
+	// Matching accounts by an email the provider didn't verify would let anyone take over an account
+	// by claiming its address.
+	if identity.Email == "" || !identity.EmailVerified {
+		return models.User{}, 403, fmt.Errorf("The identity provider didn't verify your email address.")
+	}
+
+	filter := spec.Filter{
+		Where: spec.WhereClause{
+			And: []spec.WhereCondition{
+				{
+					Column:   "email",
+					Operator: "=",
+					Value:    identity.Email,
+				},
+			},
+		},
+	}
+	users, err := self.FindAll(ctx, nil, &filter)
+	if err != nil {
+		return models.User{}, 500, fmt.Errorf("Error fetching user. %s", err)
+	}
+
+	if len(users) > 0 {
+		user := users[0]
+
+		// Anyone can register a password account under an email they don't own. Linking it would hand
+		// the real owner's identity to whoever knows that password.
+		if !user.IsEmailVerified() {
+			return models.User{}, 409, fmt.Errorf("An account with this email address already exists. Sign in with its password and verify the email address first.")
+		}
+
+		if err := self.identitiesRepository.Link(ctx, models.NewUserIdentity(user.ID, identity.Provider, identity.Subject, identity.Email)); err != nil {
+			return models.User{}, 500, fmt.Errorf("Failed to link the identity. %s", err)
+		}
+
+		Log(SeverityInfo, "CompleteOIDCLogin: identity linked", map[string]any{"userId": user.ID, "provider": identity.Provider})
+
+		return user, 200, nil
+	}
+
+	user := newUserFromIdentity(identity)
+	if err := self.identitiesRepository.CreateUser(ctx, &user, models.NewUserIdentity(user.ID, identity.Provider, identity.Subject, identity.Email)); err != nil {
+		return models.User{}, 500, fmt.Errorf("Failed to create the user. %s", err)
+	}
+
+	Log(SeverityInfo, "CompleteOIDCLogin: user signed up", map[string]any{"userId": user.ID, "provider": identity.Provider})
+
+	return user, 200, nil
+}
+
+// newUserFromIdentity builds the user signing up through a provider. They have no password until
+// they set one with the forgot password flow, and no phone number.
+func newUserFromIdentity(identity *oidc.Identity) models.User {
+	now := time.Now().UTC()
+
+	firstName := identity.FirstName
+	if firstName == "" {
+		firstName, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	user := models.User{
+		ID:              uuidv7.New().String(),
+		FirstName:       truncateRunes(firstName, 32),
+		LastName:        truncateRunes(identity.LastName, 32),
+		Email:           identity.Email,
+		IsActive:        true,
+		CreatedAt:       now,
+		EmailVerifiedAt: &now,
+	}
+
+	if avatar, err := url.Parse(identity.AvatarURL); err == nil && avatar.Scheme == "https" && len(identity.AvatarURL) <= 2048 {
+		user.AvatarURL = &identity.AvatarURL
+	}
+
+	return user
+}
+
+func truncateRunes(value string, max int) string {
+	runes := []rune(value)
+	if len(runes) <= max {
+		return value
+	}
+
+	return string(runes[:max])
+}
@@ -0,0 +1,399 @@
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/okira-e/go-as-your-backend/app/audit"
+	"github.com/okira-e/go-as-your-backend/app/mailer"
+	"github.com/okira-e/go-as-your-backend/app/oidc"
+	"github.com/okira-e/go-as-your-backend/app/signing"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	oidcTestClientID    = "go-as-your-backend"
+	oidcTestCallbackURL = "http://api.test/api/users/oidc/mock/callback"
+	oidcTestClientURL   = "http://client.test/oidc/callback"
+)
+
+// oidcTest runs the users routes against the mock provider, with the database mocked.
+type oidcTest struct {
+	t        *testing.T
+	app      *fiber.App
+	service  *Service
+	provider *oidc.MockServer
+	db       sqlmock.Sqlmock
+	// browser talks to the provider and stops at its redirects, like the test stops at ours.
+	browser *http.Client
+}
+
+func newOIDCTest(t *testing.T) *oidcTest {
+	t.Setenv("ENV", "test")
+	t.Setenv("DOMAIN", "localhost")
+	t.Setenv("CLIENT_URL", "http://client.test")
+	t.Setenv("ACCESS_TOKEN_EXPIRY", "900")
+	t.Setenv("REFRESH_TOKEN_EXPIRY", "86400")
+
+	var provider *oidc.MockServer
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := oidc.NewMockServer(server.URL)
+	if err != nil {
+		t.Fatalf("starting the mock provider: %s", err)
+	}
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("mocking the database: %s", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("opening the database: %s", err)
+	}
+
+	providers := oidc.NewRegistry(oidc.NewGeneric(oidc.Config{
+		Name:        "mock",
+		DisplayName: "Mock",
+		Issuer:      server.URL,
+		ClientID:    oidcTestClientID,
+		RedirectURL: oidcTestCallbackURL,
+	}))
+	signingKeys := signing.NewKeySet(nil, signing.Config{Algorithm: signing.HS256, Secret: []byte("test-secret")})
+
+	service := NewService(
+		NewRepository(db),
+		NewSessionsRepository(db),
+		NewPasswordResetsRepository(db),
+		NewLoginThrottlesRepository(db),
+		NewMFARepository(db),
+		NewIdentitiesRepository(db),
+		providers,
+		signingKeys,
+		audit.NewDatabase(db),
+		mailer.NewConsole("no-reply@example.com"),
+	)
+
+	app := fiber.New()
+	SetupRoutes(app.Group("/api"), NewHandler(service), service)
+
+	return &oidcTest{
+		t:        t,
+		app:      app,
+		service:  service,
+		provider: provider,
+		db:       mock,
+		browser: &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// authorize starts a sign in and lets the provider answer it. It returns the state cookie we set and
+// the callback the provider sent the browser back to.
+func (self *oidcTest) authorize(loginHint string) (*http.Cookie, *url.URL) {
+	self.t.Helper()
+
+	resp := self.do("/api/users/oidc/mock/authorize?login_hint="+url.QueryEscape(loginHint), nil)
+	if resp.StatusCode != http.StatusFound {
+		self.t.Fatalf("authorize answered %d, expected a redirect to the provider", resp.StatusCode)
+	}
+
+	stateCookie := cookie(resp, "oidc_state")
+	if stateCookie == nil || stateCookie.Value == "" {
+		self.t.Fatal("authorize didn't set the state cookie")
+	}
+
+	authURL, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		self.t.Fatalf("invalid authorization URL: %s", err)
+	}
+
+	// The provider only gets the challenge. The verifier stays in the signed state cookie.
+	claims := self.state(stateCookie)
+	query := authURL.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") != oidc.CodeChallenge(claims.Verifier) {
+		self.t.Fatalf("the code challenge %q doesn't match the verifier in the state", query.Get("code_challenge"))
+	}
+	if query.Get("state") != claims.State || query.Get("nonce") != claims.Nonce {
+		self.t.Fatal("the state and nonce sent to the provider aren't the ones in the state cookie")
+	}
+
+	providerResp, err := self.browser.Get(authURL.String())
+	if err != nil {
+		self.t.Fatalf("reaching the provider: %s", err)
+	}
+	providerResp.Body.Close()
+	if providerResp.StatusCode != http.StatusFound {
+		self.t.Fatalf("the provider answered %d, expected a redirect to the callback", providerResp.StatusCode)
+	}
+
+	callback, err := url.Parse(providerResp.Header.Get("Location"))
+	if err != nil || !strings.HasPrefix(callback.String(), oidcTestCallbackURL+"?") {
+		self.t.Fatalf("the provider sent the browser to %q", providerResp.Header.Get("Location"))
+	}
+
+	return stateCookie, callback
+}
+
+// callback hands the callback to our API and returns the query we sent the browser on to the client
+// with, and the cookies we set.
+func (self *oidcTest) callback(callback *url.URL, stateCookie *http.Cookie) (url.Values, *http.Response) {
+	self.t.Helper()
+
+	resp := self.do(callback.RequestURI(), stateCookie)
+	if resp.StatusCode != http.StatusFound {
+		self.t.Fatalf("the callback answered %d, expected a redirect to the client", resp.StatusCode)
+	}
+
+	location := resp.Header.Get("Location")
+	if !strings.HasPrefix(location, oidcTestClientURL) {
+		self.t.Fatalf("the callback sent the browser to %q", location)
+	}
+
+	link, err := url.Parse(location)
+	if err != nil {
+		self.t.Fatalf("invalid client URL: %s", err)
+	}
+
+	return link.Query(), resp
+}
+
+func (self *oidcTest) do(target string, stateCookie *http.Cookie) *http.Response {
+	self.t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if stateCookie != nil {
+		req.AddCookie(stateCookie)
+	}
+
+	resp, err := self.app.Test(req, -1)
+	if err != nil {
+		self.t.Fatalf("GET %s: %s", target, err)
+	}
+
+	return resp
+}
+
+func (self *oidcTest) state(stateCookie *http.Cookie) oidcStateClaims {
+	self.t.Helper()
+
+	var claims oidcStateClaims
+	if err := self.service.parseToken(stateCookie.Value, &claims); err != nil {
+		self.t.Fatalf("invalid state cookie: %s", err)
+	}
+
+	return claims
+}
+
+// forgeState re-signs the state cookie with a change, as if it had been issued for another sign in.
+func (self *oidcTest) forgeState(stateCookie *http.Cookie, change func(claims *oidcStateClaims)) *http.Cookie {
+	self.t.Helper()
+
+	claims := self.state(stateCookie)
+	change(&claims)
+
+	stateToken, err := self.service.signingKeys.Sign(&claims)
+	if err != nil {
+		self.t.Fatalf("signing the state: %s", err)
+	}
+
+	return &http.Cookie{Name: stateCookie.Name, Value: stateToken}
+}
+
+func (self *oidcTest) expectNoIdentity() {
+	self.db.ExpectQuery(`SELECT \* FROM "user_identities" WHERE .*provider = \$1 AND subject = \$2`).
+		WithArgs("mock", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
+func cookie(resp *http.Response, name string) *http.Cookie {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+func (self *oidcTest) expectUser(id string, email string, emailVerifiedAt *time.Time) {
+	self.db.ExpectQuery(`SELECT \* FROM "users" WHERE .*email`).
+		WithArgs(email).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "role_id", "first_name", "last_name", "email", "password", "is_active", "email_verified_at"}).
+			AddRow(id, nil, "Jane", "Doe", email, "hash", true, emailVerifiedAt))
+}
+
+func TestOIDCLoginLinksExistingVerifiedEmail(t *testing.T) {
+	test := newOIDCTest(t)
+
+	const userId = "01890a5d-ac96-774b-bcce-b302099a8057"
+	const email = "jane@example.com"
+	verifiedAt := time.Now().Add(-time.Hour)
+
+	stateCookie, callback := test.authorize(email)
+
+	test.expectNoIdentity()
+	test.expectUser(userId, email, &verifiedAt)
+	test.db.ExpectBegin()
+	test.db.ExpectQuery(`INSERT INTO "user_identities"`).
+		WithArgs(sqlmock.AnyArg(), userId, "mock", sqlmock.AnyArg(), email, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "last_login_at"}).AddRow(time.Now(), time.Now()))
+	test.db.ExpectCommit()
+	test.db.ExpectQuery(`SELECT count\(\*\) FROM .* enabled_at IS NOT NULL`).
+		WithArgs(userId).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	test.db.ExpectBegin()
+	test.db.ExpectQuery(`INSERT INTO "sessions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"created_at", "last_seen_at"}).AddRow(time.Now(), time.Now()))
+	test.db.ExpectCommit()
+
+	query, resp := test.callback(callback, stateCookie)
+	if query.Get("error") != "" {
+		t.Fatalf("the sign in failed: %s", query.Get("error"))
+	}
+
+	accessToken := cookie(resp, "access_token")
+	if accessToken == nil {
+		t.Fatal("the callback didn't set the access token cookie")
+	}
+	var claims accessClaims
+	if err := test.service.parseToken(accessToken.Value, &claims); err != nil {
+		t.Fatalf("invalid access token: %s", err)
+	}
+	if claims.UserID != userId {
+		t.Fatalf("signed in as %s, expected the user with the email %s", claims.UserID, userId)
+	}
+
+	if err := test.db.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// An account registered with a password under someone else's email must not be handed their identity.
+func TestOIDCLoginDoesNotLinkUnverifiedAccount(t *testing.T) {
+	test := newOIDCTest(t)
+
+	const email = "jane@example.com"
+
+	stateCookie, callback := test.authorize(email)
+
+	test.expectNoIdentity()
+	test.expectUser("01890a5d-ac96-774b-bcce-b302099a8057", email, nil)
+
+	query, resp := test.callback(callback, stateCookie)
+	if query.Get("error") != "An account with this email address already exists. Sign in with its password and verify the email address first." {
+		t.Fatalf("unexpected outcome %q", query.Encode())
+	}
+	if cookie(resp, "access_token") != nil {
+		t.Fatal("the refused sign in set an access token")
+	}
+
+	// Nothing was linked or verified.
+	if err := test.db.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOIDCLoginDoesNotLinkUnverifiedEmail(t *testing.T) {
+	test := newOIDCTest(t)
+	test.provider.EmailVerified = false
+
+	stateCookie, callback := test.authorize("jane@example.com")
+
+	// The user with the email is never looked up, let alone linked.
+	test.expectNoIdentity()
+
+	query, _ := test.callback(callback, stateCookie)
+	if query.Get("error") != "The identity provider didn't verify your email address." {
+		t.Fatalf("unexpected outcome %q", query.Encode())
+	}
+
+	if err := test.db.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOIDCCallbackRejectsTamperedSignIn(t *testing.T) {
+	cases := []struct {
+		name string
+		// tamper changes the callback before it reaches us.
+		tamper func(test *oidcTest, query url.Values, stateCookie *http.Cookie) *http.Cookie
+		error  string
+	}{
+		{
+			name: "state mismatch",
+			tamper: func(test *oidcTest, query url.Values, stateCookie *http.Cookie) *http.Cookie {
+				query.Set("state", "forged")
+				return stateCookie
+			},
+			error: "The sign in expired. Try again.",
+		},
+		{
+			name: "missing state cookie",
+			tamper: func(test *oidcTest, query url.Values, stateCookie *http.Cookie) *http.Cookie {
+				return nil
+			},
+			error: "The sign in expired. Try again.",
+		},
+		{
+			name: "state cookie of another provider",
+			tamper: func(test *oidcTest, query url.Values, stateCookie *http.Cookie) *http.Cookie {
+				return test.forgeState(stateCookie, func(claims *oidcStateClaims) { claims.Provider = "other" })
+			},
+			error: "The sign in expired. Try again.",
+		},
+		{
+			name: "wrong code verifier",
+			tamper: func(test *oidcTest, query url.Values, stateCookie *http.Cookie) *http.Cookie {
+				return test.forgeState(stateCookie, func(claims *oidcStateClaims) { claims.Verifier = "forged" })
+			},
+			error: "The identity provider didn't accept the sign in.",
+		},
+		{
+			name: "nonce mismatch",
+			tamper: func(test *oidcTest, query url.Values, stateCookie *http.Cookie) *http.Cookie {
+				return test.forgeState(stateCookie, func(claims *oidcStateClaims) { claims.Nonce = "forged" })
+			},
+			error: "The identity provider didn't accept the sign in.",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newOIDCTest(t)
+
+			stateCookie, callback := test.authorize("jane@example.com")
+
+			query := callback.Query()
+			stateCookie = tc.tamper(test, query, stateCookie)
+			callback.RawQuery = query.Encode()
+
+			clientQuery, resp := test.callback(callback, stateCookie)
+			if clientQuery.Get("error") != tc.error {
+				t.Fatalf("got error %q, expected %q", clientQuery.Get("error"), tc.error)
+			}
+			if cookie(resp, "access_token") != nil {
+				t.Fatal("the rejected sign in set an access token")
+			}
+
+			// Nothing reached the database.
+			if err := test.db.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/mailer"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordResetTTL is how long a password reset link stays valid.
+func passwordResetTTL() time.Duration {
+	return time.Duration(utils.GetEnvInt("PASSWORD_RESET_EXPIRY", 1800)) * time.Second
+}
+
+// ForgotPassword emails a password reset link if an account uses the address. The work happens in the
+// background and nothing is reported back, so neither the response nor its timing tell whether the
+// account exists.
+func (self *Service) ForgotPassword(email string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		if err := self.sendPasswordReset(ctx, email); err != nil {
+			Log(SeverityError, "ForgotPassword: failed to send the reset email", map[string]any{"email": email, "error": err.Error()})
+		}
+	}()
+}
+
+// ResetPassword sets a new password with the token of a reset link. The token can only be used once,
+// and every session of the user is revoked, logging out whoever may have known the old password.
+func (self *Service) ResetPassword(ctx context.Context, entityDto *models.ResetPasswordDto) (int, error) {
+	if err := ValidateUserPassword(entityDto.Password); err != nil {
+		return 400, fmt.Errorf("Invalid password. %s", err)
+	}
+
+	hashedPassBytes, err := bcrypt.GenerateFromPassword([]byte(entityDto.Password), 14)
+	if err != nil {
+		return 500, fmt.Errorf("Encountered an error while hashing the password. %s", err)
+	}
+
+	var revokedSessions []string
+	var resetUserId string
+	statusCode := 500
+
+	err = self.passwordResetsRepository.Transaction(ctx, func(repository *PasswordResetsRepository, sessionsRepository *SessionsRepository) error {
+		token, err := repository.Consume(ctx, hashResetToken(entityDto.Token))
+		if err != nil {
+			statusCode = 400
+			return fmt.Errorf("The reset link is invalid or expired.")
+		}
+
+		if err := repository.UpdatePassword(ctx, token.UserID, string(hashedPassBytes)); err != nil {
+			return err
+		}
+		resetUserId = token.UserID
+
+		revokedSessions, err = sessionsRepository.RevokeAll(ctx, token.UserID, "", models.SessionRevokedPasswordReset)
+		return err
+	})
+	if err != nil {
+		return statusCode, err
+	}
+
+	self.sessionCache.forget(revokedSessions...)
+
+	// Whoever can reset the password owns the account, so a lockout no longer protects anything.
+	if user, err := self.findUserByID(ctx, resetUserId); err == nil {
+		self.clearLoginFailures(ctx, user.Email)
+	}
+
+	return 200, nil
+}
+
+func (self *Service) sendPasswordReset(ctx context.Context, email string) error {
+	filter := spec.Filter{
+		Where: spec.WhereClause{
+			And: []spec.WhereCondition{
+				{
+					Column:   "email",
+					Operator: "=",
+					Value:    email,
+				},
+			},
+		},
+	}
+	users, err := self.FindAll(ctx, nil, &filter)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	user := users[0]
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+	ttl := passwordResetTTL()
+
+	err = self.passwordResetsRepository.Transaction(ctx, func(repository *PasswordResetsRepository, _ *SessionsRepository) error {
+		if err := repository.DeleteUnused(ctx, user.ID); err != nil {
+			return err
+		}
+
+		_, err := repository.Create(ctx, models.NewPasswordResetToken(user.ID, hashResetToken(token), ttl))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := passwordResetLink(token)
+	if err != nil {
+		return err
+	}
+
+	return self.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf(
+			"Hi %s,\n\nSomeone asked to reset the password of your account. Choose a new password by opening this link:\n\n%s\n\n"+
+				"The link expires in %s and works once. If it wasn't you, you can ignore this email and your password stays the same.\n",
+			user.FirstName, link, describeDuration(ttl),
+		),
+	})
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// passwordResetLink builds the link sent in reset emails. It points at PASSWORD_RESET_URL, by default
+// the client's /reset-password page, which is expected to post the token and the new password to the API.
+func passwordResetLink(token string) (string, error) {
+	base := utils.GetEnv("PASSWORD_RESET_URL", utils.RequireEnv("CLIENT_URL")+"/reset-password")
+
+	link, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
@@ -0,0 +1,88 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PasswordResetsRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetsRepository(db *gorm.DB) *PasswordResetsRepository {
+	return &PasswordResetsRepository{db: db}
+}
+
+// Transaction runs fn with repositories bound to a single database transaction.
+func (self *PasswordResetsRepository) Transaction(
+	ctx context.Context,
+	fn func(repository *PasswordResetsRepository, sessionsRepository *SessionsRepository) error,
+) error {
+	return self.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&PasswordResetsRepository{db: tx}, &SessionsRepository{db: tx})
+	})
+}
+
+func (self *PasswordResetsRepository) Create(ctx context.Context, entity *models.PasswordResetToken) (*models.PasswordResetToken, error) {
+	if entity == nil {
+		return nil, errors.New("entity cannot be nil")
+	}
+
+	if err := self.db.WithContext(ctx).Create(entity).Error; err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+// DeleteUnused deletes the user's tokens that weren't used yet, so only the latest one works.
+func (self *PasswordResetsRepository) DeleteUnused(ctx context.Context, userId string) error {
+	return self.db.WithContext(ctx).
+		Where("user_id = ? AND used_at IS NULL", userId).
+		Delete(&models.PasswordResetToken{}).Error
+}
+
+// Consume marks the unused, unexpired token with the given hash as used and returns it. Concurrent
+// attempts with the same token can't both succeed.
+func (self *PasswordResetsRepository) Consume(ctx context.Context, tokenHash string) (*models.PasswordResetToken, error) {
+	var consumed []models.PasswordResetToken
+	now := time.Now().UTC()
+
+	err := self.db.WithContext(ctx).
+		Model(&consumed).
+		Clauses(clause.Returning{}).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+		Update("used_at", now).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(consumed) == 0 {
+		return nil, errors.New("entity not found")
+	}
+
+	return &consumed[0], nil
+}
+
+// UpdatePassword replaces the password hash of the user.
+func (self *PasswordResetsRepository) UpdatePassword(ctx context.Context, userId string, passwordHash string) error {
+	result := self.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", userId).
+		Updates(map[string]any{
+			"password":   passwordHash,
+			"updated_at": time.Now().UTC(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
@@ -48,7 +48,7 @@ func (self *Repository) FindByID(ctx context.Context, id string) (*models.User,
 func (self *Repository) FindAll(ctx context.Context, queryOptions *spec.QueryOptions, filter *spec.Filter) ([]models.User, error) {
 	var entities []models.User
 
-	tx := self.db.WithContext(ctx)
+	tx := self.db.WithContext(ctx).Preload("Role")
 	tx = spec.ApplyPagination(tx, queryOptions)
 	tx, err := spec.ApplyFilters(tx, filter, models.User{})
 	if err != nil {
@@ -2,22 +2,45 @@ package users
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/okira-e/go-as-your-backend/app/models"
 )
 
 func SetupRoutes(api fiber.Router, handler *Handler, usersService *Service) {
 	api = api.Group("/users")
 
 	api.Post("/login", handler.Login)
+	api.Post("/login/mfa", handler.LoginMFA)
+	api.Post("/login/unlock", handler.UnlockAccount)
 	api.Post("/register", handler.Register)
 	api.Post("/refresh", handler.RefreshToken)
 	api.Post("/logout", handler.Logout)
 	api.Get("/validate-token", handler.ValidateToken)
+	api.Post("/verify-email", handler.VerifyEmail)
+	api.Post("/verify-email/resend", AuthMiddleware(usersService), handler.ResendVerificationEmail)
+	api.Post("/password/forgot", handler.ForgotPassword)
+	api.Post("/password/reset", handler.ResetPassword)
+	api.Get("/oidc/providers", handler.OIDCProviders)
+	api.Get("/oidc/:provider/authorize", handler.AuthorizeOIDC)
+	api.Get("/oidc/:provider/callback", handler.OIDCCallback)
 
 	api.Get("/me", AuthMiddleware(usersService), handler.Me)
+	api.Get("/me/sessions", AuthMiddleware(usersService), handler.FindSessions)
+	api.Delete("/me/sessions", AuthMiddleware(usersService), handler.RevokeOtherSessions)
+	api.Delete("/me/sessions/:id", AuthMiddleware(usersService), handler.RevokeSession)
+	api.Get("/me/mfa", AuthMiddleware(usersService), handler.MFAStatus)
+	api.Post("/me/mfa/enroll", AuthMiddleware(usersService), handler.EnrollMFA)
+	api.Post("/me/mfa/confirm", AuthMiddleware(usersService), handler.ConfirmMFA)
+	api.Post("/me/mfa/recovery-codes", AuthMiddleware(usersService), handler.RegenerateRecoveryCodes)
+	api.Delete("/me/mfa", AuthMiddleware(usersService), handler.DisableMFA)
+	api.Get("/me/identities", AuthMiddleware(usersService), handler.FindIdentities)
 	api.Get("/", AuthMiddleware(usersService), handler.FindAll)
 	// @TODO: Know how to secure this as it now doxes user info w/out auth
 	api.Get("/contact-info/:id", handler.GetContactInfo)
 	api.Get("/count", AuthMiddleware(usersService), handler.GetCount)
 
+	api.Get("/:id/sessions", AuthMiddleware(usersService), RoleMiddleware(models.RoleAdmin), handler.FindUserSessions)
+	api.Delete("/:id/sessions", AuthMiddleware(usersService), RoleMiddleware(models.RoleAdmin), handler.RevokeUserSessions)
+	api.Delete("/:id/sessions/:sessionId", AuthMiddleware(usersService), RoleMiddleware(models.RoleAdmin), handler.RevokeUserSession)
+
 	// users.Post("/", handler.CreateUser)
 }
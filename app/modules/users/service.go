@@ -11,18 +11,55 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/okira-e/go-as-your-backend/app/audit"
+	"github.com/okira-e/go-as-your-backend/app/mailer"
 	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/oidc"
+	"github.com/okira-e/go-as-your-backend/app/signing"
 	"github.com/okira-e/go-as-your-backend/app/spec"
 	"github.com/okira-e/go-as-your-backend/app/utils"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Service struct {
-	repository spec.Repository[models.User]
+	repository               spec.Repository[models.User]
+	sessionsRepository       *SessionsRepository
+	passwordResetsRepository *PasswordResetsRepository
+	loginThrottlesRepository *LoginThrottlesRepository
+	mfaRepository            *MFARepository
+	identitiesRepository     *IdentitiesRepository
+	oidcProviders            *oidc.Registry
+	signingKeys              *signing.KeySet
+	auditRecorder            audit.Recorder
+	sessionCache             *sessionCache
+	mailer                   mailer.Mailer
 }
 
-func NewService(repository spec.Repository[models.User]) *Service {
-	return &Service{repository: repository}
+func NewService(
+	repository spec.Repository[models.User],
+	sessionsRepository *SessionsRepository,
+	passwordResetsRepository *PasswordResetsRepository,
+	loginThrottlesRepository *LoginThrottlesRepository,
+	mfaRepository *MFARepository,
+	identitiesRepository *IdentitiesRepository,
+	oidcProviders *oidc.Registry,
+	signingKeys *signing.KeySet,
+	auditRecorder audit.Recorder,
+	mailer mailer.Mailer,
+) *Service {
+	return &Service{
+		repository:               repository,
+		sessionsRepository:       sessionsRepository,
+		passwordResetsRepository: passwordResetsRepository,
+		loginThrottlesRepository: loginThrottlesRepository,
+		mfaRepository:            mfaRepository,
+		identitiesRepository:     identitiesRepository,
+		oidcProviders:            oidcProviders,
+		signingKeys:              signingKeys,
+		auditRecorder:            auditRecorder,
+		sessionCache:             newSessionCache(sessionCheckInterval()),
+		mailer:                   mailer,
+	}
 }
 
 func (self *Service) FindAll(ctx context.Context, queryOptions *spec.QueryOptions, filter *spec.Filter) ([]models.User, error) {
@@ -56,7 +93,7 @@ func (self *Service) GetContactInfo(ctx context.Context, userId string) (models.
 	}
 	
 	info := models.UserContact{
-		Phone: entities[0].Phone,
+		Phone: entities[0].PhoneNumber(),
 	}
 
 	return info, nil
@@ -145,123 +182,127 @@ func ValidateUserPassword(password string) error {
 	return nil
 }
 
-func (self *Service) Login(email string, pass string) (string, models.User, int, error) {
-	user, err := self.ValidateSystemUserCredentials(email, pass)
-	if err != nil {
-		return "", models.User{}, 400, fmt.Errorf("User credentials were invalid.")
-	}
-
-	refreshToken, err := self.GenerateRefreshToken(user.ID)
-	if err != nil {
-		return "", models.User{}, 500, fmt.Errorf("Error while generating a refresh token. %s\n", err.Error())
-	}
-
-	return refreshToken, user, 200, nil
+// LoginResult is the outcome of a login with the right password. Either the tokens of the new session
+// are set or, when the user has two-factor authentication, only MFAToken, which CompleteMFALogin
+// exchanges for the tokens along with a code.
+type LoginResult struct {
+	User         models.User
+	AccessToken  string
+	RefreshToken string
+	MFAToken     string
 }
 
-func (self *Service) GenerateAccessToken(userId string, email string, roleName string) (string, error) {
-	accessTokenExpiryStr := utils.RequireEnv("ACCESS_TOKEN_EXPIRY")
-	accessTokenExpiry, err := strconv.Atoi(accessTokenExpiryStr)
+// Login checks the credentials and opens a session for the client. It returns an access token and the
+// first refresh token of the session, or an MFA challenge if the user has two-factor authentication.
+//
+// Failed logins are throttled per account and per IP address. A throttled IP address gets a 429, but a
+// throttled or locked account answers exactly like wrong credentials, so guessing can't tell them apart.
+func (self *Service) Login(email string, pass string, client models.SessionClient) (*LoginResult, int, error) {
+	ctx := context.Background()
+
+	accountBlocked, ipBlocked, err := self.loginThrottled(ctx, email, client.IPAddress)
 	if err != nil {
-		log.Fatalf("Invalid ACCESS_TOKEN_EXPIRY value: %s\n", err.Error())
+		return nil, 500, fmt.Errorf("Error while checking failed logins. %s\n", err.Error())
 	}
-
-	claims := jwt.MapClaims{
-		"userId":   userId,
-		"email":    email,
-		"roleName": roleName,
-		"sub":      email,                   // Subject claim (typically user ID)
-		"iss":      "go-as-your-backend",                 // Issuer claim
-		"aud":      "https://api.go-as-your-backend.com", // Audience claim
-		"exp":      time.Now().Add(time.Duration(accessTokenExpiry) * time.Second).Unix(),
-		"iat":      time.Now().Unix(), // Issued At
-		"nbf":      time.Now().Unix(), // Not Before
+	if ipBlocked {
+		return nil, 429, fmt.Errorf("Too many failed logins. Try again later.")
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	jwtSecret := utils.RequireEnv("JWT_SECRET")
+	// The password is checked even for a blocked account so it takes as long to answer.
+	user, err := self.ValidateSystemUserCredentials(email, pass)
+	if err != nil || accountBlocked {
+		reason := "credentials"
+		if accountBlocked {
+			reason = "throttled"
+		}
+		self.recordLoginFailure(ctx, email, client, accountBlocked, reason)
 
-	return token.SignedString([]byte(jwtSecret))
-}
+		return nil, 400, fmt.Errorf("User credentials were invalid.")
+	}
 
-func (self *Service) GenerateRefreshToken(userId string) (string, error) {
-	refreshTokenExpiryStr := utils.RequireEnv("REFRESH_TOKEN_EXPIRY")
-	refreshTokenExpiry, err := strconv.Atoi(refreshTokenExpiryStr)
+	mfaEnabled, err := self.mfaRepository.IsEnabled(ctx, user.ID)
 	if err != nil {
-		log.Fatalf("Invalid REFRESH_TOKEN_EXPIRY value: %s\n", err.Error())
+		return nil, 500, fmt.Errorf("Error while checking two-factor authentication. %s\n", err.Error())
 	}
+	if mfaEnabled {
+		mfaToken, err := self.generateMFAChallengeToken(user)
+		if err != nil {
+			return nil, 500, fmt.Errorf("Error while generating an MFA token. %s\n", err.Error())
+		}
 
-	claims := jwt.MapClaims{
-		"sub": userId,
-		"exp": time.Now().Add(time.Duration(refreshTokenExpiry) * time.Second).Unix(),
+		return &LoginResult{User: user, MFAToken: mfaToken}, 200, nil
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	jwtSecret := utils.RequireEnv("JWT_SECRET")
+	self.clearLoginFailures(ctx, email)
 
-	return token.SignedString([]byte(jwtSecret))
+	return self.openSession(ctx, user, client, false)
 }
 
-// validateToken validates a token and returns claims.
-func validateToken(tokenString string, secret []byte) (jwt.MapClaims, error) {
-	prefix := "Bearer "
-	tokenString = strings.TrimPrefix(tokenString, prefix)
+// openSession opens a session for the user and issues its tokens.
+func (self *Service) openSession(ctx context.Context, user models.User, client models.SessionClient, mfa bool) (*LoginResult, int, error) {
+	newSession := models.NewSession(user.ID, client, refreshTokenTTL())
+	newSession.MFA = mfa
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method.")
-		}
-		return secret, nil
-	})
+	session, err := self.sessionsRepository.Create(ctx, newSession)
 	if err != nil {
-		return nil, err
+		return nil, 500, fmt.Errorf("Error while opening a session. %s\n", err.Error())
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
+	refreshToken, err := self.GenerateRefreshToken(session)
+	if err != nil {
+		return nil, 500, fmt.Errorf("Error while generating a refresh token. %s\n", err.Error())
 	}
 
-	return nil, fmt.Errorf("Invalid token.")
-}
+	accessToken, err := self.GenerateAccessToken(user, session)
+	if err != nil {
+		return nil, 500, fmt.Errorf("Error while generating an access token. %s\n", err.Error())
+	}
 
-// Refresh generates a new access token using the refresh token.
-func (self *Service) Refresh(refreshToken string) (string, int, error) {
-	jwtSecret := utils.RequireEnv("JWT_SECRET")
+	return &LoginResult{User: user, AccessToken: accessToken, RefreshToken: refreshToken}, 200, nil
+}
 
-	claims, err := validateToken(refreshToken, []byte(jwtSecret))
+func (self *Service) GenerateAccessToken(user models.User, session *models.Session) (string, error) {
+	accessTokenExpiryStr := utils.RequireEnv("ACCESS_TOKEN_EXPIRY")
+	accessTokenExpiry, err := strconv.Atoi(accessTokenExpiryStr)
 	if err != nil {
-		return "", 401, fmt.Errorf("Invalid token. %s\n", err.Error())
+		log.Fatalf("Invalid ACCESS_TOKEN_EXPIRY value: %s\n", err.Error())
 	}
 
-	userId, _ := claims["sub"].(string)
-	if userId == "" {
-		return "", 401, fmt.Errorf("Invalid refresh token: missing subject")
-	}
+	expiresAt := time.Now().Add(time.Duration(accessTokenExpiry) * time.Second)
 
-	// Fetch the latest user info from DB
-	filter := spec.Filter{
-		Where: spec.WhereClause{
-			And: []spec.WhereCondition{
-				{
-					Column:   "id",
-					Operator: "=",
-					Value:    userId,
-				},
-			},
-		},
-	}
-	results, err := self.FindAll(context.Background(), nil, &filter)
-	if err != nil || len(results) == 0 {
-		return "", 500, fmt.Errorf("User not found.")
-	}
-	user := results[0]
+	return self.signingKeys.Sign(&accessClaims{
+		tokenClaims:   newTokenClaims(tokenTypeAccess, user.ID, "", expiresAt),
+		UserID:        user.ID,
+		Email:         user.Email,
+		RoleName:      user.Role.Name,
+		SessionID:     session.ID,
+		EmailVerified: user.IsEmailVerified(),
+		MFA:           session.MFA,
+	})
+}
 
-	accessToken, err := self.GenerateAccessToken(user.ID, user.Email, user.Role.Name)
-	if err != nil {
-		return "", 500, fmt.Errorf("Error while generating an access token. %s\n", err.Error())
-	}
+// GenerateRefreshToken issues the current refresh token of the session. It expires with the session.
+func (self *Service) GenerateRefreshToken(session *models.Session) (string, error) {
+	return self.signingKeys.Sign(&refreshClaims{
+		tokenClaims: newTokenClaims(tokenTypeRefresh, session.UserID, session.TokenID, session.ExpiresAt),
+		SessionID:   session.ID,
+	})
+}
+
+// parseToken verifies a token we signed and decodes it into claims, which check the token is of their
+// type. Tokens must name us as issuer and audience, and have an expiry.
+func (self *Service) parseToken(tokenString string, claims jwt.Claims) error {
+	prefix := "Bearer "
+	tokenString = strings.TrimPrefix(tokenString, prefix)
 
-	return accessToken, 200, nil
+	return self.signingKeys.Parse(
+		tokenString,
+		claims,
+		jwt.WithIssuer(tokenIssuer),
+		jwt.WithAudience(tokenAudience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
 }
 
 // ValidateSystemUserCredentials validates the username and password of a user.
@@ -284,6 +325,7 @@ func (self *Service) ValidateSystemUserCredentials(email string, password string
 	}
 
 	if len(users) == 0 {
+		bcrypt.CompareHashAndPassword([]byte(timingHash), []byte(password))
 		return models.User{}, fmt.Errorf("User not found.")
 	}
 
@@ -296,46 +338,3 @@ func (self *Service) ValidateSystemUserCredentials(email string, password string
 
 	return user, nil
 }
-
-func (self *Service) ValidateUserFromRequest(requestHeaders map[string][]string) (models.User, int, error) {
-	jwtSecret := utils.RequireEnv("JWT_SECRET")
-
-	authorizationValues := requestHeaders["Authorization"]
-	if len(authorizationValues) == 0 {
-		return models.User{}, 401, fmt.Errorf("No token was found in the headers.")
-	}
-
-	token := authorizationValues[0]
-
-	claims, err := validateToken(token, []byte(jwtSecret))
-	if err != nil {
-		return models.User{}, 401, fmt.Errorf("Invalid token. %s\n", err.Error())
-	}
-
-	email := claims["email"].(string)
-	if email == "" {
-		return models.User{}, 401, fmt.Errorf("\"email\" claim is missing in the token.")
-	}
-
-	filter := spec.Filter{
-		Where: spec.WhereClause{
-			And: []spec.WhereCondition{
-				{
-					Column:   "email",
-					Operator: "=",
-					Value:    email,
-				},
-			},
-		},
-	}
-	users, err := self.FindAll(context.Background(), nil, &filter)
-	if err != nil {
-		return models.User{}, 500, fmt.Errorf("Error fetching user. %s", err)
-	}
-
-	if len(users) < 1 {
-		return models.User{}, 401, fmt.Errorf("User doesn't exist.")
-	}
-
-	return users[0], 0, nil
-}
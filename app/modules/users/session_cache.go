@@ -0,0 +1,69 @@
+package users
+
+import (
+	"sync"
+	"time"
+)
+
+type sessionCacheEntry struct {
+	active    bool
+	checkedAt time.Time
+}
+
+// sessionCache remembers for a while whether sessions are active, so authenticating a request doesn't
+// cost a query each time. A session revoked elsewhere is noticed once its entry expires, and sessions
+// revoked through this process are forgotten right away.
+type sessionCache struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	entries   map[string]sessionCacheEntry
+	lastPrune time.Time
+}
+
+func newSessionCache(ttl time.Duration) *sessionCache {
+	return &sessionCache{
+		ttl:       ttl,
+		entries:   map[string]sessionCacheEntry{},
+		lastPrune: time.Now(),
+	}
+}
+
+// get returns whether the session was active when last checked, and false for ok when it wasn't
+// checked recently enough.
+func (self *sessionCache) get(sessionId string) (active bool, ok bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	entry, ok := self.entries[sessionId]
+	if !ok || time.Since(entry.checkedAt) >= self.ttl {
+		return false, false
+	}
+
+	return entry.active, true
+}
+
+func (self *sessionCache) set(sessionId string, active bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	now := time.Now()
+	self.entries[sessionId] = sessionCacheEntry{active: active, checkedAt: now}
+
+	if now.Sub(self.lastPrune) >= self.ttl {
+		for id, entry := range self.entries {
+			if now.Sub(entry.checkedAt) >= self.ttl {
+				delete(self.entries, id)
+			}
+		}
+		self.lastPrune = now
+	}
+}
+
+func (self *sessionCache) forget(sessionIds ...string) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	for _, sessionId := range sessionIds {
+		delete(self.entries, sessionId)
+	}
+}
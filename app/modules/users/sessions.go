@@ -0,0 +1,280 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/spec"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+// refreshReuseGrace is how long the refresh token a session was just rotated from is still accepted.
+// Browsers often fire several requests at once when the access token expires, and all but the first
+// would otherwise present an already rotated token and revoke the session.
+const refreshReuseGrace = 10 * time.Second
+
+// refreshTokenTTL is how long a session lasts without being refreshed.
+func refreshTokenTTL() time.Duration {
+	refreshTokenExpiry, err := strconv.Atoi(utils.RequireEnv("REFRESH_TOKEN_EXPIRY"))
+	if err != nil {
+		log.Fatalf("Invalid REFRESH_TOKEN_EXPIRY value: %s\n", err.Error())
+	}
+
+	return time.Duration(refreshTokenExpiry) * time.Second
+}
+
+// sessionCheckInterval is how long AuthMiddleware trusts that a session is active before checking it
+// again. It never exceeds the lifetime of an access token, so a revoked session is rejected within one
+// access token lifetime on every instance.
+func sessionCheckInterval() time.Duration {
+	interval := utils.GetEnvInt("SESSION_CHECK_INTERVAL", 30)
+
+	accessTokenExpiry, err := strconv.Atoi(utils.RequireEnv("ACCESS_TOKEN_EXPIRY"))
+	if err == nil && accessTokenExpiry < interval {
+		interval = accessTokenExpiry
+	}
+
+	return time.Duration(interval) * time.Second
+}
+
+// Refresh rotates the refresh token of a session and returns a new access token and refresh token.
+// Presenting a refresh token that was already rotated means it was stolen or leaked, so the whole
+// session is revoked, locking out both the thief and the legitimate client until they log in again.
+func (self *Service) Refresh(refreshToken string) (string, string, int, error) {
+	ctx := context.Background()
+
+	var claims refreshClaims
+	err := self.parseToken(refreshToken, &claims)
+	if err != nil {
+		return "", "", 401, fmt.Errorf("Invalid refresh token. %s\n", err.Error())
+	}
+
+	sessionId, tokenId := claims.SessionID, claims.ID
+
+	var session *models.Session
+	reused := false
+	statusCode := 500
+
+	err = self.sessionsRepository.Transaction(ctx, func(repository *SessionsRepository) error {
+		session, err = repository.LockByID(ctx, sessionId)
+		if err != nil {
+			statusCode = 401
+			return fmt.Errorf("Session not found.")
+		}
+
+		if !session.IsActive() {
+			statusCode = 401
+			return fmt.Errorf("Session expired. Log in again.")
+		}
+
+		switch {
+		case tokenId == session.TokenID:
+			session.Rotate(refreshTokenTTL())
+			return repository.Update(ctx, session)
+
+		case session.PreviousTokenID != nil && tokenId == *session.PreviousTokenID &&
+			time.Since(*session.RotatedAt) < refreshReuseGrace:
+			// A concurrent request already rotated the token. Hand out the current one again.
+			return nil
+
+		default:
+			reused = true
+			_, err := repository.Revoke(ctx, session.ID, models.SessionRevokedReuse)
+			return err
+		}
+	})
+	if err != nil {
+		return "", "", statusCode, err
+	}
+
+	if reused {
+		Log(SeverityWarn, "Refresh: rotated refresh token reused, session revoked", map[string]any{
+			"userId":    session.UserID,
+			"sessionId": session.ID,
+		})
+		self.sessionCache.forget(session.ID)
+		return "", "", 401, fmt.Errorf("Session expired. Log in again.")
+	}
+
+	self.sessionCache.set(session.ID, true)
+
+	user, err := self.findUserByID(ctx, session.UserID)
+	if err != nil {
+		return "", "", 500, fmt.Errorf("User not found.")
+	}
+
+	newRefreshToken, err := self.GenerateRefreshToken(session)
+	if err != nil {
+		return "", "", 500, fmt.Errorf("Error while generating a refresh token. %s\n", err.Error())
+	}
+
+	accessToken, err := self.GenerateAccessToken(user, session)
+	if err != nil {
+		return "", "", 500, fmt.Errorf("Error while generating an access token. %s\n", err.Error())
+	}
+
+	return accessToken, newRefreshToken, 200, nil
+}
+
+// Logout revokes the session so its refresh token can't be used anymore.
+func (self *Service) Logout(ctx context.Context, sessionId string) error {
+	_, err := self.sessionsRepository.Revoke(ctx, sessionId, models.SessionRevokedLogout)
+	self.sessionCache.forget(sessionId)
+
+	return err
+}
+
+// IsSessionActive reports whether the session is neither revoked nor expired. The answer is cached for
+// up to the session check interval, and checking the database also records the session as seen.
+func (self *Service) IsSessionActive(ctx context.Context, sessionId string) (bool, error) {
+	if active, ok := self.sessionCache.get(sessionId); ok {
+		return active, nil
+	}
+
+	active, err := self.sessionsRepository.Touch(ctx, sessionId)
+	if err != nil {
+		return false, err
+	}
+
+	self.sessionCache.set(sessionId, active)
+
+	return active, nil
+}
+
+// FindSessions lists the active sessions of the user, marking the one of the request as current.
+func (self *Service) FindSessions(ctx context.Context, user models.JwtUser) ([]*models.SessionDto, error) {
+	return self.findSessions(ctx, user.UserID, user.SessionID)
+}
+
+// FindUserSessions lists the active sessions of any user, for admins.
+func (self *Service) FindUserSessions(ctx context.Context, userId string) ([]*models.SessionDto, error) {
+	return self.findSessions(ctx, userId, "")
+}
+
+// RevokeSession ends one of the user's sessions, e.g. on a lost device.
+func (self *Service) RevokeSession(ctx context.Context, sessionId string, user models.JwtUser) (int, error) {
+	return self.revokeSession(ctx, user.UserID, sessionId, models.SessionRevokedByUser)
+}
+
+// RevokeOtherSessions ends every session of the user but the one of the request, and returns how many
+// it ended.
+func (self *Service) RevokeOtherSessions(ctx context.Context, user models.JwtUser) (int, error) {
+	ids, err := self.sessionsRepository.RevokeAll(ctx, user.UserID, user.SessionID, models.SessionRevokedByUser)
+	if err != nil {
+		return 0, err
+	}
+
+	self.sessionCache.forget(ids...)
+
+	return len(ids), nil
+}
+
+// RevokeUserSession ends a session of any user, for admins.
+func (self *Service) RevokeUserSession(ctx context.Context, userId string, sessionId string) (int, error) {
+	return self.revokeSession(ctx, userId, sessionId, models.SessionRevokedByAdmin)
+}
+
+// RevokeUserSessions ends every session of any user, for admins, and returns how many it ended.
+func (self *Service) RevokeUserSessions(ctx context.Context, userId string) (int, error) {
+	ids, err := self.sessionsRepository.RevokeAll(ctx, userId, "", models.SessionRevokedByAdmin)
+	if err != nil {
+		return 0, err
+	}
+
+	self.sessionCache.forget(ids...)
+
+	return len(ids), nil
+}
+
+func (self *Service) findSessions(ctx context.Context, userId string, currentSessionId string) ([]*models.SessionDto, error) {
+	entities, err := self.sessionsRepository.FindActive(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	entitiesDto := make([]*models.SessionDto, len(entities))
+	for i, entity := range entities {
+		entitiesDto[i] = entity.ToDto(currentSessionId)
+
+		userAgent := ""
+		if entity.UserAgent != nil {
+			userAgent = *entity.UserAgent
+		}
+		entitiesDto[i].Device = describeDevice(userAgent)
+	}
+
+	return entitiesDto, nil
+}
+
+func (self *Service) revokeSession(
+	ctx context.Context,
+	userId string,
+	sessionId string,
+	reason models.SessionRevocationReason,
+) (int, error) {
+	session, err := self.sessionsRepository.FindByID(ctx, sessionId)
+	if err != nil || session.UserID != userId || !session.IsActive() {
+		return 404, fmt.Errorf("Session with ID %s not found.", sessionId)
+	}
+
+	if _, err := self.sessionsRepository.Revoke(ctx, sessionId, reason); err != nil {
+		return 500, err
+	}
+
+	self.sessionCache.forget(sessionId)
+
+	return 200, nil
+}
+
+func (self *Service) findUserByID(ctx context.Context, id string) (models.User, error) {
+	filter := spec.Filter{
+		Where: spec.WhereClause{
+			And: []spec.WhereCondition{
+				{
+					Column:   "id",
+					Operator: "=",
+					Value:    id,
+				},
+			},
+		},
+	}
+
+	results, err := self.FindAll(ctx, nil, &filter)
+	if err != nil {
+		return models.User{}, err
+	}
+	if len(results) == 0 {
+		return models.User{}, fmt.Errorf("User not found.")
+	}
+
+	return results[0], nil
+}
+
+func (self *Service) findUserByEmail(ctx context.Context, email string) (models.User, error) {
+	filter := spec.Filter{
+		Where: spec.WhereClause{
+			And: []spec.WhereCondition{
+				{
+					Column:   "email",
+					Operator: "=",
+					Value:    email,
+				},
+			},
+		},
+	}
+
+	results, err := self.FindAll(ctx, nil, &filter)
+	if err != nil {
+		return models.User{}, err
+	}
+	if len(results) == 0 {
+		return models.User{}, fmt.Errorf("User not found.")
+	}
+
+	return results[0], nil
+}
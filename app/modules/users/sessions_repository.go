@@ -0,0 +1,178 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SessionsRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionsRepository(db *gorm.DB) *SessionsRepository {
+	return &SessionsRepository{db: db}
+}
+
+// Transaction runs fn with a repository bound to a single database transaction.
+func (self *SessionsRepository) Transaction(ctx context.Context, fn func(repository *SessionsRepository) error) error {
+	return self.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&SessionsRepository{db: tx})
+	})
+}
+
+func (self *SessionsRepository) Create(ctx context.Context, entity *models.Session) (*models.Session, error) {
+	if entity == nil {
+		return nil, errors.New("entity cannot be nil")
+	}
+
+	if err := self.db.WithContext(ctx).Create(entity).Error; err != nil {
+		return nil, err
+	}
+
+	return entity, nil
+}
+
+func (self *SessionsRepository) FindByID(ctx context.Context, id string) (*models.Session, error) {
+	var entity models.Session
+
+	err := self.db.WithContext(ctx).Where("id = ?", id).First(&entity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+// FindActive lists the sessions of the user that are neither revoked nor expired, most recently seen
+// first.
+func (self *SessionsRepository) FindActive(ctx context.Context, userId string) ([]models.Session, error) {
+	var entities []models.Session
+
+	err := self.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, time.Now().UTC()).
+		Order("last_seen_at DESC").
+		Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// Touch records that the session was just used and reports whether it is still active.
+func (self *SessionsRepository) Touch(ctx context.Context, id string) (bool, error) {
+	now := time.Now().UTC()
+
+	result := self.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", id, now).
+		Update("last_seen_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// MarkMFA records that the session passed two-factor authentication.
+func (self *SessionsRepository) MarkMFA(ctx context.Context, id string) error {
+	return self.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ?", id).
+		Update("mfa", true).Error
+}
+
+// LockByID returns the session and locks its row until the end of the transaction, which serializes
+// refreshes of the same session.
+func (self *SessionsRepository) LockByID(ctx context.Context, id string) (*models.Session, error) {
+	var entity models.Session
+
+	err := self.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).
+		First(&entity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("entity not found")
+		}
+
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+func (self *SessionsRepository) Update(ctx context.Context, entity *models.Session) error {
+	if entity == nil {
+		return errors.New("entity cannot be nil")
+	}
+
+	result := self.db.WithContext(ctx).Model(entity).Select("*").Omit("ID", "UserID", "CreatedAt").Updates(entity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("entity not found")
+	}
+
+	return nil
+}
+
+// Revoke revokes the session unless it already is, and reports whether it did.
+func (self *SessionsRepository) Revoke(ctx context.Context, id string, reason models.SessionRevocationReason) (bool, error) {
+	result := self.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]any{
+			"revoked_at":     time.Now().UTC(),
+			"revoked_reason": reason,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeAll revokes the active sessions of the user except exceptId, which may be empty, and returns the
+// IDs of the sessions it revoked.
+func (self *SessionsRepository) RevokeAll(
+	ctx context.Context,
+	userId string,
+	exceptId string,
+	reason models.SessionRevocationReason,
+) ([]string, error) {
+	var revoked []models.Session
+
+	tx := self.db.WithContext(ctx).
+		Model(&revoked).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("user_id = ? AND revoked_at IS NULL", userId)
+	if exceptId != "" {
+		tx = tx.Where("id <> ?", exceptId)
+	}
+
+	err := tx.Updates(map[string]any{
+		"revoked_at":     time.Now().UTC(),
+		"revoked_reason": reason,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(revoked))
+	for i, session := range revoked {
+		ids[i] = session.ID
+	}
+
+	return ids, nil
+}
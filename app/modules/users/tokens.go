@@ -0,0 +1,172 @@
+package users
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+// TokensMediaType is the media type a client puts in its Accept header to receive tokens in the
+// response body instead of cookies. The `token_delivery=body` query parameter does the same.
+const TokensMediaType = "application/vnd.go-as-your-backend.tokens+json"
+
+// bearerToken returns the token of an `Authorization: Bearer <token>` header, or "" when the header
+// is missing. ok is false when the header is present but isn't a bearer token.
+func bearerToken(ctx *fiber.Ctx) (token string, ok bool) {
+	authorization := strings.TrimSpace(ctx.Get(fiber.HeaderAuthorization))
+	if authorization == "" {
+		return "", true
+	}
+
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+
+	return token, token != ""
+}
+
+// hasCredentials reports whether the request carries a token in any of the places authenticate reads.
+func hasCredentials(ctx *fiber.Ctx) bool {
+	return ctx.Get(fiber.HeaderAuthorization) != "" ||
+		ctx.Cookies("access_token") != "" ||
+		ctx.Cookies("refresh_token") != ""
+}
+
+// tokensInBody reports whether the client asked for tokens in the response body, which is what
+// non-browser clients that can't keep cookies need.
+func tokensInBody(ctx *fiber.Ctx) bool {
+	if ctx.Query("token_delivery") == "body" {
+		return true
+	}
+
+	return strings.Contains(ctx.Get(fiber.HeaderAccept), TokensMediaType)
+}
+
+// tokensDto returns the tokens in the shape sent to clients that asked for them in the body. user is
+// left out when nil.
+func tokensDto(accessToken string, refreshToken string, user *models.UserDto) *models.AuthTokensDto {
+	accessTokenExpiry, _ := strconv.Atoi(utils.RequireEnv("ACCESS_TOKEN_EXPIRY"))
+
+	return &models.AuthTokensDto{
+		User:         user,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    accessTokenExpiry,
+	}
+}
+
+// sessionClient describes the client of the request for the session it opens.
+func sessionClient(ctx *fiber.Ctx) models.SessionClient {
+	return models.SessionClient{
+		UserAgent: ctx.Get(fiber.HeaderUserAgent),
+		IPAddress: ctx.IP(),
+	}
+}
+
+// setAuthCookies stores the tokens in HTTP-only cookies. An empty refreshToken leaves the refresh
+// token cookie untouched.
+func setAuthCookies(ctx *fiber.Ctx, accessToken string, refreshToken string) {
+	domain := utils.RequireEnv("DOMAIN")
+
+	accessTokenExpiryStr := utils.RequireEnv("ACCESS_TOKEN_EXPIRY")
+	accessTokenExpiry, _ := strconv.Atoi(accessTokenExpiryStr)
+
+	sameSite := "Lax"
+	if utils.RequireEnv("ENV") == "prod" {
+		sameSite = "None"
+	}
+
+	if refreshToken != "" {
+		refreshTokenExpiryStr := utils.RequireEnv("REFRESH_TOKEN_EXPIRY")
+		refreshTokenExpiry, _ := strconv.Atoi(refreshTokenExpiryStr)
+
+		ctx.Cookie(&fiber.Cookie{
+			Name:        "refresh_token",
+			Value:       refreshToken,
+			MaxAge:      refreshTokenExpiry,
+			Path:        "/",
+			Domain:      domain,
+			Secure:      utils.RequireEnv("ENV") == "prod",
+			HTTPOnly:    true,
+			SameSite:    sameSite,
+			SessionOnly: false,
+		})
+	}
+
+	ctx.Cookie(&fiber.Cookie{
+		Name:        "access_token",
+		Value:       accessToken,
+		MaxAge:      accessTokenExpiry,
+		Path:        "/",
+		Domain:      domain,
+		Secure:      utils.RequireEnv("ENV") == "prod",
+		HTTPOnly:    true,
+		SameSite:    sameSite,
+		SessionOnly: false,
+	})
+}
+
+// setOIDCStateCookie keeps the state of a sign in with an identity provider until the provider sends
+// the user back. It has to be Lax, not Strict, to be sent along with that cross-site redirect. An empty
+// stateToken clears it.
+func setOIDCStateCookie(ctx *fiber.Ctx, stateToken string) {
+	maxAge := int(oidcStateTTL.Seconds())
+	if stateToken == "" {
+		maxAge = -1
+	}
+
+	ctx.Cookie(&fiber.Cookie{
+		Name:     "oidc_state",
+		Value:    stateToken,
+		MaxAge:   maxAge,
+		Path:     "/",
+		Secure:   utils.RequireEnv("ENV") == "prod",
+		HTTPOnly: true,
+		SameSite: "Lax",
+	})
+}
+
+// respondWithTokens answers a login with the tokens of the new session, in the body or as cookies
+// depending on what the client asked for.
+func respondWithTokens(ctx *fiber.Ctx, statusCode int, msg string, accessToken string, refreshToken string, user *models.UserDto) error {
+	if tokensInBody(ctx) {
+		return utils.Ok(ctx, statusCode, msg, tokensDto(accessToken, refreshToken, user))
+	}
+
+	setAuthCookies(ctx, accessToken, refreshToken)
+
+	return utils.Ok(ctx, statusCode, msg, user)
+}
+
+// parseAccessToken returns the user of an access token. Any other kind of token is rejected.
+func (self *Service) parseAccessToken(tokenString string) (models.JwtUser, error) {
+	var claims accessClaims
+	if err := self.parseToken(tokenString, &claims); err != nil {
+		return models.JwtUser{}, err
+	}
+
+	return claims.user(), nil
+}
+
+// sessionIdFromToken returns the session of a refresh or access token, expired or not, as long as we
+// signed it. It is meant for logging out, where an expired token still names the session to end.
+func (self *Service) sessionIdFromToken(tokenString string) (string, bool) {
+	// Access and refresh tokens both name their session in sid.
+	var claims refreshClaims
+	if err := self.signingKeys.Parse(tokenString, &claims, jwt.WithoutClaimsValidation()); err != nil {
+		return "", false
+	}
+	if claims.Issuer != tokenIssuer || (claims.Type != tokenTypeAccess && claims.Type != tokenTypeRefresh) {
+		return "", false
+	}
+
+	return claims.SessionID, claims.SessionID != ""
+}
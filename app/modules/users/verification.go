@@ -0,0 +1,135 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/okira-e/go-as-your-backend/app/mailer"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+// emailVerificationTTL is how long a verification link stays valid.
+func emailVerificationTTL() time.Duration {
+	return time.Duration(utils.GetEnvInt("EMAIL_VERIFICATION_EXPIRY", 86400)) * time.Second
+}
+
+// SendVerificationEmail emails the user a signed link that verifies their email address. The link only
+// works for the address it was sent to and expires after EMAIL_VERIFICATION_EXPIRY seconds.
+func (self *Service) SendVerificationEmail(ctx context.Context, user *models.User) error {
+	ttl := emailVerificationTTL()
+
+	token, err := self.signingKeys.Sign(&emailVerificationClaims{
+		tokenClaims: newTokenClaims(tokenTypeEmailVerification, user.ID, "", time.Now().Add(ttl)),
+		Email:       user.Email,
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := verificationLink(token)
+	if err != nil {
+		return err
+	}
+
+	return self.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Text: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm your email address by opening this link:\n\n%s\n\n"+
+				"The link expires in %s. If you didn't create an account, you can ignore this email.\n",
+			user.FirstName, link, describeDuration(ttl),
+		),
+	})
+}
+
+// ResendVerificationEmail sends a new verification link to a user who hasn't verified their address yet.
+func (self *Service) ResendVerificationEmail(ctx context.Context, user models.JwtUser) (int, error) {
+	entity, err := self.findUserByID(ctx, user.UserID)
+	if err != nil {
+		return 404, fmt.Errorf("User not found.")
+	}
+
+	if entity.IsEmailVerified() {
+		return 409, fmt.Errorf("Your email address is already verified.")
+	}
+
+	if err := self.SendVerificationEmail(ctx, &entity); err != nil {
+		return 500, fmt.Errorf("Failed to send the verification email. %s", err)
+	}
+
+	return 200, nil
+}
+
+// VerifyEmail marks the email address named by a verification token as verified. Verifying an address
+// twice is not an error.
+func (self *Service) VerifyEmail(ctx context.Context, token string) (*models.UserDto, int, error) {
+	var claims emailVerificationClaims
+	if err := self.parseToken(token, &claims); err != nil {
+		return &models.UserDto{}, 400, fmt.Errorf("The verification link is invalid or expired.")
+	}
+
+	user, err := self.findUserByID(ctx, claims.Subject)
+	if err != nil || user.Email != claims.Email {
+		return &models.UserDto{}, 400, fmt.Errorf("The verification link is invalid or expired.")
+	}
+
+	if user.IsEmailVerified() {
+		return user.ToDto(), 200, nil
+	}
+
+	now := time.Now().UTC()
+	if err := self.repository.Update(ctx, &models.User{ID: user.ID, EmailVerifiedAt: &now}); err != nil {
+		return &models.UserDto{}, 500, err
+	}
+
+	user.EmailVerifiedAt = &now
+
+	return user.ToDto(), 200, nil
+}
+
+// IsEmailVerified reports whether the user verified their email address.
+func (self *Service) IsEmailVerified(ctx context.Context, userId string) (bool, error) {
+	user, err := self.findUserByID(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return user.IsEmailVerified(), nil
+}
+
+// verificationLink builds the link sent in verification emails. It points at EMAIL_VERIFICATION_URL,
+// by default the client's /verify-email page, which is expected to post the token to the API.
+func verificationLink(token string) (string, error) {
+	base := utils.GetEnv("EMAIL_VERIFICATION_URL", utils.RequireEnv("CLIENT_URL")+"/verify-email")
+
+	link, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
+
+// describeDuration writes a duration for people, in whole hours or minutes, e.g. "24 hours".
+func describeDuration(duration time.Duration) string {
+	if duration >= time.Hour {
+		hours := int(duration.Hours())
+		if hours == 1 {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", hours)
+	}
+
+	minutes := int(duration.Minutes())
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
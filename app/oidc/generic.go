@@ -0,0 +1,273 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Generic is an OpenID Connect provider, found through the discovery document of its issuer.
+type Generic struct {
+	config Config
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discoveryDocument
+	keys      *keySet
+}
+
+var _ Provider = (*Generic)(nil)
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+// userClaims are the standard claims we read from ID tokens and the userinfo endpoint.
+type userClaims struct {
+	Subject    string `json:"sub"`
+	Email      string `json:"email"`
+	GivenName  string `json:"given_name"`
+	FamilyName string `json:"family_name"`
+	Name       string `json:"name"`
+	Picture    string `json:"picture"`
+	// EmailVerified is a boolean, though some providers send it as a string.
+	EmailVerified any `json:"email_verified"`
+}
+
+func NewGeneric(config Config) *Generic {
+	config.Issuer = strings.TrimRight(config.Issuer, "/")
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &Generic{config: config, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (self *Generic) Name() string {
+	return self.config.Name
+}
+
+func (self *Generic) DisplayName() string {
+	return self.config.DisplayName
+}
+
+func (self *Generic) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string, loginHint string) (string, error) {
+	discovery, err := self.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", self.config.ClientID)
+	query.Set("redirect_uri", self.config.RedirectURL)
+	query.Set("scope", strings.Join(self.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	if loginHint != "" {
+		query.Set("login_hint", loginHint)
+	}
+
+	return withQuery(discovery.AuthorizationEndpoint, query)
+}
+
+func (self *Generic) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*Identity, error) {
+	discovery, err := self.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := exchangeCode(ctx, self.client, discovery.TokenEndpoint, self.config, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("the provider didn't return an ID token")
+	}
+
+	claims, err := self.verifyIDToken(ctx, token.IDToken, nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	// Some providers keep the ID token small and only tell the email through the userinfo endpoint.
+	if claims.Email == "" && discovery.UserinfoEndpoint != "" && token.AccessToken != "" {
+		var userinfo userClaims
+		if err := getJSON(ctx, self.client, discovery.UserinfoEndpoint, token.AccessToken, &userinfo); err != nil {
+			return nil, fmt.Errorf("fetching the user info: %w", err)
+		}
+		if userinfo.Subject != claims.Subject {
+			return nil, errors.New("the user info is about another user")
+		}
+		claims = &userinfo
+	}
+
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" && lastName == "" {
+		firstName, lastName = splitName(claims.Name)
+	}
+
+	return &Identity{
+		Provider:      self.config.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		FirstName:     firstName,
+		LastName:      lastName,
+		AvatarURL:     claims.Picture,
+	}, nil
+}
+
+// verifyIDToken checks the signature of the ID token against the keys of the issuer, that it was
+// issued by the issuer to us, that it hasn't expired and that it answers our authorization request.
+func (self *Generic) verifyIDToken(ctx context.Context, idToken string, nonce string) (*userClaims, error) {
+	token, err := jwt.Parse(idToken, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return self.keys.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(self.discovery.Issuer),
+		jwt.WithAudience(self.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	mapClaims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("unexpected claims")
+	}
+	if tokenNonce, _ := mapClaims["nonce"].(string); tokenNonce != nonce {
+		return nil, errors.New("nonce mismatch")
+	}
+
+	// Round trip through JSON to read the claims we care about with their types.
+	raw, err := json.Marshal(mapClaims)
+	if err != nil {
+		return nil, err
+	}
+	var claims userClaims
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("missing subject")
+	}
+
+	return &claims, nil
+}
+
+// discover fetches the discovery document of the issuer once. A failure is retried on the next call,
+// so a provider that is down at startup doesn't stay broken.
+func (self *Generic) discover(ctx context.Context) (*discoveryDocument, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if self.discovery != nil {
+		return self.discovery, nil
+	}
+
+	var discovery discoveryDocument
+	if err := getJSON(ctx, self.client, self.config.Issuer+"/.well-known/openid-configuration", "", &discovery); err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", self.config.Issuer, err)
+	}
+	if strings.TrimRight(discovery.Issuer, "/") != self.config.Issuer {
+		return nil, fmt.Errorf("discovering %s: the document is for issuer %s", self.config.Issuer, discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JwksURI == "" {
+		return nil, fmt.Errorf("discovering %s: endpoints are missing", self.config.Issuer)
+	}
+
+	self.discovery = &discovery
+	self.keys = newKeySet(discovery.JwksURI, self.client)
+
+	return self.discovery, nil
+}
+
+// exchangeCode redeems an authorization code at the token endpoint, authenticating with the client
+// secret when there is one.
+func exchangeCode(ctx context.Context, client *http.Client, tokenURL string, config Config, code string, codeVerifier string) (*tokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", config.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	form.Set("client_id", config.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("reading the token response: %w", err)
+	}
+	// Some providers, GitHub among them, report errors with a 200.
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return nil, fmt.Errorf("the token endpoint answered %s: %s %s", resp.Status, token.Error, token.Description)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("the provider didn't return an access token")
+	}
+
+	return &token, nil
+}
+
+func withQuery(endpoint string, query url.Values) (string, error) {
+	link, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	// Keep parameters the provider put in its endpoint.
+	merged := link.Query()
+	for key, values := range query {
+		merged[key] = values
+	}
+	link.RawQuery = merged.Encode()
+
+	return link.String(), nil
+}
+
+// splitName splits a full name into a first name and the rest.
+func splitName(name string) (string, string) {
+	first, last, _ := strings.Cut(strings.TrimSpace(name), " ")
+
+	return first, strings.TrimSpace(last)
+}
@@ -0,0 +1,108 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	gitHubAuthorizeURL = "https://github.com/login/oauth/authorize"
+	gitHubTokenURL     = "https://github.com/login/oauth/access_token"
+	gitHubAPIURL       = "https://api.github.com"
+)
+
+// GitHub signs users in with GitHub, which only speaks OAuth 2.0. The identity comes from its API
+// instead of an ID token.
+type GitHub struct {
+	config Config
+	client *http.Client
+}
+
+var _ Provider = (*GitHub)(nil)
+
+func NewGitHub(config Config) *GitHub {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"read:user", "user:email"}
+	}
+
+	return &GitHub{config: config, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (self *GitHub) Name() string {
+	return self.config.Name
+}
+
+func (self *GitHub) DisplayName() string {
+	return self.config.DisplayName
+}
+
+// AuthCodeURL ignores the nonce, which only applies to ID tokens.
+func (self *GitHub) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string, loginHint string) (string, error) {
+	query := url.Values{}
+	query.Set("client_id", self.config.ClientID)
+	query.Set("redirect_uri", self.config.RedirectURL)
+	query.Set("scope", strings.Join(self.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	if loginHint != "" {
+		query.Set("login", loginHint)
+	}
+
+	return withQuery(gitHubAuthorizeURL, query)
+}
+
+func (self *GitHub) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*Identity, error) {
+	token, err := exchangeCode(ctx, self.client, gitHubTokenURL, self.config, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := getJSON(ctx, self.client, gitHubAPIURL+"/user", token.AccessToken, &user); err != nil {
+		return nil, fmt.Errorf("fetching the user: %w", err)
+	}
+	if user.ID == 0 {
+		return nil, errors.New("the user has no ID")
+	}
+
+	// The public email of the profile isn't necessarily verified, so use the primary verified one.
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, self.client, gitHubAPIURL+"/user/emails", token.AccessToken, &emails); err != nil {
+		return nil, fmt.Errorf("fetching the emails: %w", err)
+	}
+
+	identity := &Identity{
+		Provider:  self.config.Name,
+		Subject:   strconv.FormatInt(user.ID, 10),
+		AvatarURL: user.AvatarURL,
+	}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+		}
+	}
+
+	identity.FirstName, identity.LastName = splitName(user.Name)
+	if identity.FirstName == "" {
+		identity.FirstName = user.Login
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,161 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// keySetRefreshInterval limits how often an unknown key ID makes us fetch the key set again, so
+// tokens with made up key IDs can't make us hammer the provider.
+const keySetRefreshInterval = time.Minute
+
+// keySet caches the signing keys a provider publishes. Providers rotate keys by publishing the new
+// one ahead of time, so the set is fetched again when a token names a key we don't know.
+type keySet struct {
+	url    string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]any
+	fetchedAt time.Time
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func newKeySet(url string, client *http.Client) *keySet {
+	return &keySet{url: url, client: client}
+}
+
+func (self *keySet) key(ctx context.Context, kid string) (any, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	if key, ok := self.keys[kid]; ok {
+		return key, nil
+	}
+
+	if self.keys == nil || time.Since(self.fetchedAt) > keySetRefreshInterval {
+		keys, err := self.fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		self.keys = keys
+		self.fetchedAt = time.Now()
+	}
+
+	if key, ok := self.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (self *keySet) fetch(ctx context.Context) (map[string]any, error) {
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, self.client, self.url, "", &document); err != nil {
+		return nil, fmt.Errorf("fetching the key set: %w", err)
+	}
+
+	keys := map[string]any{}
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip key types we don't support rather than failing on the whole set.
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (self *jsonWebKey) publicKey() (any, error) {
+	switch self.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(self.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(self.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch self.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", self.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(self.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(self.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", self.Kty)
+	}
+}
+
+// getJSON fetches url, with the access token if one is given, and decodes the JSON answer into target.
+func getJSON(ctx context.Context, client *http.Client, url string, accessToken string, target any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
@@ -0,0 +1,251 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// MockServer is an OpenID Connect provider for local development and tests. It signs in whoever is
+// named by the login_hint of the authorization request without asking anything, so a client that
+// follows redirects completes the whole flow on its own. Codes are single use and checked against the
+// PKCE challenge like a real provider would.
+type MockServer struct {
+	issuer string
+	key    *rsa.PrivateKey
+	mux    *http.ServeMux
+
+	// DefaultEmail is who signs in when the authorization request has no login_hint.
+	DefaultEmail string
+	// EmailVerified is what the provider claims about the emails it signs in.
+	EmailVerified bool
+
+	mu     sync.Mutex
+	codes  map[string]mockAuthorization
+	tokens map[string]string
+}
+
+type mockAuthorization struct {
+	clientId      string
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	email         string
+	expiresAt     time.Time
+}
+
+const mockKeyID = "mock"
+
+func NewMockServer(issuer string) (*MockServer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &MockServer{
+		issuer:        strings.TrimRight(issuer, "/"),
+		key:           key,
+		mux:           http.NewServeMux(),
+		DefaultEmail:  "mock.user@example.com",
+		EmailVerified: true,
+		codes:         map[string]mockAuthorization{},
+		tokens:        map[string]string{},
+	}
+
+	server.mux.HandleFunc("GET /.well-known/openid-configuration", server.discovery)
+	server.mux.HandleFunc("GET /authorize", server.authorize)
+	server.mux.HandleFunc("POST /token", server.token)
+	server.mux.HandleFunc("GET /jwks", server.jwks)
+	server.mux.HandleFunc("GET /userinfo", server.userinfo)
+
+	return server, nil
+}
+
+func (self *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	self.mux.ServeHTTP(w, r)
+}
+
+func (self *MockServer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                self.issuer,
+		"authorization_endpoint":                self.issuer + "/authorize",
+		"token_endpoint":                        self.issuer + "/token",
+		"userinfo_endpoint":                     self.issuer + "/userinfo",
+		"jwks_uri":                              self.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (self *MockServer) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+	if query.Get("response_type") != "code" || query.Get("client_id") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge") == "" || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	email := query.Get("login_hint")
+	if email == "" {
+		email = self.DefaultEmail
+	}
+
+	code, err := RandomString()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	self.mu.Lock()
+	self.codes[code] = mockAuthorization{
+		clientId:      query.Get("client_id"),
+		redirectURI:   redirectURI.String(),
+		codeChallenge: query.Get("code_challenge"),
+		nonce:         query.Get("nonce"),
+		email:         email,
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	self.mu.Unlock()
+
+	callback := redirectURI.Query()
+	callback.Set("code", code)
+	callback.Set("state", query.Get("state"))
+	redirectURI.RawQuery = callback.Encode()
+
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (self *MockServer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientId := r.PostForm.Get("client_id")
+	if username, _, ok := r.BasicAuth(); ok {
+		clientId, _ = url.QueryUnescape(username)
+	}
+
+	self.mu.Lock()
+	authorization, ok := self.codes[r.PostForm.Get("code")]
+	delete(self.codes, r.PostForm.Get("code"))
+	self.mu.Unlock()
+
+	if !ok || time.Now().After(authorization.expiresAt) ||
+		authorization.clientId != clientId ||
+		authorization.redirectURI != r.PostForm.Get("redirect_uri") ||
+		authorization.codeChallenge != CodeChallenge(r.PostForm.Get("code_verifier")) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            self.issuer,
+		"sub":            mockSubject(authorization.email),
+		"aud":            authorization.clientId,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"email":          authorization.email,
+		"email_verified": self.EmailVerified,
+		"given_name":     "Mock",
+		"family_name":    "User",
+	}
+	if authorization.nonce != "" {
+		claims["nonce"] = authorization.nonce
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = mockKeyID
+	signedIdToken, err := idToken.SignedString(self.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	accessToken, err := RandomString()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	self.mu.Lock()
+	self.tokens[accessToken] = authorization.email
+	self.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signedIdToken,
+	})
+}
+
+func (self *MockServer) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": mockKeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(self.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(self.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (self *MockServer) userinfo(w http.ResponseWriter, r *http.Request) {
+	accessToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	self.mu.Lock()
+	email, ok := self.tokens[accessToken]
+	self.mu.Unlock()
+
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sub":            mockSubject(email),
+		"email":          email,
+		"email_verified": self.EmailVerified,
+		"given_name":     "Mock",
+		"family_name":    "User",
+	})
+}
+
+// mockSubject derives a stable subject from the email, so signing in again finds the same identity.
+func mockSubject(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+
+	return hex.EncodeToString(sum[:16])
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,147 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+// Identity is the account a user proved they own at a provider.
+type Identity struct {
+	Provider string
+	// Subject is the stable ID of the account at the provider. Emails can change, subjects don't.
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+	AvatarURL     string
+}
+
+// Provider signs users in with the authorization code flow and PKCE.
+type Provider interface {
+	Name() string
+	DisplayName() string
+	// AuthCodeURL returns the page of the provider to send the user to. loginHint is optional.
+	AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string, loginHint string) (string, error)
+	// Exchange trades the code the provider redirected back with for the identity of the user.
+	Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*Identity, error)
+}
+
+// Config is what every provider needs. Endpoints of OpenID Connect providers are discovered from
+// their issuer.
+type Config struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// RedirectURL is our callback the provider sends the user back to. It must be registered at the
+	// provider.
+	RedirectURL string
+}
+
+// Registry holds the configured providers in the order they were listed.
+type Registry struct {
+	providers map[string]Provider
+	names     []string
+}
+
+func NewRegistry(providers ...Provider) *Registry {
+	registry := &Registry{providers: map[string]Provider{}}
+	for _, provider := range providers {
+		registry.providers[provider.Name()] = provider
+		registry.names = append(registry.names, provider.Name())
+	}
+
+	return registry
+}
+
+func (self *Registry) Get(name string) (Provider, bool) {
+	provider, ok := self.providers[name]
+	return provider, ok
+}
+
+func (self *Registry) List() []Provider {
+	providers := make([]Provider, len(self.names))
+	for i, name := range self.names {
+		providers[i] = self.providers[name]
+	}
+
+	return providers
+}
+
+var providerNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// NewFromEnv builds the providers listed in OIDC_PROVIDERS, e.g. "google,github,acme". Each one is
+// configured with OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET and, except for the google and
+// github presets, OIDC_<NAME>_ISSUER. OIDC_<NAME>_SCOPES and OIDC_<NAME>_DISPLAY_NAME are optional.
+// Callbacks are served under callbackBaseURL.
+func NewFromEnv(callbackBaseURL string) *Registry {
+	var providers []Provider
+
+	for _, name := range strings.Split(utils.GetEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !providerNamePattern.MatchString(name) {
+			log.Fatalf("Invalid OIDC provider name %q, expected lowercase letters, digits and dashes", name)
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		config := Config{
+			Name:         name,
+			DisplayName:  utils.GetEnv(prefix+"DISPLAY_NAME", name),
+			ClientID:     utils.RequireEnv(prefix + "CLIENT_ID"),
+			ClientSecret: utils.GetEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  strings.TrimRight(callbackBaseURL, "/") + "/" + name + "/callback",
+		}
+		if scopes := utils.GetEnv(prefix+"SCOPES", ""); scopes != "" {
+			config.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+		}
+
+		switch name {
+		case "github":
+			if config.DisplayName == name {
+				config.DisplayName = "GitHub"
+			}
+			providers = append(providers, NewGitHub(config))
+		case "google":
+			if config.DisplayName == name {
+				config.DisplayName = "Google"
+			}
+			config.Issuer = utils.GetEnv(prefix+"ISSUER", "https://accounts.google.com")
+			providers = append(providers, NewGeneric(config))
+		default:
+			config.Issuer = utils.RequireEnv(prefix + "ISSUER")
+			providers = append(providers, NewGeneric(config))
+		}
+	}
+
+	return NewRegistry(providers...)
+}
+
+// RandomString returns a random URL-safe string, for states, nonces and PKCE verifiers.
+func RandomString() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// CodeChallenge derives the S256 PKCE challenge of a verifier.
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
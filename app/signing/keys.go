@@ -0,0 +1,110 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/okira-e/go-as-your-backend/app/models"
+)
+
+// Algorithms keys can be generated for.
+const (
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+	HS256 = "HS256"
+)
+
+const rsaKeyBits = 2048
+
+// key is a signing key ready to sign or verify tokens.
+type key struct {
+	entity  models.SigningKey
+	method  jwt.SigningMethod
+	private crypto.Signer
+}
+
+func (self *key) public() crypto.PublicKey {
+	return self.private.Public()
+}
+
+// generatePrivateKey returns a new private key for the algorithm in PKCS #8 DER form.
+func generatePrivateKey(algorithm string) ([]byte, error) {
+	var private any
+	var err error
+
+	switch algorithm {
+	case RS256:
+		private, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case EdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return x509.MarshalPKCS8PrivateKey(private)
+}
+
+// parseKey decodes the private key of a stored signing key and checks it fits its algorithm.
+func parseKey(entity models.SigningKey) (*key, error) {
+	private, err := x509.ParsePKCS8PrivateKey(entity.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing key %s: %w", entity.ID, err)
+	}
+
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		if entity.Algorithm == RS256 {
+			return &key{entity: entity, method: jwt.SigningMethodRS256, private: private}, nil
+		}
+	case ed25519.PrivateKey:
+		if entity.Algorithm == EdDSA {
+			return &key{entity: entity, method: jwt.SigningMethodEdDSA, private: private}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("key %s doesn't fit the %s algorithm", entity.ID, entity.Algorithm)
+}
+
+// JSONWebKey is the public part of a signing key, as published in the key set.
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet is the document served at /.well-known/jwks.json.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+func (self *key) jwk() JSONWebKey {
+	jwk := JSONWebKey{Kid: self.entity.ID, Use: "sig", Alg: self.entity.Algorithm}
+
+	switch public := self.public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	return jwk
+}
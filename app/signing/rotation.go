@@ -0,0 +1,119 @@
+package signing
+
+import (
+	"context"
+	"time"
+
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/samborkent/uuidv7"
+	"gorm.io/gorm"
+)
+
+// rotationLock is the key of the advisory lock instances take to rotate, so they agree on the keys.
+const rotationLock = 0x6a776b73
+
+// Rotate deletes the keys that expired and makes sure a key of the configured algorithm is signing. The
+// next key is created PublishAhead before the current one retires, to take over when it does.
+//
+// Switching algorithms retires the current key right away, though it is still accepted for Overlap.
+func (self *KeySet) Rotate(ctx context.Context) error {
+	return self.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", rotationLock).Error; err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+
+		if err := tx.Where("expires_at <= ?", now).Delete(&models.SigningKey{}).Error; err != nil {
+			return err
+		}
+
+		if self.config.Algorithm == HS256 {
+			return nil
+		}
+
+		var entities []models.SigningKey
+		if err := tx.Order("activates_at").Find(&entities).Error; err != nil {
+			return err
+		}
+
+		var current *models.SigningKey
+		var pending []models.SigningKey
+		for i, entity := range entities {
+			switch {
+			case !entity.ActivatesAt.After(now):
+				current = &entities[i]
+			case entity.Algorithm != self.config.Algorithm:
+				// It never signed anything, so it can go.
+				if err := tx.Delete(&entity).Error; err != nil {
+					return err
+				}
+			default:
+				pending = append(pending, entity)
+			}
+		}
+
+		if current != nil && current.Algorithm != self.config.Algorithm && current.RetiresAt.After(now) {
+			current.RetiresAt = now
+			current.ExpiresAt = now.Add(self.config.Overlap)
+			if err := tx.Save(current).Error; err != nil {
+				return err
+			}
+		}
+
+		if current == nil || !current.RetiresAt.After(now) {
+			if len(pending) > 0 {
+				current = &pending[0]
+				pending = pending[1:]
+				current.ActivatesAt = now
+				if err := tx.Save(current).Error; err != nil {
+					return err
+				}
+			} else {
+				var err error
+				if current, err = self.createKey(tx, now); err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(pending) == 0 && !now.Before(current.RetiresAt.Add(-self.config.PublishAhead)) {
+			if _, err := self.createKey(tx, current.RetiresAt); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (self *KeySet) createKey(tx *gorm.DB, activatesAt time.Time) (*models.SigningKey, error) {
+	privateKey, err := generatePrivateKey(self.config.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	retiresAt := activatesAt.Add(self.config.Rotation)
+	entity := &models.SigningKey{
+		ID:          uuidv7.New().String(),
+		Algorithm:   self.config.Algorithm,
+		PrivateKey:  privateKey,
+		CreatedAt:   time.Now().UTC(),
+		ActivatesAt: activatesAt,
+		RetiresAt:   retiresAt,
+		ExpiresAt:   retiresAt.Add(self.config.Overlap),
+	}
+
+	if err := tx.Create(entity).Error; err != nil {
+		return nil, err
+	}
+
+	Log(SeverityInfo, "KeySet: created a signing key", map[string]any{
+		"kid":         entity.ID,
+		"algorithm":   entity.Algorithm,
+		"activatesAt": entity.ActivatesAt,
+	})
+
+	return entity, nil
+}
@@ -0,0 +1,274 @@
+package signing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/okira-e/go-as-your-backend/app/logging"
+	"github.com/okira-e/go-as-your-backend/app/models"
+	"github.com/okira-e/go-as-your-backend/app/utils"
+	"gorm.io/gorm"
+)
+
+// reloadInterval limits how often a token naming an unknown key makes us load the keys again, so
+// tokens with made up key IDs can't hammer the database.
+const reloadInterval = time.Minute
+
+// Config controls how tokens are signed and how often the signing key is replaced.
+type Config struct {
+	// Algorithm signs new tokens: RS256 or EdDSA with rotated keys, or HS256 with Secret.
+	Algorithm string
+	// Rotation is how long a key signs new tokens before the next one takes over.
+	Rotation time.Duration
+	// Overlap is how long a key is still accepted after it retired. It should be at least the lifetime
+	// of the longest lived token.
+	Overlap time.Duration
+	// PublishAhead is how long the next key is published before it takes over, so services caching
+	// the key set know it before the first token signed with it.
+	PublishAhead time.Duration
+	// Secret signs tokens with HS256. With another algorithm it only verifies tokens without a key ID,
+	// issued before switching, and should be removed once they expired.
+	Secret []byte
+}
+
+// KeySet signs tokens with the current key and verifies them with any key that hasn't expired. The keys
+// are kept in the signing_keys table so every instance uses the same ones.
+type KeySet struct {
+	db     *gorm.DB
+	config Config
+
+	mu       sync.RWMutex
+	keys     []*key
+	loadedAt time.Time
+}
+
+func NewKeySet(db *gorm.DB, config Config) *KeySet {
+	return &KeySet{db: db, config: config}
+}
+
+// NewFromEnv configures the key set from the JWT_* variables, then creates the first key if needed and
+// loads the keys.
+func NewFromEnv(ctx context.Context, db *gorm.DB) (*KeySet, error) {
+	config := Config{
+		Algorithm:    utils.GetEnv("JWT_ALGORITHM", EdDSA),
+		Rotation:     time.Duration(utils.GetEnvInt("JWT_KEY_ROTATION", 30*86400)) * time.Second,
+		Overlap:      time.Duration(utils.GetEnvInt("JWT_KEY_OVERLAP", longestTokenLifetime())) * time.Second,
+		PublishAhead: time.Duration(utils.GetEnvInt("JWT_KEY_PUBLISH_AHEAD", 86400)) * time.Second,
+		Secret:       []byte(utils.GetEnv("JWT_SECRET", "")),
+	}
+
+	switch config.Algorithm {
+	case RS256, EdDSA:
+	case HS256:
+		if len(config.Secret) == 0 {
+			return nil, errors.New("JWT_SECRET is required to sign tokens with HS256")
+		}
+	default:
+		return nil, fmt.Errorf("unknown JWT_ALGORITHM %q, expected RS256, EdDSA or HS256", config.Algorithm)
+	}
+	if config.Rotation <= 0 {
+		return nil, errors.New("JWT_KEY_ROTATION must be positive")
+	}
+	config.PublishAhead = min(config.PublishAhead, config.Rotation/2)
+
+	keySet := NewKeySet(db, config)
+	if err := keySet.Rotate(ctx); err != nil {
+		return nil, err
+	}
+	if err := keySet.Reload(ctx); err != nil {
+		return nil, err
+	}
+
+	return keySet, nil
+}
+
+// longestTokenLifetime is the default overlap, so no token outlives the key it was signed with.
+func longestTokenLifetime() int {
+	return max(
+		utils.GetEnvInt("ACCESS_TOKEN_EXPIRY", 0),
+		utils.GetEnvInt("REFRESH_TOKEN_EXPIRY", 0),
+		utils.GetEnvInt("EMAIL_VERIFICATION_EXPIRY", 86400),
+	)
+}
+
+// Sign signs the claims with the current key, naming it in the kid header.
+func (self *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if self.config.Algorithm == HS256 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(self.config.Secret)
+	}
+
+	key := self.current(time.Now())
+	if key == nil {
+		return "", errors.New("no signing key is active")
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.entity.ID
+
+	return token.SignedString(key.private)
+}
+
+// Parse verifies the signature of a token and decodes its claims into claims. The claims are validated
+// too, unless the options say otherwise.
+func (self *KeySet) Parse(tokenString string, claims jwt.Claims, options ...jwt.ParserOption) error {
+	options = append(options, jwt.WithValidMethods([]string{RS256, EdDSA, HS256}))
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, self.keyfunc, options...)
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("invalid token")
+	}
+
+	return nil
+}
+
+func (self *KeySet) keyfunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok && len(self.config.Secret) > 0 {
+			return self.config.Secret, nil
+		}
+
+		return nil, errors.New("missing key ID")
+	}
+
+	key := self.lookup(kid)
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.public(), nil
+}
+
+// JWKS returns the public keys tokens may be signed with, including the next key once it is published.
+func (self *KeySet) JWKS() JSONWebKeySet {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	now := time.Now()
+	document := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range self.keys {
+		if now.Before(key.entity.ExpiresAt) {
+			document.Keys = append(document.Keys, key.jwk())
+		}
+	}
+
+	return document
+}
+
+// current returns the key that signs new tokens, the one activated last.
+func (self *KeySet) current(now time.Time) *key {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	var current *key
+	for _, key := range self.keys {
+		if key.entity.ActivatesAt.After(now) || !now.Before(key.entity.ExpiresAt) {
+			continue
+		}
+		if current == nil || key.entity.ActivatesAt.After(current.entity.ActivatesAt) {
+			current = key
+		}
+	}
+
+	return current
+}
+
+// lookup returns the key with the ID unless it expired. Keys created by another instance may not be
+// loaded yet, so an unknown ID loads the keys again.
+func (self *KeySet) lookup(kid string) *key {
+	if key := self.find(kid); key != nil {
+		return key
+	}
+
+	self.mu.RLock()
+	loadedAt := self.loadedAt
+	self.mu.RUnlock()
+
+	if time.Since(loadedAt) < reloadInterval {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := self.Reload(ctx); err != nil {
+		Log(SeverityError, "KeySet: failed to load the signing keys", map[string]any{"error": err.Error()})
+		return nil
+	}
+
+	return self.find(kid)
+}
+
+func (self *KeySet) find(kid string) *key {
+	self.mu.RLock()
+	defer self.mu.RUnlock()
+
+	for _, key := range self.keys {
+		if key.entity.ID == kid && time.Now().Before(key.entity.ExpiresAt) {
+			return key
+		}
+	}
+
+	return nil
+}
+
+// Reload loads the keys that haven't expired from the database.
+func (self *KeySet) Reload(ctx context.Context) error {
+	var entities []models.SigningKey
+
+	err := self.db.WithContext(ctx).
+		Where("expires_at > ?", time.Now().UTC()).
+		Order("activates_at").
+		Find(&entities).Error
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*key, 0, len(entities))
+	for _, entity := range entities {
+		key, err := parseKey(entity)
+		if err != nil {
+			Log(SeverityError, "KeySet: skipping an unusable signing key", map[string]any{"kid": entity.ID, "error": err.Error()})
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	self.keys = keys
+	self.loadedAt = time.Now()
+
+	return nil
+}
+
+// Run rotates and loads the keys every interval until the context is done.
+func (self *KeySet) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := self.Rotate(ctx); err != nil {
+				Log(SeverityError, "KeySet: failed to rotate the signing keys", map[string]any{"error": err.Error()})
+			}
+			if err := self.Reload(ctx); err != nil {
+				Log(SeverityError, "KeySet: failed to load the signing keys", map[string]any{"error": err.Error()})
+			}
+		}
+	}
+}
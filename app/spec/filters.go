@@ -58,8 +58,24 @@ func ApplyPagination(tx *gorm.DB, queryOptions *QueryOptions) *gorm.DB {
 	return tx
 }
 
+// VirtualColumn lets filters refer to a column that isn't stored on the model, such as one
+// computed from a related table.
+type VirtualColumn struct {
+	// Where builds the SQL condition for an already validated, upper-cased operator. ok is false
+	// when the column doesn't support the operator or value.
+	Where func(operator string, value any) (sql string, values []any, ok bool)
+	// OrderBy is the SQL expression to sort by. Empty means the column can't be sorted on.
+	OrderBy string
+}
+
 // ApplyFilters applies validated filters to a GORM query
 func ApplyFilters(tx *gorm.DB, filter *Filter, model any) (*gorm.DB, error) {
+	return ApplyFiltersWith(tx, filter, model, nil)
+}
+
+// ApplyFiltersWith applies validated filters to a GORM query, accepting the given virtual columns
+// in addition to the model's own columns.
+func ApplyFiltersWith(tx *gorm.DB, filter *Filter, model any, virtualColumns map[string]VirtualColumn) (*gorm.DB, error) {
 	if filter == nil {
 		return tx, nil
 	}
@@ -114,7 +130,7 @@ func ApplyFilters(tx *gorm.DB, filter *Filter, model any) (*gorm.DB, error) {
 	}
 
 	// Apply WHERE
-	tx = applyWhereClause(tx, filter.Where, model)
+	tx = applyWhereClause(tx, filter.Where, model, virtualColumns)
 
 	// Apply GROUP BY
 	if len(filter.GroupBy) > 0 {
@@ -133,15 +149,22 @@ func ApplyFilters(tx *gorm.DB, filter *Filter, model any) (*gorm.DB, error) {
 	// Apply ORDER BY
 	if len(filter.OrderBy) > 0 {
 		for _, order := range filter.OrderBy {
-			if !isValidColumn(model, order.Column) {
-				continue
-			}
-
 			direction := "ASC"
 			if isValidSortDirection(order.Direction) {
 				direction = strings.ToUpper(order.Direction)
 			}
 
+			if virtual, ok := virtualColumns[strings.ToLower(strings.TrimSpace(order.Column))]; ok {
+				if virtual.OrderBy != "" {
+					tx = tx.Order(virtual.OrderBy + " " + direction)
+				}
+				continue
+			}
+
+			if !isValidColumn(model, order.Column) {
+				continue
+			}
+
 			// Use GORM's clause.OrderByColumn for safe ordering
 			tx = tx.Order(tx.Statement.Quote(order.Column) + " " + direction)
 		}
@@ -172,10 +195,10 @@ var (
 	}
 )
 
-func applyWhereClause(tx *gorm.DB, where WhereClause, model any) *gorm.DB {
+func applyWhereClause(tx *gorm.DB, where WhereClause, model any, virtualColumns map[string]VirtualColumn) *gorm.DB {
 	// Apply AND conditions
 	for _, condition := range where.And {
-		tx = applyCondition(tx, condition, model)
+		tx = applyCondition(tx, condition, model, virtualColumns)
 	}
 
 	// Apply OR conditions
@@ -184,26 +207,13 @@ func applyWhereClause(tx *gorm.DB, where WhereClause, model any) *gorm.DB {
 		var orValues []any
 
 		for _, condition := range where.Or {
-			if !isValidColumn(model, condition.Column) {
-				continue
-			}
-			if !isValidOperator(condition.Operator) {
+			sql, values, ok := buildCondition(tx, condition, model, virtualColumns)
+			if !ok {
 				continue
 			}
 
-			quotedColumn := tx.Statement.Quote(condition.Column)
-			operator := strings.ToUpper(strings.TrimSpace(condition.Operator))
-
-			switch operator {
-			case "IS NULL":
-				orConditions = append(orConditions, quotedColumn+" IS NULL")
-			case "IN":
-				orConditions = append(orConditions, quotedColumn+" IN (?)")
-				orValues = append(orValues, condition.Value)
-			default:
-				orConditions = append(orConditions, quotedColumn+" "+operator+" ?")
-				orValues = append(orValues, condition.Value)
-			}
+			orConditions = append(orConditions, sql)
+			orValues = append(orValues, values...)
 		}
 
 		if len(orConditions) > 0 {
@@ -215,34 +225,57 @@ func applyWhereClause(tx *gorm.DB, where WhereClause, model any) *gorm.DB {
 }
 
 // applyCondition applies a single WHERE condition
-func applyCondition(tx *gorm.DB, condition WhereCondition, model any) *gorm.DB {
-	// Validate column exists in model
-	if !isValidColumn(model, condition.Column) {
+func applyCondition(tx *gorm.DB, condition WhereCondition, model any, virtualColumns map[string]VirtualColumn) *gorm.DB {
+	sql, values, ok := buildCondition(tx, condition, model, virtualColumns)
+	if !ok {
 		return tx
 	}
 
+	return tx.Where(sql, values...)
+}
+
+// buildCondition turns a single condition into SQL. ok is false when the condition is invalid and
+// must be skipped.
+func buildCondition(
+	tx *gorm.DB,
+	condition WhereCondition,
+	model any,
+	virtualColumns map[string]VirtualColumn,
+) (string, []any, bool) {
 	// Validate operator is allowed
 	if !isValidOperator(condition.Operator) {
-		return tx
+		return "", nil, false
+	}
+
+	operator := strings.ToUpper(strings.TrimSpace(condition.Operator))
+
+	if virtual, ok := virtualColumns[strings.ToLower(strings.TrimSpace(condition.Column))]; ok {
+		if virtual.Where == nil {
+			return "", nil, false
+		}
+
+		return virtual.Where(operator, condition.Value)
+	}
+
+	// Validate column exists in model
+	if !isValidColumn(model, condition.Column) {
+		return "", nil, false
 	}
 
 	// Quote column name to prevent SQL injection
 	quotedColumn := tx.Statement.Quote(condition.Column)
-	operator := strings.ToUpper(strings.TrimSpace(condition.Operator))
 
 	switch operator {
 	case "IS NULL":
 		// IS NULL doesn't require a value
-		tx = tx.Where(quotedColumn + " IS NULL")
+		return quotedColumn + " IS NULL", nil, true
 	case "IN":
 		// IN expects an array/slice value
-		tx = tx.Where(quotedColumn+" IN (?)", condition.Value)
+		return quotedColumn + " IN (?)", []any{condition.Value}, true
 	default:
 		// Standard operators: =, >, <, >=, <=, LIKE
-		tx = tx.Where(quotedColumn+" "+operator+" ?", condition.Value)
+		return quotedColumn + " " + operator + " ?", []any{condition.Value}, true
 	}
-
-	return tx
 }
 
 // Helper functions
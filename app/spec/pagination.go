@@ -0,0 +1,13 @@
+package spec
+
+// CursorOptions selects the page of results that comes after Cursor. An empty Cursor selects the first page.
+type CursorOptions struct {
+	Cursor string
+	Limit  int
+}
+
+// Page is one page of results. NextCursor is nil on the last page.
+type Page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
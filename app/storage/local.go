@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores objects as files under a directory on the local filesystem.
+type Local struct {
+	dir       string
+	publicURL string
+}
+
+var _ Storage = (*Local)(nil)
+
+func NewLocal(dir string, publicURL string) *Local {
+	return &Local{dir: dir, publicURL: strings.TrimRight(publicURL, "/")}
+}
+
+// Dir returns the directory the objects are stored in, so it can be served statically.
+func (self *Local) Dir() string {
+	return self.dir
+}
+
+// PublicURL returns the base of the object URLs.
+func (self *Local) PublicURL() string {
+	return self.publicURL
+}
+
+func (self *Local) Put(ctx context.Context, key string, body io.ReadSeeker, size int64, contentType string) error {
+	path, err := self.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial object.
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	written, err := io.Copy(file, io.LimitReader(body, size))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if written != size {
+		return fmt.Errorf("short write: expected %d bytes, wrote %d", size, written)
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+func (self *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := self.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	return file, err
+}
+
+func (self *Local) Delete(ctx context.Context, key string) error {
+	path, err := self.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (self *Local) URL(key string) string {
+	return self.publicURL + "/" + key
+}
+
+func (self *Local) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+
+	return filepath.Join(self.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testBucket          = "uploads"
+	testRegion          = "eu-west-1"
+	testAccessKeyID     = "AKIDEXAMPLE"
+	testSecretAccessKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	// testKey needs escaping, which the signature has to agree on with the server.
+	testKey = "avatars/jane doe+1.png"
+)
+
+// fakeS3 is an S3-compatible server keeping the objects of one bucket in memory. Like S3 and MinIO, it
+// checks the signature of every request against the secret key and the request it received.
+type fakeS3 struct {
+	pathStyle bool
+
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	body        []byte
+	contentType string
+}
+
+func newFakeS3(pathStyle bool) *fakeS3 {
+	return &fakeS3{pathStyle: pathStyle, objects: map[string]fakeObject{}}
+}
+
+func (self *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := verifySignature(r); err != nil {
+		http.Error(w, "SignatureDoesNotMatch: "+err.Error(), http.StatusForbidden)
+		return
+	}
+
+	key, err := self.key(r)
+	if err != nil {
+		http.Error(w, "NoSuchBucket: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if sum := sha256.Sum256(body); hex.EncodeToString(sum[:]) != r.Header.Get("X-Amz-Content-Sha256") {
+			http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+			return
+		}
+		self.objects[key] = fakeObject{body: body, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		object, ok := self.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Write(object.body)
+	case http.MethodDelete:
+		delete(self.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// key finds the bucket in the path or the host, depending on the addressing style, and returns the key
+// of the object.
+func (self *fakeS3) key(r *http.Request) (string, error) {
+	if self.pathStyle {
+		key, ok := strings.CutPrefix(r.URL.Path, "/"+testBucket+"/")
+		if !ok {
+			return "", fmt.Errorf("no bucket in the path %s", r.URL.Path)
+		}
+
+		return key, nil
+	}
+
+	if host, _, _ := strings.Cut(r.Host, ":"); host != testBucket+".s3.test" {
+		return "", fmt.Errorf("no bucket in the host %s", r.Host)
+	}
+
+	return strings.TrimPrefix(r.URL.Path, "/"), nil
+}
+
+func (self *fakeS3) object(key string) (fakeObject, bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	object, ok := self.objects[key]
+	return object, ok
+}
+
+// verifySignature checks the AWS Signature Version 4 of the request as the server sees it, from the
+// path as it was sent on the wire.
+func verifySignature(r *http.Request) error {
+	var credential, signedHeaders, signature string
+	for _, part := range strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 "), ", ") {
+		name, value, _ := strings.Cut(part, "=")
+		switch name {
+		case "Credential":
+			credential = value
+		case "SignedHeaders":
+			signedHeaders = value
+		case "Signature":
+			signature = value
+		}
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	signedAt, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil || time.Since(signedAt).Abs() > 15*time.Minute {
+		return fmt.Errorf("invalid date %q", amzDate)
+	}
+	scope := amzDate[:8] + "/" + testRegion + "/s3/aws4_request"
+	if credential != testAccessKeyID+"/"+scope {
+		return fmt.Errorf("unexpected credential %q", credential)
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	for _, name := range []string{"host", "x-amz-content-sha256", "x-amz-date"} {
+		if !strings.Contains(";"+signedHeaders+";", ";"+name+";") {
+			return fmt.Errorf("%s isn't signed", name)
+		}
+	}
+
+	path, query, _ := strings.Cut(r.RequestURI, "?")
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		path,
+		query,
+		canonicalHeaders.String(),
+		signedHeaders,
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + testSecretAccessKey)
+	for _, data := range []string{amzDate[:8], testRegion, "s3", "aws4_request", stringToSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(data))
+		key = mac.Sum(nil)
+	}
+	if !hmac.Equal([]byte(signature), []byte(hex.EncodeToString(key))) {
+		return errors.New("wrong signature")
+	}
+
+	return nil
+}
+
+// newTestS3 serves a fake bucket and returns a client for it. With virtual-hosted addressing the
+// endpoint is s3.test, and every connection goes to the fake server whatever the host.
+func newTestS3(t *testing.T, pathStyle bool) (*S3, *fakeS3, string) {
+	fake := newFakeS3(pathStyle)
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	endpoint := server.URL
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !pathStyle {
+		endpoint = "http://s3.test"
+		transport.DialContext = func(ctx context.Context, network string, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		}
+	}
+
+	store := NewS3(S3Config{
+		Endpoint:        endpoint + "/",
+		Region:          testRegion,
+		Bucket:          testBucket,
+		AccessKeyID:     testAccessKeyID,
+		SecretAccessKey: testSecretAccessKey,
+		ForcePathStyle:  pathStyle,
+	})
+	store.client = &http.Client{Transport: transport}
+
+	return store, fake, endpoint
+}
+
+func TestS3(t *testing.T) {
+	cases := []struct {
+		name      string
+		pathStyle bool
+		// url is the URL of testKey, given the endpoint.
+		url func(endpoint string) string
+	}{
+		{
+			name:      "path style",
+			pathStyle: true,
+			url: func(endpoint string) string {
+				return endpoint + "/uploads/avatars/jane%20doe%2B1.png"
+			},
+		},
+		{
+			name:      "virtual hosted style",
+			pathStyle: false,
+			url: func(endpoint string) string {
+				return "http://uploads.s3.test/avatars/jane%20doe%2B1.png"
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store, fake, endpoint := newTestS3(t, tc.pathStyle)
+			ctx := context.Background()
+			body := []byte("\x89PNG not really")
+
+			if err := store.Put(ctx, testKey, bytes.NewReader(body), int64(len(body)), "image/png"); err != nil {
+				t.Fatalf("put: %s", err)
+			}
+			object, ok := fake.object(testKey)
+			if !ok || !bytes.Equal(object.body, body) || object.contentType != "image/png" {
+				t.Fatalf("the bucket holds %+v, expected the uploaded object", object)
+			}
+
+			if url := store.URL(testKey); url != tc.url(endpoint) {
+				t.Fatalf("got URL %s, expected %s", url, tc.url(endpoint))
+			}
+
+			reader, err := store.Get(ctx, testKey)
+			if err != nil {
+				t.Fatalf("get: %s", err)
+			}
+			got, err := io.ReadAll(reader)
+			reader.Close()
+			if err != nil || !bytes.Equal(got, body) {
+				t.Fatalf("got %q, %v, expected the uploaded object", got, err)
+			}
+
+			if err := store.Delete(ctx, testKey); err != nil {
+				t.Fatalf("delete: %s", err)
+			}
+			if _, ok := fake.object(testKey); ok {
+				t.Fatal("the object is still in the bucket")
+			}
+			if _, err := store.Get(ctx, testKey); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("get after delete returned %v, expected ErrNotFound", err)
+			}
+
+			// Deleting a missing object is not an error.
+			if err := store.Delete(ctx, testKey); err != nil {
+				t.Fatalf("deleting again: %s", err)
+			}
+		})
+	}
+}
+
+func TestS3URLWithPublicURL(t *testing.T) {
+	store := NewS3(S3Config{
+		Endpoint:  "https://s3.eu-west-1.amazonaws.com",
+		Bucket:    testBucket,
+		PublicURL: "https://cdn.example.com/",
+	})
+
+	if url := store.URL(testKey); url != "https://cdn.example.com/avatars/jane%20doe%2B1.png" {
+		t.Fatalf("got URL %s", url)
+	}
+}
+
+func TestS3WrongSecretIsRejected(t *testing.T) {
+	store, fake, _ := newTestS3(t, true)
+	store.config.SecretAccessKey = "wrong"
+
+	err := store.Put(context.Background(), testKey, strings.NewReader("body"), 4, "text/plain")
+	if err == nil || !strings.Contains(err.Error(), "unexpected status 403") {
+		t.Fatalf("got %v, expected the server to reject the signature", err)
+	}
+	if _, ok := fake.object(testKey); ok {
+		t.Fatal("the object was stored")
+	}
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/okira-e/go-as-your-backend/app/utils"
+)
+
+// ErrNotFound is returned when an object doesn't exist in the storage backend.
+var ErrNotFound = errors.New("object not found")
+
+// Storage is a blob store for uploaded files. Keys are slash separated relative paths.
+type Storage interface {
+	// Put stores size bytes read from body under key, replacing any existing object.
+	Put(ctx context.Context, key string, body io.ReadSeeker, size int64, contentType string) error
+	// Get opens the object stored under key. The caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the address the object can be downloaded from.
+	URL(key string) string
+}
+
+// NewFromEnv builds the storage backend selected by STORAGE_DRIVER, which is "local" by default.
+func NewFromEnv() Storage {
+	driver := utils.GetEnv("STORAGE_DRIVER", "local")
+
+	switch driver {
+	case "local":
+		return NewLocal(
+			utils.GetEnv("STORAGE_LOCAL_DIR", "./uploads"),
+			utils.GetEnv("STORAGE_PUBLIC_URL", "/uploads"),
+		)
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:        utils.RequireEnv("S3_ENDPOINT"),
+			Region:          utils.GetEnv("S3_REGION", "us-east-1"),
+			Bucket:          utils.RequireEnv("S3_BUCKET"),
+			AccessKeyID:     utils.RequireEnv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: utils.RequireEnv("S3_SECRET_ACCESS_KEY"),
+			ForcePathStyle:  utils.GetEnv("S3_FORCE_PATH_STYLE", "false") == "true",
+			PublicURL:       utils.GetEnv("STORAGE_PUBLIC_URL", ""),
+		})
+	default:
+		log.Fatalf("Unknown storage driver %q, expected local or s3", driver)
+		return nil
+	}
+}
+
+// validKey rejects keys that could escape the storage root.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,244 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change.
+const diffContextLines = 3
+
+// maxDiffEdits caps the number of edits the diff searches for at each step, which bounds its time to
+// about the length of the texts times maxDiffEdits. Parts of the texts that differ by more than that
+// are diffed as a whole replacement.
+const maxDiffEdits = 1000
+
+type diffLine struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// UnifiedDiff returns the line based unified diff between two texts, or an empty string when they
+// are equal.
+func UnifiedDiff(fromName string, toName string, from string, to string) string {
+	lines := diffLines(splitLines(from), splitLines(to))
+
+	hunks := unifiedHunks(lines)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var diff strings.Builder
+	fmt.Fprintf(&diff, "--- %s\n+++ %s\n", fromName, toName)
+	for _, hunk := range hunks {
+		diff.WriteString(hunk)
+	}
+
+	return diff.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines lines up a and b along a shortest edit script, found with Myers' algorithm in linear space.
+func diffLines(a []string, b []string) []diffLine {
+	return appendDiff(make([]diffLine, 0, len(a)+len(b)), a, b)
+}
+
+func appendDiff(lines []diffLine, a []string, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	for _, text := range a[:prefix] {
+		lines = append(lines, diffLine{' ', text})
+	}
+
+	middleA := a[prefix : len(a)-suffix]
+	middleB := b[prefix : len(b)-suffix]
+
+	if x, y, ok := middleSnake(middleA, middleB); ok {
+		lines = appendDiff(lines, middleA[:x], middleB[:y])
+		lines = appendDiff(lines, middleA[x:], middleB[y:])
+	} else {
+		for _, text := range middleA {
+			lines = append(lines, diffLine{'-', text})
+		}
+		for _, text := range middleB {
+			lines = append(lines, diffLine{'+', text})
+		}
+	}
+
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{' ', text})
+	}
+
+	return lines
+}
+
+// middleSnake searches the shortest edit script from both ends at once, and returns where the two
+// searches meet, splitting the texts in two halves to diff separately. The texts must differ in their
+// first and last lines. ok is false when either text is empty, or when they differ by too much.
+func middleSnake(a []string, b []string) (int, int, bool) {
+	n, m := len(a), len(b)
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+
+	maxD := min((n+m+1)/2, maxDiffEdits)
+	offset := maxD + 1
+	// forward[offset+k] is the furthest x reached on diagonal k = x-y from the start, backward[offset+k]
+	// the same from the end.
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for i := range forward {
+		forward[i] = -1
+		backward[i] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+
+	delta := n - m
+	// With an odd delta the searches meet while going forward, with an even one while going backward.
+	checkForward := delta%2 != 0
+
+	forwardStart, forwardEnd, backwardStart, backwardEnd := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k := -d + forwardStart; k <= d-forwardEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+
+			switch {
+			case x > n:
+				forwardEnd += 2
+			case y > m:
+				forwardStart += 2
+			case checkForward:
+				backwardK := offset + delta - k
+				if backwardK >= 0 && backwardK < len(backward) && backward[backwardK] != -1 && x >= n-backward[backwardK] {
+					return x, y, true
+				}
+			}
+		}
+
+		for k := -d + backwardStart; k <= d-backwardEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+
+			switch {
+			case x > n:
+				backwardEnd += 2
+			case y > m:
+				backwardStart += 2
+			case !checkForward:
+				forwardK := offset + delta - k
+				if forwardK >= 0 && forwardK < len(forward) && forward[forwardK] != -1 {
+					forwardX := forward[forwardK]
+					forwardY := forwardX - (forwardK - offset)
+					if forwardX >= n-x {
+						return forwardX, forwardY, true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// unifiedHunks groups changed lines, with their surrounding context, into "@@" hunks.
+func unifiedHunks(lines []diffLine) []string {
+	// fromLine[i] and toLine[i] are the 0-based line numbers lines[i] starts at in each text.
+	fromLine := make([]int, len(lines)+1)
+	toLine := make([]int, len(lines)+1)
+	for i, line := range lines {
+		fromLine[i+1] = fromLine[i]
+		toLine[i+1] = toLine[i]
+		if line.kind != '+' {
+			fromLine[i+1]++
+		}
+		if line.kind != '-' {
+			toLine[i+1]++
+		}
+	}
+
+	var hunks []string
+	for i := 0; i < len(lines); i++ {
+		if lines[i].kind == ' ' {
+			continue
+		}
+
+		start := max(0, i-diffContextLines)
+
+		// Extend the hunk over every change that is close enough for the contexts to touch.
+		end := i
+		for j := i; j < len(lines) && j <= end+2*diffContextLines; j++ {
+			if lines[j].kind != ' ' {
+				end = j
+			}
+		}
+		end = min(len(lines), end+diffContextLines+1)
+
+		var hunk strings.Builder
+		fmt.Fprintf(
+			&hunk,
+			"@@ -%s +%s @@\n",
+			hunkRange(fromLine[start], fromLine[end]-fromLine[start]),
+			hunkRange(toLine[start], toLine[end]-toLine[start]),
+		)
+		for _, line := range lines[start:end] {
+			hunk.WriteByte(line.kind)
+			hunk.WriteString(line.text)
+			hunk.WriteByte('\n')
+		}
+
+		hunks = append(hunks, hunk.String())
+		i = end - 1
+	}
+
+	return hunks
+}
+
+func hunkRange(start int, length int) string {
+	// An empty range refers to the line before it, per the unified diff format.
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
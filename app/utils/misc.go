@@ -19,3 +19,25 @@ func GetUserFromContext(ctx *fiber.Ctx) (models.JwtUser, error) {
 
 	return sessionUser, nil
 }
+
+// GetOptionalUserFromContext returns the user set by the optional auth middleware, or nil for anonymous requests.
+func GetOptionalUserFromContext(ctx *fiber.Ctx) *models.JwtUser {
+	user, err := GetUserFromContext(ctx)
+	if err != nil {
+		return nil
+	}
+
+	return &user
+}
+
+// SetViewedPost marks the request as a view of the post, for the analytics middleware to record.
+func SetViewedPost(ctx *fiber.Ctx, postId string) {
+	ctx.Locals("viewedPostId", postId)
+}
+
+// GetViewedPost returns the post marked as viewed by the request, or an empty string.
+func GetViewedPost(ctx *fiber.Ctx) string {
+	postId, _ := ctx.Locals("viewedPostId").(string)
+
+	return postId
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"log"
 	"os"
+	"strconv"
 )
 
 func RequireEnv(name string) string {
@@ -13,3 +14,28 @@ func RequireEnv(name string) string {
 
 	return val
 }
+
+// GetEnv returns the value of an optional environment variable, or fallback when it isn't set.
+func GetEnv(name string, fallback string) string {
+	val := os.Getenv(name)
+	if val == "" {
+		return fallback
+	}
+
+	return val
+}
+
+// GetEnvInt returns the integer value of an optional environment variable, or fallback when it isn't set.
+func GetEnvInt(name string, fallback int) int {
+	val := os.Getenv(name)
+	if val == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(val)
+	if err != nil {
+		log.Fatalf("Environment variable %s must be an integer, got %q", name, val)
+	}
+
+	return parsed
+}
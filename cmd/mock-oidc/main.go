@@ -0,0 +1,33 @@
+// Command mock-oidc runs a local OpenID Connect provider that signs in anyone without asking, for
+// trying out and testing social login. Configure it as a provider with:
+//
+//	OIDC_PROVIDERS=mock
+//	OIDC_MOCK_ISSUER=http://localhost:9999
+//	OIDC_MOCK_CLIENT_ID=go-as-your-backend
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/okira-e/go-as-your-backend/app/oidc"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9999", "address to listen on")
+	issuer := flag.String("issuer", "http://localhost:9999", "issuer URL the provider is reached at")
+	email := flag.String("email", "mock.user@example.com", "email signed in when the request has no login_hint")
+	unverified := flag.Bool("unverified", false, "claim the emails aren't verified")
+	flag.Parse()
+
+	server, err := oidc.NewMockServer(*issuer)
+	if err != nil {
+		log.Fatalf("Failed to start the mock provider: %s\n", err.Error())
+	}
+	server.DefaultEmail = *email
+	server.EmailVerified = !*unverified
+
+	log.Printf("Mock OIDC provider %s listening on %s\n", *issuer, *addr)
+	log.Fatalln(http.ListenAndServe(*addr, server))
+}
@@ -1,16 +1,34 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/okira-e/go-as-your-backend/app/audit"
+	"github.com/okira-e/go-as-your-backend/app/mailer"
+	"github.com/okira-e/go-as-your-backend/app/metrics"
+	"github.com/okira-e/go-as-your-backend/app/modules/analytics"
+	"github.com/okira-e/go-as-your-backend/app/modules/attachments"
+	"github.com/okira-e/go-as-your-backend/app/modules/bookmarks"
+	"github.com/okira-e/go-as-your-backend/app/modules/comments"
+	"github.com/okira-e/go-as-your-backend/app/modules/follows"
+	"github.com/okira-e/go-as-your-backend/app/modules/moderation"
 	"github.com/okira-e/go-as-your-backend/app/modules/posts"
 	"github.com/okira-e/go-as-your-backend/app/modules/roles"
+	"github.com/okira-e/go-as-your-backend/app/modules/series"
+	"github.com/okira-e/go-as-your-backend/app/modules/tags"
 	"github.com/okira-e/go-as-your-backend/app/modules/users"
+	"github.com/okira-e/go-as-your-backend/app/oidc"
+	"github.com/okira-e/go-as-your-backend/app/signing"
+	"github.com/okira-e/go-as-your-backend/app/storage"
 	"github.com/okira-e/go-as-your-backend/app/utils"
 
 	"github.com/joho/godotenv"
@@ -28,7 +46,10 @@ func main() {
 		log.Fatalf("Error connecting to the database. %s\n", err.Error())
 	}
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		// Leave room for the multipart envelope around the largest accepted upload.
+		BodyLimit: attachments.MaxUploadSize() + 1<<20,
+	})
 
 	// Setup CORS.
 	clientOrigin := utils.RequireEnv("CLIENT_URL")
@@ -83,6 +104,11 @@ func setupDatabase() (*gorm.DB, error) {
 	return db, nil
 }
 
+// apiURL is the public base URL of the API, which identity providers redirect users back to.
+func apiURL() string {
+	return strings.TrimRight(utils.GetEnv("API_URL", "http://"+utils.RequireEnv("HOST")+":"+utils.RequireEnv("PORT")), "/")
+}
+
 func setupModules(app *fiber.App, db *gorm.DB) {
 	version := utils.RequireEnv("API_VERSION")
 
@@ -93,10 +119,50 @@ func setupModules(app *fiber.App, db *gorm.DB) {
 		return c.SendStatus(fiber.StatusOK)
 	})
 
+	// Metrics in the Prometheus text format. Set METRICS_TOKEN to require it as a Bearer token.
+	api.Get("/metrics", func(c *fiber.Ctx) error {
+		if token := utils.GetEnv("METRICS_TOKEN", ""); token != "" &&
+			subtle.ConstantTimeCompare([]byte(c.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			return c.SendStatus(fiber.StatusUnauthorized)
+		}
+
+		c.Set("Content-Type", "text/plain; version=0.0.4")
+		return metrics.Write(c)
+	})
+
+	signingKeys, err := signing.NewFromEnv(context.Background(), db)
+	if err != nil {
+		log.Fatalf("Error setting up the token signing keys. %s\n", err.Error())
+	}
+	go signingKeys.Run(context.Background(), time.Minute)
+
+	// The public keys our tokens are signed with, so other services can verify them on their own.
+	app.Get("/.well-known/jwks.json", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+		return c.JSON(signingKeys.JWKS())
+	})
+
 	versionedApi := api.Group("/" + version)
 
 	usersRepo := users.NewRepository(db)
-	usersService := users.NewService(usersRepo)
+	sessionsRepo := users.NewSessionsRepository(db)
+	passwordResetsRepo := users.NewPasswordResetsRepository(db)
+	mfaRepo := users.NewMFARepository(db)
+	identitiesRepo := users.NewIdentitiesRepository(db)
+	oidcProviders := oidc.NewFromEnv(apiURL() + "/api/" + version + "/users/oidc")
+	loginThrottlesRepo := users.NewLoginThrottlesRepository(db)
+	usersService := users.NewService(
+		usersRepo,
+		sessionsRepo,
+		passwordResetsRepo,
+		loginThrottlesRepo,
+		mfaRepo,
+		identitiesRepo,
+		oidcProviders,
+		signingKeys,
+		audit.NewDatabase(db),
+		mailer.NewFromEnv(),
+	)
 	usersHandler := users.NewHandler(usersService)
 	users.SetupRoutes(versionedApi, usersHandler, usersService)
 
@@ -105,8 +171,73 @@ func setupModules(app *fiber.App, db *gorm.DB) {
 	rolesHandler := roles.NewHandler(rolesService)
 	roles.SetupRoutes(versionedApi, rolesHandler)
 
+	analyticsRepo := analytics.NewRepository(db)
+	viewRecorder := analytics.NewRecorder(
+		analyticsRepo,
+		utils.GetEnvInt("ANALYTICS_BUFFER_SIZE", 10000),
+		time.Duration(utils.GetEnvInt("ANALYTICS_FLUSH_INTERVAL", 10))*time.Second,
+		time.Duration(utils.GetEnvInt("ANALYTICS_DEDUPE_WINDOW", 30))*time.Minute,
+		utils.GetEnv("ANALYTICS_SALT", ""),
+	)
+	go viewRecorder.Run(context.Background())
+
 	postsRepo := posts.NewRepository(db)
 	postsService := posts.NewService(postsRepo)
 	postsHandler := posts.NewHandler(postsService)
-	posts.SetupRoutes(versionedApi, postsHandler, usersService)
+	posts.SetupRoutes(versionedApi, postsHandler, usersService, analytics.TrackViews(viewRecorder))
+
+	analyticsService := analytics.NewService(analyticsRepo, postsRepo)
+	analyticsHandler := analytics.NewHandler(analyticsService)
+	analytics.SetupRoutes(versionedApi, analyticsHandler, usersService)
+
+	go postsService.RunScheduler(30 * time.Second)
+	go func() {
+		count, err := postsService.RenderMissing(context.Background())
+		if err != nil {
+			log.Printf("Error rendering the content of existing posts. %s\n", err.Error())
+		}
+		if count > 0 {
+			fmt.Printf("Rendered the content of %d existing posts.\n", count)
+		}
+	}()
+
+	commentsRepo := comments.NewRepository(db)
+	commentsService := comments.NewService(commentsRepo, postsRepo)
+	commentsHandler := comments.NewHandler(commentsService)
+	comments.SetupRoutes(versionedApi, commentsHandler, usersService)
+
+	seriesRepo := series.NewRepository(db)
+	seriesService := series.NewService(seriesRepo, postsRepo)
+	seriesHandler := series.NewHandler(seriesService)
+	series.SetupRoutes(versionedApi, seriesHandler, usersService)
+
+	bookmarksRepo := bookmarks.NewRepository(db)
+	bookmarksService := bookmarks.NewService(bookmarksRepo, postsService)
+	bookmarksHandler := bookmarks.NewHandler(bookmarksService)
+	bookmarks.SetupRoutes(versionedApi, bookmarksHandler, usersService)
+
+	moderationRepo := moderation.NewRepository(db)
+	moderationService := moderation.NewService(moderationRepo, postsRepo)
+	moderationHandler := moderation.NewHandler(moderationService)
+	moderation.SetupRoutes(versionedApi, moderationHandler, usersService)
+
+	followsRepo := follows.NewRepository(db)
+	followsService := follows.NewService(followsRepo)
+	followsHandler := follows.NewHandler(followsService)
+	follows.SetupRoutes(versionedApi, followsHandler, usersService)
+
+	tagsRepo := tags.NewRepository(db)
+	tagsService := tags.NewService(tagsRepo)
+	tagsHandler := tags.NewHandler(tagsService)
+	tags.SetupRoutes(versionedApi, tagsHandler, usersService)
+
+	store := storage.NewFromEnv()
+
+	attachmentsRepo := attachments.NewRepository(db)
+	attachmentsService := attachments.NewService(attachmentsRepo, postsRepo, store)
+	attachmentsHandler := attachments.NewHandler(attachmentsService)
+	attachments.SetupRoutes(versionedApi, attachmentsHandler, usersService)
+	if local, ok := store.(*storage.Local); ok && strings.HasPrefix(local.PublicURL(), "/") {
+		attachments.SetupFileRoutes(app, local.PublicURL(), attachmentsHandler, usersService)
+	}
 }
@@ -0,0 +1,8 @@
+-- Modify "posts" table
+ALTER TABLE "posts" ADD COLUMN "status" character varying(16) NOT NULL DEFAULT 'draft', ADD COLUMN "published_at" timestamp NULL, ADD COLUMN "scheduled_for" timestamp NULL, ADD CONSTRAINT "posts_status_check" CHECK (status IN ('draft', 'in_review', 'scheduled', 'published', 'archived'));
+-- Backfill the status of posts published before the editorial workflow existed
+UPDATE "posts" SET "status" = 'published', "published_at" = "created_at" WHERE "published";
+-- Create index "posts_status_published_at_idx" to table: "posts"
+CREATE INDEX "posts_status_published_at_idx" ON "posts" ("status", "published_at");
+-- Create index "posts_status_scheduled_for_idx" to table: "posts"
+CREATE INDEX "posts_status_scheduled_for_idx" ON "posts" ("status", "scheduled_for");
@@ -0,0 +1,16 @@
+-- Modify "posts" table
+ALTER TABLE "posts" ADD COLUMN "slug" character varying(255) NULL;
+-- Give existing posts a slug that is guaranteed to be unique
+UPDATE "posts" SET "slug" = "id"::text;
+-- Modify "posts" table
+ALTER TABLE "posts" ALTER COLUMN "slug" SET NOT NULL, ADD CONSTRAINT "posts_slug_key" UNIQUE ("slug");
+-- Create "post_slugs" table
+CREATE TABLE "post_slugs" (
+  "slug" character varying(255) NOT NULL,
+  "post_id" uuid NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  PRIMARY KEY ("slug"),
+  CONSTRAINT "post_id" FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "post_slugs_post_id_idx" to table: "post_slugs"
+CREATE INDEX "post_slugs_post_id_idx" ON "post_slugs" ("post_id");
@@ -0,0 +1,21 @@
+-- Modify "posts" table
+ALTER TABLE "posts" ADD COLUMN "comments_count" bigint NOT NULL DEFAULT 0, ADD COLUMN "comments_locked" boolean NOT NULL DEFAULT false;
+-- Create "comments" table
+CREATE TABLE "comments" (
+  "id" uuid NOT NULL,
+  "post_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "parent_id" uuid NULL,
+  "depth" integer NOT NULL DEFAULT 0,
+  "content" text NOT NULL,
+  "replies_count" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp NULL,
+  "deleted_at" timestamp NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "parent_id" FOREIGN KEY ("parent_id") REFERENCES "comments" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "post_id" FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "comments_post_id_parent_id_id_idx" to table: "comments"
+CREATE INDEX "comments_post_id_parent_id_id_idx" ON "comments" ("post_id", "parent_id", "id");
@@ -0,0 +1,18 @@
+-- Create "tags" table
+CREATE TABLE "tags" (
+  "id" uuid NOT NULL,
+  "name" character varying(32) NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "tags_name_key" UNIQUE ("name")
+);
+-- Create "post_tags" table
+CREATE TABLE "post_tags" (
+  "post_id" uuid NOT NULL,
+  "tag_id" uuid NOT NULL,
+  PRIMARY KEY ("post_id", "tag_id"),
+  CONSTRAINT "post_id" FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "tag_id" FOREIGN KEY ("tag_id") REFERENCES "tags" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "post_tags_tag_id_idx" to table: "post_tags"
+CREATE INDEX "post_tags_tag_id_idx" ON "post_tags" ("tag_id");
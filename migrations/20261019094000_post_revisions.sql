@@ -0,0 +1,17 @@
+-- Create "post_revisions" table
+CREATE TABLE "post_revisions" (
+  "id" uuid NOT NULL,
+  "post_id" uuid NOT NULL,
+  "number" integer NOT NULL,
+  "title" character varying(255) NOT NULL,
+  "content" text NULL,
+  "editor_id" uuid NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "post_revisions_post_id_number_key" UNIQUE ("post_id", "number"),
+  CONSTRAINT "editor_id" FOREIGN KEY ("editor_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE NO ACTION,
+  CONSTRAINT "post_id" FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Snapshot existing posts as their first revision
+INSERT INTO "post_revisions" ("id", "post_id", "number", "title", "content", "editor_id", "created_at")
+SELECT gen_random_uuid(), "id", 1, "title", "content", "user_id", COALESCE("updated_at", "created_at") FROM "posts";
@@ -0,0 +1,2 @@
+-- Modify "posts" table
+ALTER TABLE "posts" ADD COLUMN "content_html" text NULL, ADD COLUMN "excerpt" text NOT NULL DEFAULT '', ADD COLUMN "reading_time_minutes" integer NOT NULL DEFAULT 0;
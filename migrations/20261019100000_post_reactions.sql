@@ -0,0 +1,13 @@
+-- Create "post_reactions" table
+CREATE TABLE "post_reactions" (
+  "post_id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "kind" character varying(16) NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  PRIMARY KEY ("post_id", "user_id", "kind"),
+  CONSTRAINT "post_id" FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "post_reactions_kind_check" CHECK (kind IN ('like', 'love', 'laugh', 'wow', 'sad', 'angry'))
+);
+-- Create index "post_reactions_user_id_idx" to table: "post_reactions"
+CREATE INDEX "post_reactions_user_id_idx" ON "post_reactions" ("user_id");
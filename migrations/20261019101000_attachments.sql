@@ -0,0 +1,21 @@
+-- Create "attachments" table
+CREATE TABLE "attachments" (
+  "id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "post_id" uuid NULL,
+  "storage_key" text NOT NULL,
+  "file_name" character varying(255) NOT NULL,
+  "content_type" character varying(127) NOT NULL,
+  "size" bigint NOT NULL,
+  "width" integer NULL,
+  "height" integer NULL,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "attachments_storage_key_key" UNIQUE ("storage_key"),
+  CONSTRAINT "user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "post_id" FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON UPDATE NO ACTION ON DELETE SET NULL
+);
+-- Create index "attachments_post_id_idx" to table: "attachments"
+CREATE INDEX "attachments_post_id_idx" ON "attachments" ("post_id");
+-- Create index "attachments_user_id_idx" to table: "attachments"
+CREATE INDEX "attachments_user_id_idx" ON "attachments" ("user_id");
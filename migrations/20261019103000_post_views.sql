@@ -0,0 +1,28 @@
+-- Create "post_views_daily" table
+CREATE TABLE "post_views_daily" (
+  "post_id" uuid NOT NULL,
+  "day" date NOT NULL,
+  "views" bigint NOT NULL DEFAULT 0,
+  "unique_visitors" bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY ("post_id", "day"),
+  CONSTRAINT "post_id" FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create "post_view_visitors" table
+CREATE TABLE "post_view_visitors" (
+  "post_id" uuid NOT NULL,
+  "day" date NOT NULL,
+  "visitor_hash" character varying(64) NOT NULL,
+  PRIMARY KEY ("post_id", "day", "visitor_hash"),
+  CONSTRAINT "post_id" FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "post_view_visitors_day_idx" to table: "post_view_visitors"
+CREATE INDEX "post_view_visitors_day_idx" ON "post_view_visitors" ("day");
+-- Create "post_view_referrers" table
+CREATE TABLE "post_view_referrers" (
+  "post_id" uuid NOT NULL,
+  "day" date NOT NULL,
+  "referrer" character varying(255) NOT NULL,
+  "views" bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY ("post_id", "day", "referrer"),
+  CONSTRAINT "post_id" FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
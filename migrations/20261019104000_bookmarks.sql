@@ -0,0 +1,29 @@
+-- Create "bookmark_lists" table
+CREATE TABLE "bookmark_lists" (
+  "id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "name" character varying(64) NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "bookmark_lists_user_id_name_key" UNIQUE ("user_id", "name"),
+  CONSTRAINT "user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create "bookmarks" table
+CREATE TABLE "bookmarks" (
+  "id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "post_id" uuid NOT NULL,
+  "list_id" uuid NULL,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "post_id" FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "list_id" FOREIGN KEY ("list_id") REFERENCES "bookmark_lists" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "bookmarks_list_id_post_id_key" to table: "bookmarks"
+CREATE UNIQUE INDEX "bookmarks_list_id_post_id_key" ON "bookmarks" ("list_id", "post_id") WHERE (list_id IS NOT NULL);
+-- Create index "bookmarks_user_id_idx" to table: "bookmarks"
+CREATE INDEX "bookmarks_user_id_idx" ON "bookmarks" ("user_id");
+-- Create index "bookmarks_user_id_post_id_key" to table: "bookmarks"
+CREATE UNIQUE INDEX "bookmarks_user_id_post_id_key" ON "bookmarks" ("user_id", "post_id") WHERE (list_id IS NULL);
@@ -0,0 +1,38 @@
+-- Modify "posts" table
+ALTER TABLE "posts" ADD COLUMN "hidden" boolean NOT NULL DEFAULT false;
+-- Create "moderation_actions" table
+CREATE TABLE "moderation_actions" (
+  "id" uuid NOT NULL,
+  "post_id" uuid NOT NULL,
+  "post_title" character varying(255) NOT NULL,
+  "moderator_id" uuid NULL,
+  "action" character varying(16) NOT NULL,
+  "note" text NULL,
+  "reports_resolved" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "moderator_id" FOREIGN KEY ("moderator_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL,
+  CONSTRAINT "moderation_actions_action_check" CHECK (action IN ('hide', 'restore', 'delete', 'dismiss'))
+);
+-- Create index "moderation_actions_post_id_idx" to table: "moderation_actions"
+CREATE INDEX "moderation_actions_post_id_idx" ON "moderation_actions" ("post_id");
+-- Create "post_reports" table
+CREATE TABLE "post_reports" (
+  "id" uuid NOT NULL,
+  "post_id" uuid NOT NULL,
+  "reporter_id" uuid NOT NULL,
+  "reason" character varying(32) NOT NULL,
+  "details" text NULL,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "resolved_at" timestamp NULL,
+  "action_id" uuid NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "post_id" FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "reporter_id" FOREIGN KEY ("reporter_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "action_id" FOREIGN KEY ("action_id") REFERENCES "moderation_actions" ("id") ON UPDATE NO ACTION ON DELETE SET NULL,
+  CONSTRAINT "post_reports_reason_check" CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'misinformation', 'other'))
+);
+-- Create index "post_reports_open_idx" to table: "post_reports"
+CREATE INDEX "post_reports_open_idx" ON "post_reports" ("post_id") WHERE (resolved_at IS NULL);
+-- Create index "post_reports_post_id_reporter_id_key" to table: "post_reports"
+CREATE UNIQUE INDEX "post_reports_post_id_reporter_id_key" ON "post_reports" ("post_id", "reporter_id") WHERE (resolved_at IS NULL);
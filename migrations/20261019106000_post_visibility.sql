@@ -0,0 +1,13 @@
+-- Modify "posts" table
+ALTER TABLE "posts" ADD COLUMN "visibility" character varying(16) NOT NULL DEFAULT 'public', ADD CONSTRAINT "posts_visibility_check" CHECK (visibility IN ('public', 'unlisted', 'followers', 'private'));
+-- Create "user_follows" table
+CREATE TABLE "user_follows" (
+  "follower_id" uuid NOT NULL,
+  "followee_id" uuid NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  PRIMARY KEY ("follower_id", "followee_id"),
+  CONSTRAINT "follower_id" FOREIGN KEY ("follower_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "followee_id" FOREIGN KEY ("followee_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "user_follows_followee_id_idx" to table: "user_follows"
+CREATE INDEX "user_follows_followee_id_idx" ON "user_follows" ("followee_id");
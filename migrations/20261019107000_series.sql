@@ -0,0 +1,25 @@
+-- Create "series" table
+CREATE TABLE "series" (
+  "id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "title" character varying(255) NOT NULL,
+  "description" text NULL,
+  "version" integer NOT NULL DEFAULT 1,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "updated_at" timestamp NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "series_user_id_idx" to table: "series"
+CREATE INDEX "series_user_id_idx" ON "series" ("user_id");
+-- Create "series_posts" table
+CREATE TABLE "series_posts" (
+  "series_id" uuid NOT NULL,
+  "post_id" uuid NOT NULL,
+  "position" integer NOT NULL,
+  PRIMARY KEY ("series_id", "post_id"),
+  CONSTRAINT "series_posts_post_id_key" UNIQUE ("post_id"),
+  CONSTRAINT "series_posts_series_id_position_key" UNIQUE ("series_id", "position"),
+  CONSTRAINT "series_id" FOREIGN KEY ("series_id") REFERENCES "series" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "post_id" FOREIGN KEY ("post_id") REFERENCES "posts" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
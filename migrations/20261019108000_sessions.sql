@@ -0,0 +1,20 @@
+-- Create "sessions" table
+CREATE TABLE "sessions" (
+  "id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "token_id" uuid NOT NULL,
+  "previous_token_id" uuid NULL,
+  "rotated_at" timestamp NULL,
+  "user_agent" text NULL,
+  "ip_address" character varying(45) NULL,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "last_seen_at" timestamp NOT NULL DEFAULT now(),
+  "expires_at" timestamp NOT NULL,
+  "revoked_at" timestamp NULL,
+  "revoked_reason" character varying(16) NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "sessions_revoked_reason_check" CHECK (revoked_reason IN ('logout', 'reuse'))
+);
+-- Create index "sessions_user_id_idx" to table: "sessions"
+CREATE INDEX "sessions_user_id_idx" ON "sessions" ("user_id");
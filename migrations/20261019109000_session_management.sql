@@ -0,0 +1,2 @@
+-- Modify "sessions" table
+ALTER TABLE "sessions" DROP CONSTRAINT "sessions_revoked_reason_check", ADD CONSTRAINT "sessions_revoked_reason_check" CHECK (revoked_reason IN ('logout', 'reuse', 'revoked', 'admin'));
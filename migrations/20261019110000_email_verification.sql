@@ -0,0 +1,4 @@
+-- Modify "users" table
+ALTER TABLE "users" ADD COLUMN "email_verified_at" timestamp NULL;
+-- Accounts created before email verification existed are treated as verified
+UPDATE "users" SET "email_verified_at" = "created_at";
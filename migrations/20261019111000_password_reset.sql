@@ -0,0 +1,16 @@
+-- Modify "sessions" table
+ALTER TABLE "sessions" DROP CONSTRAINT "sessions_revoked_reason_check", ADD CONSTRAINT "sessions_revoked_reason_check" CHECK (revoked_reason IN ('logout', 'reuse', 'revoked', 'admin', 'password_reset'));
+-- Create "password_reset_tokens" table
+CREATE TABLE "password_reset_tokens" (
+  "id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "token_hash" character varying(64) NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "expires_at" timestamp NOT NULL,
+  "used_at" timestamp NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "password_reset_tokens_token_hash_key" UNIQUE ("token_hash"),
+  CONSTRAINT "user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "password_reset_tokens_user_id_idx" to table: "password_reset_tokens"
+CREATE INDEX "password_reset_tokens_user_id_idx" ON "password_reset_tokens" ("user_id");
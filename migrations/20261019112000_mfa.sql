@@ -0,0 +1,23 @@
+-- Modify "sessions" table
+ALTER TABLE "sessions" ADD COLUMN "mfa" boolean NOT NULL DEFAULT false;
+-- Create "user_mfa" table
+CREATE TABLE "user_mfa" (
+  "user_id" uuid NOT NULL,
+  "secret" character varying(64) NOT NULL,
+  "enabled_at" timestamp NULL,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  PRIMARY KEY ("user_id"),
+  CONSTRAINT "user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create "mfa_recovery_codes" table
+CREATE TABLE "mfa_recovery_codes" (
+  "id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "code_hash" character varying(64) NOT NULL,
+  "used_at" timestamp NULL,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "mfa_recovery_codes_user_id_idx" to table: "mfa_recovery_codes"
+CREATE INDEX "mfa_recovery_codes_user_id_idx" ON "mfa_recovery_codes" ("user_id");
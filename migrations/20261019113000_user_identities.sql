@@ -0,0 +1,19 @@
+-- Modify "users" table
+ALTER TABLE "users" ALTER COLUMN "phone" DROP NOT NULL, ALTER COLUMN "phone" DROP DEFAULT;
+-- Users who never gave a phone number get no phone number instead of an empty one
+UPDATE "users" SET "phone" = NULL WHERE "phone" = '';
+-- Create "user_identities" table
+CREATE TABLE "user_identities" (
+  "id" uuid NOT NULL,
+  "user_id" uuid NOT NULL,
+  "provider" character varying(32) NOT NULL,
+  "subject" character varying(255) NOT NULL,
+  "email" text NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "last_login_at" timestamp NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "user_identities_provider_subject_key" UNIQUE ("provider", "subject"),
+  CONSTRAINT "user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "user_identities_user_id_idx" to table: "user_identities"
+CREATE INDEX "user_identities_user_id_idx" ON "user_identities" ("user_id");
@@ -0,0 +1,27 @@
+-- Create "login_throttles" table
+CREATE TABLE "login_throttles" (
+  "key" character varying(320) NOT NULL,
+  "failures" integer NOT NULL DEFAULT 0,
+  "last_failure_at" timestamp NOT NULL,
+  "blocked_until" timestamp NULL,
+  "locked_at" timestamp NULL,
+  "unlock_token_hash" character varying(64) NULL,
+  PRIMARY KEY ("key"),
+  CONSTRAINT "login_throttles_unlock_token_hash_key" UNIQUE ("unlock_token_hash")
+);
+-- Create "audit_events" table
+CREATE TABLE "audit_events" (
+  "id" uuid NOT NULL,
+  "type" character varying(64) NOT NULL,
+  "user_id" uuid NULL,
+  "ip_address" character varying(45) NULL,
+  "user_agent" text NULL,
+  "metadata" jsonb NOT NULL DEFAULT '{}',
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "user_id" FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON UPDATE NO ACTION ON DELETE SET NULL
+);
+-- Create index "audit_events_type_created_at_idx" to table: "audit_events"
+CREATE INDEX "audit_events_type_created_at_idx" ON "audit_events" ("type", "created_at");
+-- Create index "audit_events_user_id_idx" to table: "audit_events"
+CREATE INDEX "audit_events_user_id_idx" ON "audit_events" ("user_id");
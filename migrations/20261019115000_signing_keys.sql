@@ -0,0 +1,13 @@
+-- Create "signing_keys" table
+CREATE TABLE "signing_keys" (
+  "id" uuid NOT NULL,
+  "algorithm" character varying(16) NOT NULL,
+  "private_key" bytea NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT now(),
+  "activates_at" timestamp NOT NULL,
+  "retires_at" timestamp NOT NULL,
+  "expires_at" timestamp NOT NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "signing_keys_expires_at_idx" to table: "signing_keys"
+CREATE INDEX "signing_keys_expires_at_idx" ON "signing_keys" ("expires_at");
@@ -37,8 +37,12 @@ table "users" {
 
   column "phone" {
     type = text
-    null = false
-    default = ""
+    null = true
+  }
+
+  column "avatar_url" {
+    type = text
+    null = true
   }
 
   column "is_active" {
@@ -58,6 +62,11 @@ table "users" {
     null = true
   }
 
+  column "email_verified_at" {
+    type = timestamp
+    null = true
+  }
+
   primary_key {
     columns = [column.id]
   }
@@ -113,22 +122,202 @@ table "posts" {
     null = false
   }
 
+  column "slug" {
+    type = varchar(255)
+    null = false
+  }
+
   column "content" {
     type = text
     null = true
   }
 
+  column "content_html" {
+    type = text
+    null = true
+  }
+
+  column "excerpt" {
+    type = text
+    null = false
+    default = ""
+  }
+
+  column "reading_time_minutes" {
+    type = integer
+    null = false
+    default = 0
+  }
+
   column "published" {
     type = bool
     null = false
     default = false
   }
 
+  column "status" {
+    type = varchar(16)
+    null = false
+    default = "draft"
+  }
+
+  column "visibility" {
+    type = varchar(16)
+    null = false
+    default = "public"
+  }
+
+  column "published_at" {
+    type = timestamp
+    null = true
+  }
+
+  column "scheduled_for" {
+    type = timestamp
+    null = true
+  }
+
+  column "comments_count" {
+    type = bigint
+    null = false
+    default = 0
+  }
+
+  column "comments_locked" {
+    type = bool
+    null = false
+    default = false
+  }
+
+  column "hidden" {
+    type = bool
+    null = false
+    default = false
+  }
+
+  column "user_id" {
+    type = uuid
+    null = false
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  column "updated_at" {
+    type = timestamp
+    null = true
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "user_id" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  unique "posts_slug_key" {
+      columns = [column.slug]
+  }
+
+  index "posts_status_published_at_idx" {
+    columns = [column.status, column.published_at]
+  }
+
+  index "posts_status_scheduled_for_idx" {
+    columns = [column.status, column.scheduled_for]
+  }
+
+  check "posts_status_check" {
+    expr = "status IN ('draft', 'in_review', 'scheduled', 'published', 'archived')"
+  }
+
+  check "posts_visibility_check" {
+    expr = "visibility IN ('public', 'unlisted', 'followers', 'private')"
+  }
+}
+
+table "post_slugs" {
+  schema = schema.public
+
+  column "slug" {
+    type = varchar(255)
+    null = false
+  }
+
+  column "post_id" {
+    type = uuid
+    null = false
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.slug]
+  }
+
+  foreign_key "post_id" {
+    columns     = [column.post_id]
+    ref_columns = [table.posts.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  index "post_slugs_post_id_idx" {
+    columns = [column.post_id]
+  }
+}
+
+table "comments" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "post_id" {
+    type = uuid
+    null = false
+  }
+
   column "user_id" {
     type = uuid
     null = false
   }
 
+  column "parent_id" {
+    type = uuid
+    null = true
+  }
+
+  column "depth" {
+    type = integer
+    null = false
+    default = 0
+  }
+
+  column "content" {
+    type = text
+    null = false
+  }
+
+  column "replies_count" {
+    type = bigint
+    null = false
+    default = 0
+  }
+
   column "created_at" {
     type = timestamp
     null = false
@@ -140,14 +329,1252 @@ table "posts" {
     null = true
   }
 
+  column "deleted_at" {
+    type = timestamp
+    null = true
+  }
+
   primary_key {
     columns = [column.id]
   }
 
+  foreign_key "post_id" {
+    columns     = [column.post_id]
+    ref_columns = [table.posts.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
   foreign_key "user_id" {
     columns     = [column.user_id]
     ref_columns = [table.users.column.id]
     on_update   = NO_ACTION
     on_delete   = CASCADE
   }
+
+  foreign_key "parent_id" {
+    columns     = [column.parent_id]
+    ref_columns = [table.comments.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  index "comments_post_id_parent_id_id_idx" {
+    columns = [column.post_id, column.parent_id, column.id]
+  }
+}
+
+table "tags" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "name" {
+    type = varchar(32)
+    null = false
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  unique "tags_name_key" {
+      columns = [column.name]
+  }
+}
+
+table "post_tags" {
+  schema = schema.public
+
+  column "post_id" {
+    type = uuid
+    null = false
+  }
+
+  column "tag_id" {
+    type = uuid
+    null = false
+  }
+
+  primary_key {
+    columns = [column.post_id, column.tag_id]
+  }
+
+  foreign_key "post_id" {
+    columns     = [column.post_id]
+    ref_columns = [table.posts.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  foreign_key "tag_id" {
+    columns     = [column.tag_id]
+    ref_columns = [table.tags.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  index "post_tags_tag_id_idx" {
+    columns = [column.tag_id]
+  }
+}
+
+table "post_revisions" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "post_id" {
+    type = uuid
+    null = false
+  }
+
+  column "number" {
+    type = integer
+    null = false
+  }
+
+  column "title" {
+    type = varchar(255)
+    null = false
+  }
+
+  column "content" {
+    type = text
+    null = true
+  }
+
+  column "editor_id" {
+    type = uuid
+    null = false
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "post_id" {
+    columns     = [column.post_id]
+    ref_columns = [table.posts.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  foreign_key "editor_id" {
+    columns     = [column.editor_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = NO_ACTION
+  }
+
+  unique "post_revisions_post_id_number_key" {
+      columns = [column.post_id, column.number]
+  }
+}
+
+table "post_reactions" {
+  schema = schema.public
+
+  column "post_id" {
+    type = uuid
+    null = false
+  }
+
+  column "user_id" {
+    type = uuid
+    null = false
+  }
+
+  column "kind" {
+    type = varchar(16)
+    null = false
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.post_id, column.user_id, column.kind]
+  }
+
+  foreign_key "post_id" {
+    columns     = [column.post_id]
+    ref_columns = [table.posts.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  foreign_key "user_id" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  index "post_reactions_user_id_idx" {
+    columns = [column.user_id]
+  }
+
+  check "post_reactions_kind_check" {
+    expr = "kind IN ('like', 'love', 'laugh', 'wow', 'sad', 'angry')"
+  }
+}
+
+table "attachments" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "user_id" {
+    type = uuid
+    null = false
+  }
+
+  column "post_id" {
+    type = uuid
+    null = true
+  }
+
+  column "storage_key" {
+    type = text
+    null = false
+  }
+
+  column "file_name" {
+    type = varchar(255)
+    null = false
+  }
+
+  column "content_type" {
+    type = varchar(127)
+    null = false
+  }
+
+  column "size" {
+    type = bigint
+    null = false
+  }
+
+  column "width" {
+    type = integer
+    null = true
+  }
+
+  column "height" {
+    type = integer
+    null = true
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "user_id" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  foreign_key "post_id" {
+    columns     = [column.post_id]
+    ref_columns = [table.posts.column.id]
+    on_update   = NO_ACTION
+    on_delete   = SET_NULL
+  }
+
+  unique "attachments_storage_key_key" {
+      columns = [column.storage_key]
+  }
+
+  index "attachments_post_id_idx" {
+    columns = [column.post_id]
+  }
+
+  index "attachments_user_id_idx" {
+    columns = [column.user_id]
+  }
+}
+
+table "post_views_daily" {
+  schema = schema.public
+
+  column "post_id" {
+    type = uuid
+    null = false
+  }
+
+  column "day" {
+    type = date
+    null = false
+  }
+
+  column "views" {
+    type = bigint
+    null = false
+    default = 0
+  }
+
+  column "unique_visitors" {
+    type = bigint
+    null = false
+    default = 0
+  }
+
+  primary_key {
+    columns = [column.post_id, column.day]
+  }
+
+  foreign_key "post_id" {
+    columns     = [column.post_id]
+    ref_columns = [table.posts.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+}
+
+table "post_view_visitors" {
+  schema = schema.public
+
+  column "post_id" {
+    type = uuid
+    null = false
+  }
+
+  column "day" {
+    type = date
+    null = false
+  }
+
+  column "visitor_hash" {
+    type = varchar(64)
+    null = false
+  }
+
+  primary_key {
+    columns = [column.post_id, column.day, column.visitor_hash]
+  }
+
+  foreign_key "post_id" {
+    columns     = [column.post_id]
+    ref_columns = [table.posts.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  index "post_view_visitors_day_idx" {
+    columns = [column.day]
+  }
+}
+
+table "post_view_referrers" {
+  schema = schema.public
+
+  column "post_id" {
+    type = uuid
+    null = false
+  }
+
+  column "day" {
+    type = date
+    null = false
+  }
+
+  column "referrer" {
+    type = varchar(255)
+    null = false
+  }
+
+  column "views" {
+    type = bigint
+    null = false
+    default = 0
+  }
+
+  primary_key {
+    columns = [column.post_id, column.day, column.referrer]
+  }
+
+  foreign_key "post_id" {
+    columns     = [column.post_id]
+    ref_columns = [table.posts.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+}
+
+table "bookmark_lists" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "user_id" {
+    type = uuid
+    null = false
+  }
+
+  column "name" {
+    type = varchar(64)
+    null = false
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  column "updated_at" {
+    type = timestamp
+    null = true
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "user_id" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  unique "bookmark_lists_user_id_name_key" {
+    columns = [column.user_id, column.name]
+  }
+}
+
+table "bookmarks" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "user_id" {
+    type = uuid
+    null = false
+  }
+
+  column "post_id" {
+    type = uuid
+    null = false
+  }
+
+  column "list_id" {
+    type = uuid
+    null = true
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "user_id" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  foreign_key "post_id" {
+    columns     = [column.post_id]
+    ref_columns = [table.posts.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  foreign_key "list_id" {
+    columns     = [column.list_id]
+    ref_columns = [table.bookmark_lists.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  index "bookmarks_user_id_post_id_key" {
+    unique  = true
+    columns = [column.user_id, column.post_id]
+    where   = "list_id IS NULL"
+  }
+
+  index "bookmarks_list_id_post_id_key" {
+    unique  = true
+    columns = [column.list_id, column.post_id]
+    where   = "list_id IS NOT NULL"
+  }
+
+  index "bookmarks_user_id_idx" {
+    columns = [column.user_id]
+  }
+}
+
+table "moderation_actions" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "post_id" {
+    type = uuid
+    null = false
+  }
+
+  column "post_title" {
+    type = varchar(255)
+    null = false
+  }
+
+  column "moderator_id" {
+    type = uuid
+    null = true
+  }
+
+  column "action" {
+    type = varchar(16)
+    null = false
+  }
+
+  column "note" {
+    type = text
+    null = true
+  }
+
+  column "reports_resolved" {
+    type = bigint
+    null = false
+    default = 0
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "moderator_id" {
+    columns     = [column.moderator_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = SET_NULL
+  }
+
+  index "moderation_actions_post_id_idx" {
+    columns = [column.post_id]
+  }
+
+  check "moderation_actions_action_check" {
+    expr = "action IN ('hide', 'restore', 'delete', 'dismiss')"
+  }
+}
+
+table "post_reports" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "post_id" {
+    type = uuid
+    null = false
+  }
+
+  column "reporter_id" {
+    type = uuid
+    null = false
+  }
+
+  column "reason" {
+    type = varchar(32)
+    null = false
+  }
+
+  column "details" {
+    type = text
+    null = true
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  column "resolved_at" {
+    type = timestamp
+    null = true
+  }
+
+  column "action_id" {
+    type = uuid
+    null = true
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "post_id" {
+    columns     = [column.post_id]
+    ref_columns = [table.posts.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  foreign_key "reporter_id" {
+    columns     = [column.reporter_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  foreign_key "action_id" {
+    columns     = [column.action_id]
+    ref_columns = [table.moderation_actions.column.id]
+    on_update   = NO_ACTION
+    on_delete   = SET_NULL
+  }
+
+  index "post_reports_post_id_reporter_id_key" {
+    unique  = true
+    columns = [column.post_id, column.reporter_id]
+    where   = "resolved_at IS NULL"
+  }
+
+  index "post_reports_open_idx" {
+    columns = [column.post_id]
+    where   = "resolved_at IS NULL"
+  }
+
+  check "post_reports_reason_check" {
+    expr = "reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'misinformation', 'other')"
+  }
+}
+
+table "user_follows" {
+  schema = schema.public
+
+  column "follower_id" {
+    type = uuid
+    null = false
+  }
+
+  column "followee_id" {
+    type = uuid
+    null = false
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.follower_id, column.followee_id]
+  }
+
+  foreign_key "follower_id" {
+    columns     = [column.follower_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  foreign_key "followee_id" {
+    columns     = [column.followee_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  index "user_follows_followee_id_idx" {
+    columns = [column.followee_id]
+  }
+}
+
+table "series" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "user_id" {
+    type = uuid
+    null = false
+  }
+
+  column "title" {
+    type = varchar(255)
+    null = false
+  }
+
+  column "description" {
+    type = text
+    null = true
+  }
+
+  column "version" {
+    type = integer
+    null = false
+    default = 1
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  column "updated_at" {
+    type = timestamp
+    null = true
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "user_id" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  index "series_user_id_idx" {
+    columns = [column.user_id]
+  }
+}
+
+table "series_posts" {
+  schema = schema.public
+
+  column "series_id" {
+    type = uuid
+    null = false
+  }
+
+  column "post_id" {
+    type = uuid
+    null = false
+  }
+
+  column "position" {
+    type = integer
+    null = false
+  }
+
+  primary_key {
+    columns = [column.series_id, column.post_id]
+  }
+
+  foreign_key "series_id" {
+    columns     = [column.series_id]
+    ref_columns = [table.series.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  foreign_key "post_id" {
+    columns     = [column.post_id]
+    ref_columns = [table.posts.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  unique "series_posts_post_id_key" {
+    columns = [column.post_id]
+  }
+
+  unique "series_posts_series_id_position_key" {
+    columns = [column.series_id, column.position]
+  }
+}
+
+table "sessions" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "user_id" {
+    type = uuid
+    null = false
+  }
+
+  column "token_id" {
+    type = uuid
+    null = false
+  }
+
+  column "previous_token_id" {
+    type = uuid
+    null = true
+  }
+
+  column "rotated_at" {
+    type = timestamp
+    null = true
+  }
+
+  column "user_agent" {
+    type = text
+    null = true
+  }
+
+  column "ip_address" {
+    type = varchar(45)
+    null = true
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  column "last_seen_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  column "expires_at" {
+    type = timestamp
+    null = false
+  }
+
+  column "revoked_at" {
+    type = timestamp
+    null = true
+  }
+
+  column "revoked_reason" {
+    type = varchar(16)
+    null = true
+  }
+
+  column "mfa" {
+    type = boolean
+    null = false
+    default = false
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "user_id" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  index "sessions_user_id_idx" {
+    columns = [column.user_id]
+  }
+
+  check "sessions_revoked_reason_check" {
+    expr = "revoked_reason IN ('logout', 'reuse', 'revoked', 'admin', 'password_reset')"
+  }
+}
+
+table "password_reset_tokens" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "user_id" {
+    type = uuid
+    null = false
+  }
+
+  column "token_hash" {
+    type = varchar(64)
+    null = false
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  column "expires_at" {
+    type = timestamp
+    null = false
+  }
+
+  column "used_at" {
+    type = timestamp
+    null = true
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "user_id" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  unique "password_reset_tokens_token_hash_key" {
+    columns = [column.token_hash]
+  }
+
+  index "password_reset_tokens_user_id_idx" {
+    columns = [column.user_id]
+  }
+}
+
+table "user_mfa" {
+  schema = schema.public
+
+  column "user_id" {
+    type = uuid
+    null = false
+  }
+
+  column "secret" {
+    type = varchar(64)
+    null = false
+  }
+
+  column "enabled_at" {
+    type = timestamp
+    null = true
+  }
+
+  column "last_used_step" {
+    type = bigint
+    null = false
+    default = 0
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.user_id]
+  }
+
+  foreign_key "user_id" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+}
+
+table "mfa_recovery_codes" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "user_id" {
+    type = uuid
+    null = false
+  }
+
+  column "code_hash" {
+    type = varchar(64)
+    null = false
+  }
+
+  column "used_at" {
+    type = timestamp
+    null = true
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "user_id" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  index "mfa_recovery_codes_user_id_idx" {
+    columns = [column.user_id]
+  }
+}
+
+table "user_identities" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "user_id" {
+    type = uuid
+    null = false
+  }
+
+  column "provider" {
+    type = varchar(32)
+    null = false
+  }
+
+  column "subject" {
+    type = varchar(255)
+    null = false
+  }
+
+  column "email" {
+    type = text
+    null = false
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  column "last_login_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "user_id" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = CASCADE
+  }
+
+  unique "user_identities_provider_subject_key" {
+    columns = [column.provider, column.subject]
+  }
+
+  index "user_identities_user_id_idx" {
+    columns = [column.user_id]
+  }
+}
+
+table "login_throttles" {
+  schema = schema.public
+
+  column "key" {
+    type = varchar(320)
+    null = false
+  }
+
+  column "failures" {
+    type = integer
+    null = false
+    default = 0
+  }
+
+  column "last_failure_at" {
+    type = timestamp
+    null = false
+  }
+
+  column "blocked_until" {
+    type = timestamp
+    null = true
+  }
+
+  column "locked_at" {
+    type = timestamp
+    null = true
+  }
+
+  column "unlock_token_hash" {
+    type = varchar(64)
+    null = true
+  }
+
+  primary_key {
+    columns = [column.key]
+  }
+
+  unique "login_throttles_unlock_token_hash_key" {
+    columns = [column.unlock_token_hash]
+  }
+}
+
+table "audit_events" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "type" {
+    type = varchar(64)
+    null = false
+  }
+
+  column "user_id" {
+    type = uuid
+    null = true
+  }
+
+  column "ip_address" {
+    type = varchar(45)
+    null = true
+  }
+
+  column "user_agent" {
+    type = text
+    null = true
+  }
+
+  column "metadata" {
+    type = jsonb
+    null = false
+    default = sql("'{}'")
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  foreign_key "user_id" {
+    columns     = [column.user_id]
+    ref_columns = [table.users.column.id]
+    on_update   = NO_ACTION
+    on_delete   = SET_NULL
+  }
+
+  index "audit_events_user_id_idx" {
+    columns = [column.user_id]
+  }
+
+  index "audit_events_type_created_at_idx" {
+    columns = [column.type, column.created_at]
+  }
+}
+
+table "signing_keys" {
+  schema = schema.public
+
+  column "id" {
+    type = uuid
+    null = false
+  }
+
+  column "algorithm" {
+    type = varchar(16)
+    null = false
+  }
+
+  column "private_key" {
+    type = bytea
+    null = false
+  }
+
+  column "created_at" {
+    type = timestamp
+    null = false
+    default = sql("now()")
+  }
+
+  column "activates_at" {
+    type = timestamp
+    null = false
+  }
+
+  column "retires_at" {
+    type = timestamp
+    null = false
+  }
+
+  column "expires_at" {
+    type = timestamp
+    null = false
+  }
+
+  primary_key {
+    columns = [column.id]
+  }
+
+  index "signing_keys_expires_at_idx" {
+    columns = [column.expires_at]
+  }
 }